@@ -50,7 +50,7 @@ func run() error {
 
 	for _, dir := range dirEntries {
 		name := dir.Name()
-		if name == "template.go" || name == "template_fake.go" {
+		if name == "template.go" || name == "template_fake.go" || strings.HasSuffix(name, "_test.go") {
 			continue
 		}
 
@@ -13,20 +13,55 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 )
 
+// DataPlaneScope is the default AAD scope for Cosmos DB data plane tokens in
+// the public cloud.  See Cloud for other cloud environments.
+const DataPlaneScope = "https://cosmos.azure.com/.default"
+
 type Authorizer interface {
 	Authorize(context.Context, *http.Request, string, string) error
 }
 
+// MasterKeyAuthorizer is an Authorizer which signs requests with an account
+// master key.  The key can be rotated with SetMasterKey; requests which have
+// already been signed are unaffected.
+type MasterKeyAuthorizer interface {
+	Authorizer
+	SetMasterKey(string) error
+}
+
+// failoverAuthorizer is implemented by Authorizers which hold an alternate
+// credential to fall back to when a request is rejected with 401 Unauthorized
+type failoverAuthorizer interface {
+	Authorizer
+	// generation returns a value which changes on every failover
+	generation() int
+	// failover switches to the alternate credential if no other failover has
+	// happened since generation was read.  It returns true if the request
+	// should be retried.
+	failover(generation int) bool
+}
+
 type masterKeyAuthorizer struct {
-	masterKey []byte
+	mu         sync.RWMutex
+	masterKeys [][]byte
+	current    int
+	gen        int
 }
 
 func (a *masterKeyAuthorizer) Authorize(ctx context.Context, req *http.Request, resourceType, resourceLink string) error {
 	date := time.Now().UTC().Format("Mon, 02 Jan 2006 15:04:05 GMT")
 
-	h := hmac.New(sha256.New, a.masterKey)
+	a.mu.RLock()
+	masterKey := a.masterKeys[a.current]
+	a.mu.RUnlock()
+
+	h := hmac.New(sha256.New, masterKey)
 	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n\n", strings.ToLower(req.Method), resourceType, resourceLink, strings.ToLower(date))
 
 	req.Header.Set("Authorization", url.QueryEscape(fmt.Sprintf("type=master&ver=1.0&sig=%s", base64.StdEncoding.EncodeToString(h.Sum(nil)))))
@@ -35,13 +70,188 @@ func (a *masterKeyAuthorizer) Authorize(ctx context.Context, req *http.Request,
 	return nil
 }
 
-func NewMasterKeyAuthorizer(masterKey string) (Authorizer, error) {
+func (a *masterKeyAuthorizer) SetMasterKey(masterKey string) error {
 	b, err := base64.StdEncoding.DecodeString(masterKey)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.masterKeys[a.current] = b
+
+	return nil
+}
+
+func (a *masterKeyAuthorizer) generation() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.gen
+}
+
+func (a *masterKeyAuthorizer) failover(generation int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.masterKeys) < 2 {
+		return false
+	}
+
+	if a.gen == generation {
+		a.current = (a.current + 1) % len(a.masterKeys)
+		a.gen++
+	}
+
+	return true
+}
+
+func NewMasterKeyAuthorizer(masterKey string) (MasterKeyAuthorizer, error) {
+	b, err := base64.StdEncoding.DecodeString(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &masterKeyAuthorizer{masterKeys: [][]byte{b}}, nil
+}
+
+// NewMasterKeyAuthorizerWithSecondary returns a MasterKeyAuthorizer which
+// signs requests with primaryMasterKey.  If a request is rejected with 401
+// Unauthorized, it is retried once with secondaryMasterKey, which then becomes
+// the default.  SetMasterKey replaces whichever key is currently the default.
+func NewMasterKeyAuthorizerWithSecondary(primaryMasterKey, secondaryMasterKey string) (MasterKeyAuthorizer, error) {
+	primary, err := base64.StdEncoding.DecodeString(primaryMasterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	secondary, err := base64.StdEncoding.DecodeString(secondaryMasterKey)
 	if err != nil {
 		return nil, err
 	}
 
-	return &masterKeyAuthorizer{masterKey: b}, nil
+	return &masterKeyAuthorizer{masterKeys: [][]byte{primary, secondary}}, nil
+}
+
+// ErrResourceTokenNotFound is the error returned if a resourceTokenAuthorizer
+// holds no token which covers the requested resource
+var ErrResourceTokenNotFound = fmt.Errorf("resource token not found")
+
+// ResourceTokenAuthorizer is an Authorizer which signs requests with resource
+// tokens.  Tokens are keyed by resource link and, optionally, partition key;
+// the token with the longest resource link covering the requested resource is
+// used, preferring a token scoped to the request's partition key.
+type ResourceTokenAuthorizer interface {
+	Authorizer
+	SetToken(resourceLink, partitionKey, token string)
+	SetPermission(*Permission)
+	DeleteToken(resourceLink, partitionKey string)
+}
+
+type resourceTokenKey struct {
+	resourceLink string
+	partitionKey string
+}
+
+type resourceTokenAuthorizer struct {
+	mu     sync.RWMutex
+	tokens map[resourceTokenKey]string
+}
+
+// NewResourceTokenAuthorizer returns a new ResourceTokenAuthorizer
+func NewResourceTokenAuthorizer() ResourceTokenAuthorizer {
+	return &resourceTokenAuthorizer{tokens: map[resourceTokenKey]string{}}
+}
+
+func (a *resourceTokenAuthorizer) SetToken(resourceLink, partitionKey, token string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.tokens[resourceTokenKey{resourceLink: strings.Trim(resourceLink, "/"), partitionKey: partitionKey}] = token
+}
+
+// SetPermission adds the token of a Permission returned by the permissions
+// API, scoped to its resource and resource partition key
+func (a *resourceTokenAuthorizer) SetPermission(permission *Permission) {
+	var partitionKey string
+	switch len(permission.ResourcePartitionKey) {
+	case 0:
+	case 1:
+		partitionKey = permission.ResourcePartitionKey[0]
+	default:
+		partitionKey = HierarchicalPartitionKey(permission.ResourcePartitionKey...)
+	}
+
+	a.SetToken(permission.Resource, partitionKey, permission.Token)
+}
+
+func (a *resourceTokenAuthorizer) DeleteToken(resourceLink, partitionKey string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.tokens, resourceTokenKey{resourceLink: strings.Trim(resourceLink, "/"), partitionKey: partitionKey})
+}
+
+func (a *resourceTokenAuthorizer) token(resourceLink, partitionKeyHeader string) (string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var token string
+	var best resourceTokenKey
+	var found bool
+
+	for key, t := range a.tokens {
+		if key.resourceLink != resourceLink && !strings.HasPrefix(resourceLink, key.resourceLink+"/") {
+			continue
+		}
+		if key.partitionKey != "" && encodePartitionKey(key.partitionKey) != partitionKeyHeader {
+			continue
+		}
+
+		if !found ||
+			len(key.resourceLink) > len(best.resourceLink) ||
+			len(key.resourceLink) == len(best.resourceLink) && best.partitionKey == "" {
+			token, best, found = t, key, true
+		}
+	}
+
+	return token, found
+}
+
+func (a *resourceTokenAuthorizer) Authorize(ctx context.Context, req *http.Request, resourceType, resourceLink string) error {
+	token, found := a.token(resourceLink, req.Header.Get("X-Ms-Documentdb-Partitionkey"))
+	if !found {
+		return ErrResourceTokenNotFound
+	}
+
+	req.Header.Set("Authorization", url.QueryEscape(token))
+	req.Header.Set("x-ms-date", time.Now().UTC().Format("Mon, 02 Jan 2006 15:04:05 GMT"))
+
+	return nil
+}
+
+// TokenAuthorizer is an Authorizer which signs requests with AAD tokens,
+// refreshing them before they expire
+type TokenAuthorizer interface {
+	Authorizer
+	SetRefreshHooks(*TokenRefreshHooks)
+}
+
+// TokenRefreshHooks are callbacks invoked as a TokenAuthorizer refreshes its
+// token.  Any of them may be nil.
+type TokenRefreshHooks struct {
+	// OnAttempt is called before a token refresh is attempted
+	OnAttempt func()
+
+	// OnSuccess is called after a successful token refresh with the duration
+	// of the attempt and the expiration of the new token
+	OnSuccess func(duration time.Duration, expiration time.Time)
+
+	// OnFailure is called after a failed token refresh with the duration of
+	// the attempt, the expiration of the token still in use (zero if there is
+	// none) and the error
+	OnFailure func(duration time.Duration, expiration time.Time, err error)
 }
 
 type tokenAuthorizer struct {
@@ -50,6 +260,7 @@ type tokenAuthorizer struct {
 	cond        *sync.Cond
 	acquiring   bool
 	lastAttempt time.Time
+	hooks       TokenRefreshHooks
 	getToken    func(context.Context) (token string, newExpiration time.Time, err error)
 }
 
@@ -66,10 +277,95 @@ func (a *tokenAuthorizer) Authorize(ctx context.Context, req *http.Request, reso
 	return nil
 }
 
-func NewTokenAuthorizer(token string, expiration time.Time, getToken func(context.Context) (token string, newExpiration time.Time, err error)) Authorizer {
+// SetRefreshHooks sets or unsets the callbacks invoked on token refresh
+func (a *tokenAuthorizer) SetRefreshHooks(hooks *TokenRefreshHooks) {
+	a.cond.L.Lock()
+	defer a.cond.L.Unlock()
+
+	if hooks == nil {
+		hooks = &TokenRefreshHooks{}
+	}
+	a.hooks = *hooks
+}
+
+func NewTokenAuthorizer(token string, expiration time.Time, getToken func(context.Context) (token string, newExpiration time.Time, err error)) TokenAuthorizer {
 	return &tokenAuthorizer{token: token, expiration: expiration, getToken: getToken, cond: sync.NewCond(&sync.Mutex{})}
 }
 
+// NewTokenCredentialAuthorizer returns an Authorizer which acquires AAD tokens
+// from cred.  If no scopes are given, DataPlaneScope is used.
+func NewTokenCredentialAuthorizer(cred azcore.TokenCredential, scopes ...string) TokenAuthorizer {
+	return NewTokenAuthorizer("", time.Time{}, TokenCredentialGetToken(cred, scopes...))
+}
+
+// NewManagedIdentityAuthorizer returns an Authorizer which acquires AAD tokens
+// using the managed identity of the host.  If clientID is empty, the
+// system-assigned identity is used.
+func NewManagedIdentityAuthorizer(clientID string) (TokenAuthorizer, error) {
+	return NewManagedIdentityAuthorizerForCloud(CloudPublic, clientID)
+}
+
+// NewManagedIdentityAuthorizerForCloud is like NewManagedIdentityAuthorizer,
+// but acquires tokens for the given cloud environment
+func NewManagedIdentityAuthorizerForCloud(cloud Cloud, clientID string) (TokenAuthorizer, error) {
+	options := &azidentity.ManagedIdentityCredentialOptions{
+		ClientOptions: azcore.ClientOptions{
+			Cloud: cloud.Configuration,
+		},
+	}
+	if clientID != "" {
+		options.ID = azidentity.ClientID(clientID)
+	}
+
+	cred, err := azidentity.NewManagedIdentityCredential(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTokenCredentialAuthorizer(cred, cloud.Scope), nil
+}
+
+// NewWorkloadIdentityAuthorizer returns an Authorizer which acquires AAD
+// tokens using workload identity federation, as configured on AKS by the
+// AZURE_CLIENT_ID, AZURE_TENANT_ID and AZURE_FEDERATED_TOKEN_FILE environment
+// variables.  If clientID is not empty, it overrides AZURE_CLIENT_ID.
+func NewWorkloadIdentityAuthorizer(clientID string) (TokenAuthorizer, error) {
+	return NewWorkloadIdentityAuthorizerForCloud(CloudPublic, clientID)
+}
+
+// NewWorkloadIdentityAuthorizerForCloud is like NewWorkloadIdentityAuthorizer,
+// but acquires tokens from the authority of the given cloud environment
+func NewWorkloadIdentityAuthorizerForCloud(cloud Cloud, clientID string) (TokenAuthorizer, error) {
+	cred, err := azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+		ClientOptions: azcore.ClientOptions{
+			Cloud: cloud.Configuration,
+		},
+		ClientID: clientID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTokenCredentialAuthorizer(cred, cloud.Scope), nil
+}
+
+// TokenCredentialGetToken adapts cred to the getToken callback signature used
+// by NewTokenAuthorizer.  If no scopes are given, DataPlaneScope is used.
+func TokenCredentialGetToken(cred azcore.TokenCredential, scopes ...string) func(context.Context) (string, time.Time, error) {
+	if len(scopes) == 0 {
+		scopes = []string{DataPlaneScope}
+	}
+
+	return func(ctx context.Context) (string, time.Time, error) {
+		token, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: scopes})
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		return token.Token, token.ExpiresOn, nil
+	}
+}
+
 // Get returns the underlying resource.
 // If the resource is fresh, no refresh is performed.
 func (a *tokenAuthorizer) acquireToken(ctx context.Context) (string, error) {
@@ -112,6 +408,7 @@ func (a *tokenAuthorizer) acquireToken(ctx context.Context) (string, error) {
 		// If we get here, wait for the new token value to be acquired/updated
 		a.cond.Wait()
 	}
+	hooks := a.hooks
 	a.cond.L.Unlock() // Release the lock so no goroutines are blocked
 
 	var err error
@@ -120,10 +417,16 @@ func (a *tokenAuthorizer) acquireToken(ctx context.Context) (string, error) {
 		var expiration time.Time
 		var newValue string
 		a.lastAttempt = now
+		if hooks.OnAttempt != nil {
+			hooks.OnAttempt()
+		}
 		newValue, expiration, err = a.getToken(ctx)
+		duration := time.Since(now)
 
 		// Atomically, update the shared token's new value & expiration.
 		a.cond.L.Lock()
+		currentExpiration := a.expiration
+		refreshErr := err
 		if err == nil {
 			// Update token & expiration, return the new value
 			token = newValue
@@ -135,6 +438,15 @@ func (a *tokenAuthorizer) acquireToken(ctx context.Context) (string, error) {
 		a.acquiring = false
 		a.cond.L.Unlock()
 		a.cond.Broadcast()
+
+		if refreshErr == nil && hooks.OnSuccess != nil {
+			hooks.OnSuccess(duration, expiration)
+		} else if refreshErr != nil && hooks.OnFailure != nil {
+			if expired {
+				currentExpiration = time.Time{}
+			}
+			hooks.OnFailure(duration, currentExpiration, refreshErr)
+		}
 	}
 	return token, err
 }
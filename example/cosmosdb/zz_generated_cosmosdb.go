@@ -5,14 +5,14 @@ package cosmosdb
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/textproto"
 	"strconv"
+	"strings"
 	"time"
-
-	"github.com/ugorji/go/codec"
 )
 
 // Options represents API options
@@ -22,19 +22,131 @@ type Options struct {
 	PostTriggers        []string
 	PartitionKeyRangeID string
 	Continuation        string
+
+	// PartitionKey, if set, is the partition key value of the operation.  It
+	// applies to requests which do not otherwise specify one, allowing point
+	// operations on partitioned collections to be made with the core client.
+	// It also restricts a change feed to the documents with that partition
+	// key.
+	PartitionKey string
+
+	// MaxItemCount, if non-zero, is the maximum number of items returned in
+	// each page of a query or feed, overriding the value passed to Next.  -1
+	// leaves the page size to the server.
+	MaxItemCount int
+
+	// PopulateQueryMetrics requests detailed query metrics, which are made
+	// available by the query iterator
+	PopulateQueryMetrics bool
+
+	// PopulateIndexMetrics requests the index utilization of a query, which
+	// is made available in its QueryMetrics
+	PopulateIndexMetrics bool
+
+	// ResponseContinuationTokenLimitInKB, if non-zero, limits the size of the
+	// continuations returned by a query, at the cost of the server doing more
+	// work to resume it
+	ResponseContinuationTokenLimitInKB int
+
+	// MaxDegreeOfParallelism is the number of partition key ranges which a
+	// cross-partition query fetches concurrently, or the number of batches
+	// which a bulk operation executes concurrently.  0 or 1 makes one request
+	// at a time; -1 makes all requests at once.
+	MaxDegreeOfParallelism int
+
+	// FeedRange, if set, restricts a cross-partition query or a change feed to
+	// the documents whose partition keys fall within it
+	FeedRange *FeedRange
+
+	// ChangeFeedStartFrom, if set, is the position from which a change feed
+	// is read where it has no continuation, rather than the beginning
+	ChangeFeedStartFrom *ChangeFeedStartFrom
+
+	// EnableScriptLogging requests the console.log output of a stored
+	// procedure, which is made available in ResponseInfo.ScriptLog
+	EnableScriptLogging bool
+
+	// IfNoneMatch, if set, is the ETag of a cached copy of a document.  Get
+	// returns an Error with StatusCode 304 Not Modified if the document is
+	// unchanged.
+	IfNoneMatch string
+
+	// IndexingDirective, if set, overrides the indexing of documents written
+	// by the operation, for example to exclude documents which are never
+	// queried from the index
+	IndexingDirective IndexingDirective
+
+	// PatchCondition, if set, is a filter predicate which a document must
+	// match for a Patch to be applied, e.g. "FROM c WHERE c.status = 'active'"
+	PatchCondition string
+
+	// Authorizer, if set, is used to authorize the operation in place of the
+	// client's Authorizer
+	Authorizer Authorizer
+
+	// Session, if set, is used to track session tokens for the operation in
+	// place of the client's Session
+	Session *Session
+
+	// Timeout, if non-zero, limits the duration of each request made by the
+	// operation, independently of its context.  A request which times out
+	// fails with an Error with StatusCode 408 Request Timeout, and may be
+	// retried.  If the operation returns a response body to be read, the
+	// limit includes reading it.
+	Timeout time.Duration
+
+	// MaxRetryDuration, if non-zero, overrides the RetryPolicy's cap on the
+	// total delay before the retries of the operation's requests
+	MaxRetryDuration time.Duration
+
+	// ResponseInfo, if set, is populated with the metadata of the response
+	ResponseInfo *ResponseInfo
 }
 
+// IndexingDirective represents an indexing directive
+type IndexingDirective string
+
+// IndexingDirective constants
+const (
+	IndexingDirectiveInclude IndexingDirective = "Include"
+	IndexingDirectiveExclude IndexingDirective = "Exclude"
+)
+
 // Error represents an error
 type Error struct {
-	StatusCode int
-	Code       string `json:"code"`
-	Message    string `json:"message"`
+	StatusCode    int
+	SubStatusCode int
+	Code          string `json:"code"`
+	Message       string `json:"message"`
 }
 
 func (e *Error) Error() string {
 	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
 }
 
+// IsNotModified returns true if err is the result of a conditional Get of a
+// document which is unchanged
+func IsNotModified(err error) bool {
+	return IsErrorStatusCode(err, http.StatusNotModified)
+}
+
+// IsUniqueKeyViolation returns true if err is the result of writing a
+// document which would duplicate a unique key of its collection
+func IsUniqueKeyViolation(err error) bool {
+	return IsErrorStatusCode(err, http.StatusConflict) &&
+		strings.Contains(err.(*Error).Message, uniqueKeyViolationMessage)
+}
+
+// IsDuplicateID returns true if err is the result of creating a document
+// whose id already exists
+func IsDuplicateID(err error) bool {
+	return IsErrorStatusCode(err, http.StatusConflict) && !IsUniqueKeyViolation(err)
+}
+
+// uniqueKeyViolationMessage is included in the message of a 409 Conflict
+// caused by a unique key violation
+const uniqueKeyViolationMessage = "Unique index constraint violation"
+
 // IsErrorStatusCode returns true if err is of type Error and its StatusCode
 // matches statusCode
 func IsErrorStatusCode(err error, statusCode int) bool {
@@ -64,25 +176,77 @@ func RetryOnPreconditionFailed(f func() error) (err error) {
 	return
 }
 
-func (c *databaseClient) do(ctx context.Context, method, path, resourceType, resourceLink string, expectedStatusCode int, in, out interface{}, headers http.Header) error {
-	var resp *http.Response
-	var err error
+// multiStatusResponse is implemented by the responses of operations, such as
+// transactional batches, whose body describes their outcome whatever their
+// status code
+type multiStatusResponse interface {
+	decodeMultiStatus(statusCode int, body []byte) error
+}
 
-	for retry := 0; retry < c.maxRetries; retry++ {
-		resp, err = c._do(ctx, method, path, resourceType, resourceLink, expectedStatusCode, in, out, headers)
-		if !IsErrorStatusCode(err, http.StatusTooManyRequests) {
-			break
-		}
+// maxDegreeOfParallelism returns the number of requests of an operation to
+// make concurrently, or -1 for no limit
+func (o *Options) maxDegreeOfParallelism() int {
+	if o == nil || o.MaxDegreeOfParallelism == 0 {
+		return 1
+	}
 
-		c.log.Warnf("%s %s: attempt %d: %s", method, path, retry, err)
+	return o.MaxDegreeOfParallelism
+}
 
-		ms, err2 := strconv.ParseInt(resp.Header.Get("x-ms-retry-after-ms"), 10, 0)
-		if err2 != nil {
-			return err2
-		}
+// withTimeout returns a context limited to the timeout of a request, and its
+// cancel function
+func (o *Options) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o == nil || o.Timeout == 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, o.Timeout)
+}
+
+// maxRetryDuration returns the cap on the total delay before the retries of
+// a request
+func (o *Options) maxRetryDuration(retryPolicy RetryPolicy) time.Duration {
+	if o == nil || o.MaxRetryDuration == 0 {
+		return retryPolicy.MaxRetryDuration()
+	}
+
+	return o.MaxRetryDuration
+}
+
+// feedRange returns the feed range to which an operation is restricted, or
+// nil if it is not
+func (o *Options) feedRange() *FeedRange {
+	if o == nil {
+		return nil
+	}
 
-		time.Sleep(time.Duration(ms) * time.Millisecond)
+	return o.FeedRange
+}
+
+// setFeedHeaders sets the headers of options which apply to queries and feeds
+func (o *Options) setFeedHeaders(headers http.Header) {
+	if o == nil {
+		return
+	}
+
+	if o.MaxItemCount != 0 {
+		headers.Set("X-Ms-Max-Item-Count", strconv.Itoa(o.MaxItemCount))
+	}
+	if o.PopulateQueryMetrics {
+		headers.Set("X-Ms-Documentdb-Populatequerymetrics", "True")
+	}
+	if o.PopulateIndexMetrics {
+		headers.Set("X-Ms-Cosmos-Populateindexmetrics", "True")
 	}
+	if o.ResponseContinuationTokenLimitInKB != 0 {
+		headers.Set("X-Ms-Documentdb-Responsecontinuationtokenlimitinkb", strconv.Itoa(o.ResponseContinuationTokenLimitInKB))
+	}
+}
+
+// do sends a request, retrying it as necessary, and replaces headers with the
+// headers of the response
+func (c *databaseClient) do(ctx context.Context, method, path, resourceType, resourceLink string, expectedStatusCode int, in, out interface{}, headers http.Header, options *Options) error {
+	resp, err := c.send(ctx, method, path, resourceType, resourceLink, expectedStatusCode, in, out, headers, options)
 
 	if resp != nil && headers != nil {
 		for k := range headers {
@@ -96,56 +260,375 @@ func (c *databaseClient) do(ctx context.Context, method, path, resourceType, res
 	return err
 }
 
-func (c *databaseClient) _do(ctx context.Context, method, path, resourceType, resourceLink string, expectedStatusCode int, in, out interface{}, headers http.Header) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, method, "https://"+c.databaseHostname+"/"+path, nil)
-	if err != nil {
-		return nil, err
+// send sends a request, retrying it as necessary.  Unlike do, it leaves headers
+// unchanged, sparing callers which do not need the headers of the response
+// the cost of copying them.
+func (c *databaseClient) send(ctx context.Context, method, path, resourceType, resourceLink string, expectedStatusCode int, in, out interface{}, headers http.Header, options *Options) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+
+	var generation int
+	failover, _ := c.getAuthorizer(options).(failoverAuthorizer)
+	if failover != nil {
+		generation = failover.generation()
 	}
 
-	if in != nil {
-		buf := &bytes.Buffer{}
-		err := codec.NewEncoder(buf, c.jsonHandle).Encode(in)
-		if err != nil {
-			return nil, err
+	var regionFailedOver bool
+
+	retryPolicy := c.getRetryPolicy()
+	maxRetryDuration := options.maxRetryDuration(retryPolicy)
+	var retryDuration time.Duration
+
+	idempotent := isIdempotent(method, headers)
+
+	cb := c.getCircuitBreaker()
+
+	// the request headers are built once and shared by the attempts, each of
+	// which sets only those which vary between them
+	reqHeader := newRequestHeader(headers, options)
+
+	ctx, span := c.startSpan(ctx, method, path, resourceType, resourceLink, headers, reqHeader)
+	var retries int
+	defer func() {
+		endSpan(span, resp, retries, err)
+	}()
+
+	for retry := 0; retry < retryPolicy.MaxAttempts(); retry++ {
+		retries = retry
+
+		hostname := c.hostname(method, path, headers)
+		if !cb.allow(hostname) {
+			resp, err = nil, ErrCircuitOpen
+			break
+		}
+
+		resp, err = c.doAttempt(ctx, hostname, method, path, resourceType, resourceLink, expectedStatusCode, in, out, headers, reqHeader, options)
+		cb.record(c.getLogger(), hostname, err)
+
+		// without a response, the transport may still be reading the headers
+		// of the abandoned request, so they are not reused
+		if resp == nil {
+			reqHeader = reqHeader.Clone()
+		}
+
+		if IsErrorStatusCode(err, http.StatusUnauthorized) && failover != nil && failover.failover(generation) {
+			c.getLogger().Warn("retrying with alternate credential", "method", method, "path", path, "attempt", retry, "status", statusCode(resp), "error", err)
+			failover = nil
+			continue
+		}
+		if !regionFailedOver && c.regionFailover(hostname, method, headers, idempotent || retriesNonIdempotent(retryPolicy), err) {
+			c.getLogger().Warn("retrying in alternate region", "method", method, "path", path, "attempt", retry, "status", statusCode(resp), "error", err)
+			regionFailedOver = true
+			continue
+		}
+
+		attempt := &RetryAttempt{Attempt: retry, Method: method, Path: path, Idempotent: idempotent, Response: resp, Err: err}
+		if err == nil || retry+1 >= retryPolicy.MaxAttempts() || !retryPolicy.ShouldRetry(attempt) {
+			break
+		}
+
+		delay := retryPolicy.Delay(attempt)
+		if maxRetryDuration != 0 && retryDuration+delay > maxRetryDuration {
+			break
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			break
+		}
+		retryDuration += delay
+
+		c.getLogger().Warn("retrying", "method", method, "path", path, "attempt", retry, "status", statusCode(resp), "error", err)
+		addRetryEvent(span, retry, err)
+
+		select {
+		case <-ctx.Done():
+			resp, err = nil, ctx.Err()
+			return resp, err
+		case <-time.After(delay):
 		}
-		req.Body = io.NopCloser(buf)
-		req.Header.Set("Content-Type", "application/json")
 	}
 
+	if err == nil && resp != nil && options != nil && options.ResponseInfo != nil {
+		options.ResponseInfo.set(resp.Header)
+	}
+
+	return resp, err
+}
+
+// newRequestHeader returns the headers of a request, from those given by the
+// caller and those which are the same for every attempt
+func newRequestHeader(headers http.Header, options *Options) http.Header {
+	reqHeader := make(http.Header, len(headers)+4)
 	for k, v := range headers {
-		req.Header[textproto.CanonicalMIMEHeaderKey(k)] = v
+		reqHeader[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+
+	if options != nil && options.PartitionKey != "" && reqHeader.Get("X-Ms-Documentdb-Partitionkey") == "" {
+		reqHeader.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(options.PartitionKey))
+	}
+
+	// responses are decompressed here rather than by the transport, which
+	// may not
+	if reqHeader.Get("Accept-Encoding") == "" {
+		reqHeader.Set("Accept-Encoding", "gzip")
+	}
+
+	// operations which need a later API version set it in headers
+	if reqHeader.Get("x-ms-version") == "" {
+		reqHeader.Set("x-ms-version", "2018-12-31")
+	}
+
+	return reqHeader
+}
+
+func (c *databaseClient) getAuthorizer(options *Options) Authorizer {
+	if options != nil && options.Authorizer != nil {
+		return options.Authorizer
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.authorizer
+}
+
+// doAttempt makes a single attempt of a request, limited to the timeout of
+// options
+func (c *databaseClient) doAttempt(ctx context.Context, hostname, method, path, resourceType, resourceLink string, expectedStatusCode int, in, out interface{}, headers, reqHeader http.Header, options *Options) (*http.Response, error) {
+	attemptCtx, cancel := options.withTimeout(ctx)
+
+	start := time.Now()
+	resp, err := c._do(attemptCtx, hostname, method, path, resourceType, resourceLink, expectedStatusCode, in, out, headers, reqHeader, options)
+	if metrics := c.getMetrics(); metrics != nil {
+		observeRequest(metrics, method, path, resourceType, headers, start, resp)
+	}
+
+	// a response body returned to the caller remains subject to the timeout
+	// until it is closed
+	if body, ok := out.(*io.ReadCloser); ok && err == nil && *body != nil {
+		*body = &cancelReadCloser{ReadCloser: *body, cancel: cancel}
+	} else {
+		cancel()
+	}
+
+	if err != nil && attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		err = &Error{StatusCode: http.StatusRequestTimeout, Message: fmt.Sprintf("request timed out after %s", options.Timeout)}
+	}
+
+	return resp, err
+}
+
+// cancelReadCloser cancels the context of a request when its response body is
+// closed
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (rc *cancelReadCloser) Close() error {
+	defer rc.cancel()
+	return rc.ReadCloser.Close()
+}
+
+// maxBufferedRequestSize is the size above which a request body is streamed
+// while it is encoded, rather than encoded into memory first
+const maxBufferedRequestSize = 64 << 10
+
+// errRequestTooLarge is returned by a limitedWriter when its limit is exceeded
+var errRequestTooLarge = fmt.Errorf("request is too large to buffer")
+
+// limitedWriter writes to a buffer up to a limit
+type limitedWriter struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (w *limitedWriter) Write(b []byte) (int, error) {
+	if w.buf.Len()+len(b) > w.limit {
+		return 0, errRequestTooLarge
+	}
+
+	return w.buf.Write(b)
+}
+
+// setRequestBody sets the body of req to in, encoded as JSON unless it is a
+// rawBody.  Bodies up to maxBufferedRequestSize are encoded into a pooled
+// buffer and sent with a Content-Length; larger ones are streamed to the
+// server as they are encoded, so that they are not held in memory.  If request
+// compression is enabled, bodies are gzipped: buffered ones if they reach the
+// client's threshold, and streamed ones always.
+func (c *databaseClient) setRequestBody(req *http.Request, in interface{}) error {
+	req.Header.Del("Content-Encoding")
+
+	if in == nil {
+		return nil
+	}
+
+	if body, ok := in.(rawBody); ok {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		return nil
+	}
+
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
 	}
 
-	req.Header.Set("x-ms-version", "2018-12-31")
+	serializer := c.getSerializer()
+	threshold := c.getRequestCompressionThreshold()
 
-	if c.authorizer != nil {
-		err := c.authorizer.Authorize(ctx, req, resourceType, resourceLink)
+	buf := getBuffer()
+	err := serializer.Encode(&limitedWriter{buf: buf, limit: maxBufferedRequestSize}, in)
+	if err == nil {
+		if threshold > 0 && buf.Len() >= threshold {
+			buf, err = compressBuffer(buf)
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+
+		req.Body = newPooledBody(buf)
+		req.ContentLength = int64(buf.Len())
+		return nil
+	}
+
+	putBuffer(buf)
+
+	if !errors.Is(err, errRequestTooLarge) {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	if threshold > 0 {
+		go func() {
+			zw := getGzipWriter(pw)
+			err := serializer.Encode(zw, in)
+			if err == nil {
+				err = zw.Close()
+			}
+			putGzipWriter(zw)
+			pw.CloseWithError(err)
+		}()
+		req.Header.Set("Content-Encoding", "gzip")
+	} else {
+		go func() {
+			pw.CloseWithError(serializer.Encode(pw, in))
+		}()
+	}
+
+	req.Body = pr
+
+	return nil
+}
+
+// rawBody is a request body which is sent as is, rather than encoded as JSON.
+// Its Content-Type is set by the caller.
+type rawBody []byte
+
+func (c *databaseClient) _do(ctx context.Context, hostname, method, path, resourceType, resourceLink string, expectedStatusCode int, in, out interface{}, headers, reqHeader http.Header, options *Options) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, "https://"+hostname+"/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = reqHeader
+
+	err = c.setRequestBody(req, in)
+	if err != nil {
+		return nil, err
+	}
+
+	// the transport closes the body once it is sent; if the request is not
+	// sent, e.g. because a policy returned a response of its own, close it
+	// here to release it
+	var sent bool
+	defer func() {
+		if !sent && req.Body != nil {
+			req.Body.Close()
+		}
+	}()
+
+	if c.allowTentativeWrites(method, path, headers) {
+		req.Header.Set("X-Ms-Cosmos-Allow-Tentative-Writes", "true")
+	} else {
+		req.Header.Del("X-Ms-Cosmos-Allow-Tentative-Writes")
+	}
+
+	c.setSessionToken(req, method, resourceLink, headers, options)
+
+	authorizer := c.getAuthorizer(options)
+	if authorizer != nil {
+		err := authorizer.Authorize(ctx, req, resourceType, resourceLink)
 		if err != nil {
 			return nil, err
 		}
 	}
-	resp, err := c.hc.Do(req)
+
+	resp, err := c.pipeline(func(req *http.Request) (*http.Response, error) {
+		sent = true
+		return c.hc.Do(req)
+	})(req)
 	if err != nil {
 		return nil, err
 	}
+	decompressResponse(resp)
+
+	// a body which is handed to the caller is read incrementally, so is not
+	// limited
+	var limit *responseLimit
+	if _, ok := out.(*io.ReadCloser); !ok {
+		if maxResponseSize := c.getMaxResponseSize(); maxResponseSize > 0 {
+			limit = limitResponse(req, resp, maxResponseSize)
+		}
+	}
+
+	var keepBody bool
 	defer func() {
-		resp.Body.Read(nil)
-		resp.Body.Close()
+		if !keepBody {
+			// the connection is only reused if the body is read to the end
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
 	}()
 
-	d := codec.NewDecoder(resp.Body, c.jsonHandle)
+	c.updateSessionToken(resp, resourceLink, options)
+
+	serializer := c.getSerializer()
+	body := io.Reader(resp.Body)
+
+	if out, ok := out.(multiStatusResponse); ok && resp.Header.Get("Content-Type") == "application/json" {
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return resp, limit.check(err)
+		}
+
+		err = out.decodeMultiStatus(resp.StatusCode, b)
+		if err == nil || resp.StatusCode == expectedStatusCode {
+			return resp, err
+		}
+
+		// the body is not a multi-status response, so decode it as an error
+		body = bytes.NewReader(b)
+	}
 
 	if resp.StatusCode != expectedStatusCode {
 		err := &Error{}
 		if resp.Header.Get("Content-Type") == "application/json" {
-			d.Decode(&err)
+			serializer.Decode(body, &err)
 		}
 		err.StatusCode = resp.StatusCode
+		err.SubStatusCode, _ = strconv.Atoi(resp.Header.Get("x-ms-substatus"))
 		return resp, err
 	}
 
+	// a caller which asks for the body takes responsibility for closing it
+	if body, ok := out.(*io.ReadCloser); ok {
+		*body = resp.Body
+		keepBody = true
+		return resp, nil
+	}
+
 	if out != nil && resp.Header.Get("Content-Type") == "application/json" {
-		return resp, d.Decode(&out)
+		return resp, limit.check(serializer.Decode(body, out))
 	}
 
 	return resp, nil
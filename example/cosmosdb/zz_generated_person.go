@@ -4,6 +4,7 @@ package cosmosdb
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -21,18 +22,34 @@ type PersonClient interface {
 	Create(context.Context, string, *pkg.Person, *Options) (*pkg.Person, error)
 	List(*Options) PersonIterator
 	ListAll(context.Context, *Options) (*pkg.People, error)
+	ForEach(context.Context, *Options, func(*pkg.Person) error) error
 	Get(context.Context, string, string, *Options) (*pkg.Person, error)
 	Replace(context.Context, string, *pkg.Person, *Options) (*pkg.Person, error)
+	Patch(context.Context, string, string, []PatchOperation, *Options) (*pkg.Person, error)
+	SetTTL(context.Context, string, string, int, *Options) (*pkg.Person, error)
+	ExecuteBatch(context.Context, string, *PersonBatch, *Options) (*PersonBatchResponse, error)
+	ExecuteBulk(context.Context, *PersonBulk, *Options) ([]*PersonBatchResult, error)
 	Delete(context.Context, string, *pkg.Person, *Options) error
 	Query(string, *Query, *Options) PersonRawIterator
 	QueryAll(context.Context, string, *Query, *Options) (*pkg.People, error)
+	QueryPage(context.Context, string, *Query, *PageRequest, *Options) (*pkg.People, *PageRequest, error)
+	ReadMany(context.Context, []ItemIdentity, *Options) (*pkg.People, []ItemIdentity, error)
 	ChangeFeed(*Options) PersonIterator
+	AllVersionsChangeFeed(*Options) PersonChangeFeedItemIterator
+	ChangeFeedProcessor(CollectionClient, string, func(context.Context, *pkg.People) error, *ChangeFeedProcessorOptions) ChangeFeedProcessor
+	ChangeFeedEstimator(CollectionClient, string, *ChangeFeedProcessorOptions) ChangeFeedEstimator
 }
 
 type personChangeFeedIterator struct {
 	*personClient
 	continuation string
 	options      *Options
+	feed         *changeFeed
+	reader       *documentReader
+}
+
+type personChangeFeedItemIterator struct {
+	feed *changeFeed
 }
 
 type personListIterator struct {
@@ -40,27 +57,66 @@ type personListIterator struct {
 	continuation string
 	done         bool
 	options      *Options
+	reader       *documentReader
 }
 
 type personQueryIterator struct {
 	*personClient
-	partitionkey string
-	query        *Query
-	continuation string
-	done         bool
-	options      *Options
+	partitionkey   string
+	query          *Query
+	continuation   string
+	done           bool
+	options        *Options
+	crossPartition *crossPartitionQuery
+	routed         bool
+	metrics        QueryMetrics
+	reader         *documentReader
 }
 
-// PersonIterator is a person iterator
+// PersonIterator is a person iterator.  NextDocument returns documents
+// one at a time, decoding each as it is read from the response; it returns
+// nil when the iterator is exhausted.  Continuation reflects whole pages, so
+// it should not be used after partially reading a page with NextDocument.
 type PersonIterator interface {
 	Next(context.Context, int) (*pkg.People, error)
+	NextDocument(context.Context) (*pkg.Person, error)
 	Continuation() string
+	Items(context.Context) func(func(*pkg.Person, error) bool)
+	ForEach(context.Context, func(*pkg.Person) error) error
+	Stream(context.Context, int) (<-chan *pkg.People, func() error)
+}
+
+// PersonChangeFeedItemIterator is an iterator of the all versions and
+// deletes change feed of a person collection.  Next returns nil if there
+// are no further changes, but later calls return any changes made since.
+type PersonChangeFeedItemIterator interface {
+	Next(context.Context, int) (*PersonChangeFeedItems, error)
+	Continuation() string
+}
+
+// PersonChangeFeedItem represents a change in the all versions and deletes
+// change feed of a person collection.  Current is empty for a delete;
+// Previous is only set for replaces and deletes if the collection retains
+// previous images.
+type PersonChangeFeedItem struct {
+	Current  *pkg.Person        `json:"current,omitempty"`
+	Previous *pkg.Person        `json:"previous,omitempty"`
+	Metadata ChangeFeedMetadata `json:"metadata,omitempty"`
+}
+
+// PersonChangeFeedItems represents a page of changes in the all versions
+// and deletes change feed of a person collection
+type PersonChangeFeedItems struct {
+	Count      int                     `json:"_count,omitempty"`
+	ResourceID string                  `json:"_rid,omitempty"`
+	Items      []*PersonChangeFeedItem `json:"Documents,omitempty"`
 }
 
 // PersonRawIterator is a person raw iterator
 type PersonRawIterator interface {
 	PersonIterator
 	NextRaw(context.Context, int, interface{}) error
+	QueryMetrics() *QueryMetrics
 }
 
 // NewPersonClient returns a new person client
@@ -93,7 +149,7 @@ func (c *personClient) all(ctx context.Context, i PersonIterator) (*pkg.People,
 
 func (c *personClient) Create(ctx context.Context, partitionkey string, newperson *pkg.Person, options *Options) (person *pkg.Person, err error) {
 	headers := http.Header{}
-	headers.Set("X-Ms-Documentdb-Partitionkey", `["`+partitionkey+`"]`)
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
 
 	if options == nil {
 		options = &Options{}
@@ -105,7 +161,7 @@ func (c *personClient) Create(ctx context.Context, partitionkey string, newperso
 		return
 	}
 
-	err = c.do(ctx, http.MethodPost, c.path+"/docs", "docs", c.path, http.StatusCreated, &newperson, &person, headers)
+	_, err = c.send(ctx, http.MethodPost, c.path+"/docs", "docs", c.path, http.StatusCreated, &newperson, &person, headers, options)
 	return
 }
 
@@ -122,42 +178,150 @@ func (c *personClient) ListAll(ctx context.Context, options *Options) (*pkg.Peop
 	return c.all(ctx, c.List(options))
 }
 
+// ForEach calls fn with each Person in the collection, fetching a page at a
+// time, until fn returns an error.  Unlike ListAll, it does not accumulate the
+// People in memory.
+func (c *personClient) ForEach(ctx context.Context, options *Options, fn func(*pkg.Person) error) error {
+	return c.List(options).ForEach(ctx, fn)
+}
+
 func (c *personClient) Get(ctx context.Context, partitionkey, personid string, options *Options) (person *pkg.Person, err error) {
 	headers := http.Header{}
-	headers.Set("X-Ms-Documentdb-Partitionkey", `["`+partitionkey+`"]`)
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
+	if options != nil && options.IfNoneMatch != "" {
+		headers.Set("If-None-Match", options.IfNoneMatch)
+	}
 
 	err = c.setOptions(options, nil, headers)
 	if err != nil {
 		return
 	}
 
-	err = c.do(ctx, http.MethodGet, c.path+"/docs/"+personid, "docs", c.path+"/docs/"+personid, http.StatusOK, nil, &person, headers)
+	_, err = c.send(ctx, http.MethodGet, c.path+"/docs/"+personid, "docs", c.path+"/docs/"+personid, http.StatusOK, nil, &person, headers, options)
 	return
 }
 
 func (c *personClient) Replace(ctx context.Context, partitionkey string, newperson *pkg.Person, options *Options) (person *pkg.Person, err error) {
 	headers := http.Header{}
-	headers.Set("X-Ms-Documentdb-Partitionkey", `["`+partitionkey+`"]`)
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
 
 	err = c.setOptions(options, newperson, headers)
 	if err != nil {
 		return
 	}
 
-	err = c.do(ctx, http.MethodPut, c.path+"/docs/"+newperson.ID, "docs", c.path+"/docs/"+newperson.ID, http.StatusOK, &newperson, &person, headers)
+	_, err = c.send(ctx, http.MethodPut, c.path+"/docs/"+newperson.ID, "docs", c.path+"/docs/"+newperson.ID, http.StatusOK, &newperson, &person, headers, options)
 	return
 }
 
+// Patch applies operations to the Person with the given ID without
+// replacing it, so that no ETag is required
+func (c *personClient) Patch(ctx context.Context, partitionkey, personid string, operations []PatchOperation, options *Options) (person *pkg.Person, err error) {
+	headers := http.Header{}
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
+	headers.Set("X-Ms-Version", patchAPIVersion)
+	headers.Set("Content-Type", "application/json_patch+json")
+
+	err = c.setOptions(options, nil, headers)
+	if err != nil {
+		return
+	}
+
+	patch := &patchRequest{Operations: operations}
+	if options != nil {
+		patch.Condition = options.PatchCondition
+	}
+
+	_, err = c.send(ctx, http.MethodPatch, c.path+"/docs/"+personid, "docs", c.path+"/docs/"+personid, http.StatusOK, patch, &person, headers, options)
+	return
+}
+
+// SetTTL sets the TTL in seconds of the Person with the given ID, without
+// replacing it.  See TTLNoExpiry and TTLOff.
+func (c *personClient) SetTTL(ctx context.Context, partitionkey, personid string, ttl int, options *Options) (*pkg.Person, error) {
+	return c.Patch(ctx, partitionkey, personid, []PatchOperation{ttlPatch(ttl)}, options)
+}
+
+// ExecuteBatch executes the operations of batch atomically.  An error is
+// returned only if the batch could not be executed; if an operation fails,
+// the batch is rolled back and the failure is reported in the response.
+func (c *personClient) ExecuteBatch(ctx context.Context, partitionkey string, batch *PersonBatch, options *Options) (*PersonBatchResponse, error) {
+	operations, err := prepareBatch(batch.operations, options)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := http.Header{}
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
+	setBatchHeaders(headers, true)
+
+	err = c.setOptions(options, nil, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &batchResponse{}
+	err = c.do(ctx, http.MethodPost, c.path+"/docs", "docs", c.path, http.StatusOK, operations, resp, headers, options)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := c.batchResults(resp.results)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PersonBatchResponse{
+		StatusCode: resp.statusCode,
+		Results:    results,
+	}, nil
+}
+
+// ExecuteBulk executes the operations of bulk, grouped into batches by the
+// partition key range to which their partition keys belong.  Unlike a
+// PersonBatch, the operations succeed or fail independently; the result of
+// each is returned in order.
+func (c *personClient) ExecuteBulk(ctx context.Context, bulk *PersonBulk, options *Options) ([]*PersonBatchResult, error) {
+	results, err := c.executeBulk(ctx, c.path, bulk.partitionkeys, bulk.operations, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.batchResults(results)
+}
+
+func (c *personClient) batchResults(results []batchResult) ([]*PersonBatchResult, error) {
+	personResults := make([]*PersonBatchResult, len(results))
+
+	for i, result := range results {
+		personResults[i] = &PersonBatchResult{
+			StatusCode:    result.StatusCode,
+			SubStatusCode: result.SubStatusCode,
+			RequestCharge: result.RequestCharge,
+			ETag:          result.ETag,
+		}
+
+		if len(result.ResourceBody) > 0 {
+			err := c.decode(result.ResourceBody, &personResults[i].Person)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return personResults, nil
+}
+
 func (c *personClient) Delete(ctx context.Context, partitionkey string, person *pkg.Person, options *Options) (err error) {
 	headers := http.Header{}
-	headers.Set("X-Ms-Documentdb-Partitionkey", `["`+partitionkey+`"]`)
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
 
 	err = c.setOptions(options, person, headers)
 	if err != nil {
 		return
 	}
 
-	err = c.do(ctx, http.MethodDelete, c.path+"/docs/"+person.ID, "docs", c.path+"/docs/"+person.ID, http.StatusNoContent, nil, nil, headers)
+	_, err = c.send(ctx, http.MethodDelete, c.path+"/docs/"+person.ID, "docs", c.path+"/docs/"+person.ID, http.StatusNoContent, nil, nil, headers, options)
 	return
 }
 
@@ -167,20 +331,169 @@ func (c *personClient) Query(partitionkey string, query *Query, options *Options
 		continuation = options.Continuation
 	}
 
-	return &personQueryIterator{personClient: c, partitionkey: partitionkey, query: query, options: options, continuation: continuation}
+	i := &personQueryIterator{personClient: c, partitionkey: partitionkey, query: query, options: options, continuation: continuation}
+
+	// without a partition key or range, fan the query out across all
+	// partition key ranges
+	if partitionkey == "" && (options == nil || options.PartitionKeyRangeID == "" && options.PartitionKey == "") {
+		i.crossPartition = c.newCrossPartitionQuery(c.path, query, options, continuation)
+	}
+
+	return i
 }
 
 func (c *personClient) QueryAll(ctx context.Context, partitionkey string, query *Query, options *Options) (*pkg.People, error) {
 	return c.all(ctx, c.Query(partitionkey, query, options))
 }
 
+// QueryPage returns the requested page of the results of query and the
+// request for the following page, or nil if there are no further results
+func (c *personClient) QueryPage(ctx context.Context, partitionkey string, query *Query, page *PageRequest, options *Options) (*pkg.People, *PageRequest, error) {
+	if page.PageSize <= 0 {
+		return nil, nil, ErrInvalidPageRequest
+	}
+
+	if page.Continuation == "" && page.number() > 1 {
+		people, err := c.QueryAll(ctx, partitionkey, page.offsetQuery(query), options)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return people, page.next(len(people.People), ""), nil
+	}
+
+	opts := Options{}
+	if options != nil {
+		opts = *options
+	}
+	opts.Continuation = page.Continuation
+	opts.MaxItemCount = 0
+
+	i := c.Query(partitionkey, query, &opts)
+
+	allpeople := &pkg.People{}
+	for len(allpeople.People) < page.PageSize {
+		people, err := i.Next(ctx, page.PageSize-len(allpeople.People))
+		if err != nil {
+			return nil, nil, err
+		}
+		if people == nil {
+			return allpeople, nil, nil
+		}
+
+		allpeople.Count += people.Count
+		allpeople.ResourceID = people.ResourceID
+		allpeople.People = append(allpeople.People, people.People...)
+	}
+
+	return allpeople, page.next(len(allpeople.People), i.Continuation()), nil
+}
+
+// ReadMany reads the People identified by items, returning those found in
+// the order requested and the identities of those which were not.  The IDs of
+// each partition key are read with a point read or a single query, and
+// partition keys are read concurrently up to Options.MaxDegreeOfParallelism.
+func (c *personClient) ReadMany(ctx context.Context, items []ItemIdentity, options *Options) (*pkg.People, []ItemIdentity, error) {
+	groups := groupReadMany(items)
+	found := make([][]*pkg.Person, len(groups))
+	errs := make([]error, len(groups))
+
+	parallel(options.maxDegreeOfParallelism(), len(groups), func(i int) {
+		g := groups[i]
+
+		if len(g.ids) == 1 {
+			person, err := c.Get(ctx, g.partitionkey, g.ids[0], options)
+			if IsErrorStatusCode(err, http.StatusNotFound) {
+				return
+			}
+			found[i], errs[i] = []*pkg.Person{person}, err
+			return
+		}
+
+		people, err := c.QueryAll(ctx, g.partitionkey, g.query(), options)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		found[i] = people.People
+	})
+
+	byIdentity := map[ItemIdentity]*pkg.Person{}
+	for i, g := range groups {
+		if errs[i] != nil {
+			return nil, nil, errs[i]
+		}
+		for _, person := range found[i] {
+			byIdentity[ItemIdentity{ID: person.ID, PartitionKey: g.partitionkey}] = person
+		}
+	}
+
+	people := &pkg.People{}
+	var missing []ItemIdentity
+	for _, item := range items {
+		if person, ok := byIdentity[item]; ok {
+			people.People = append(people.People, person)
+		} else {
+			missing = append(missing, item)
+		}
+	}
+	people.Count = len(people.People)
+
+	return people, missing, nil
+}
+
 func (c *personClient) ChangeFeed(options *Options) PersonIterator {
 	continuation := ""
 	if options != nil {
 		continuation = options.Continuation
 	}
 
-	return &personChangeFeedIterator{personClient: c, options: options, continuation: continuation}
+	i := &personChangeFeedIterator{personClient: c, options: options, continuation: continuation}
+
+	// without a partition key range, read the change feed of every range
+	if options == nil || options.PartitionKeyRangeID == "" {
+		i.feed = c.newChangeFeed(c.path, options, continuation)
+	}
+
+	return i
+}
+
+// AllVersionsChangeFeed returns an iterator of the all versions and deletes
+// change feed of the collection, which returns every create, replace and
+// delete made since it was first read, or since options.Continuation
+func (c *personClient) AllVersionsChangeFeed(options *Options) PersonChangeFeedItemIterator {
+	continuation := ""
+	if options != nil {
+		continuation = options.Continuation
+	}
+
+	feed := c.newChangeFeed(c.path, options, continuation)
+	feed.allVersions = true
+
+	return &personChangeFeedItemIterator{feed: feed}
+}
+
+// ChangeFeedProcessor returns a ChangeFeedProcessor of the collection which
+// stores its leases in the collection leaseCollID of leasec, or in
+// options.CheckpointStore if it is set, and calls handler with each batch of
+// changes
+func (c *personClient) ChangeFeedProcessor(leasec CollectionClient, leaseCollID string, handler func(context.Context, *pkg.People) error, options *ChangeFeedProcessorOptions) ChangeFeedProcessor {
+	return c.newChangeFeedProcessor(c.path, leasec, leaseCollID, func(ctx context.Context, page *queryPage) error {
+		var people *pkg.People
+		err := c.decodePage(page, &people)
+		if err != nil {
+			return err
+		}
+
+		return handler(ctx, people)
+	}, options)
+}
+
+// ChangeFeedEstimator returns a ChangeFeedEstimator of the change feed
+// processors of the collection configured with the same lease collection and
+// options
+func (c *personClient) ChangeFeedEstimator(leasec CollectionClient, leaseCollID string, options *ChangeFeedProcessorOptions) ChangeFeedEstimator {
+	return c.newChangeFeedProcessor(c.path, leasec, leaseCollID, nil, options)
 }
 
 func (c *personClient) setOptions(options *Options, person *pkg.Person, headers http.Header) error {
@@ -188,6 +501,11 @@ func (c *personClient) setOptions(options *Options, person *pkg.Person, headers
 		return nil
 	}
 
+	err := options.validateTriggers()
+	if err != nil {
+		return err
+	}
+
 	if person != nil && !options.NoETag {
 		if person.ETag == "" {
 			return ErrETagRequired
@@ -203,17 +521,46 @@ func (c *personClient) setOptions(options *Options, person *pkg.Person, headers
 	if len(options.PartitionKeyRangeID) > 0 {
 		headers.Set("X-Ms-Documentdb-PartitionKeyRangeID", options.PartitionKeyRangeID)
 	}
+	if options.IndexingDirective != "" {
+		headers.Set("X-Ms-Indexing-Directive", string(options.IndexingDirective))
+	}
+	options.setFeedHeaders(headers)
 
 	return nil
 }
 
 func (i *personChangeFeedIterator) Next(ctx context.Context, maxItemCount int) (people *pkg.People, err error) {
+	if i.feed != nil {
+		err = i.feed.nextRaw(ctx, maxItemCount, &people)
+		return
+	}
+
+	err = i.next(ctx, maxItemCount, &people)
+	return
+}
+
+func (i *personChangeFeedIterator) NextDocument(ctx context.Context) (person *pkg.Person, err error) {
+	if i.feed != nil {
+		var ok bool
+		ok, err = i.feed.nextDocument(ctx, &person)
+		if !ok {
+			person = nil
+		}
+		return
+	}
+
+	return i.nextDocument(ctx, &i.reader, func(body *io.ReadCloser) error { return i.next(ctx, -1, body) })
+}
+
+func (i *personChangeFeedIterator) next(ctx context.Context, maxItemCount int, out interface{}) (err error) {
 	headers := http.Header{}
 	headers.Set("A-IM", "Incremental feed")
 
 	headers.Set("X-Ms-Max-Item-Count", strconv.Itoa(maxItemCount))
 	if i.continuation != "" {
 		headers.Set("If-None-Match", i.continuation)
+	} else if i.options != nil {
+		i.options.ChangeFeedStartFrom.setHeaders(headers)
 	}
 
 	err = i.setOptions(i.options, nil, headers)
@@ -221,7 +568,7 @@ func (i *personChangeFeedIterator) Next(ctx context.Context, maxItemCount int) (
 		return
 	}
 
-	err = i.do(ctx, http.MethodGet, i.path+"/docs", "docs", i.path, http.StatusOK, nil, &people, headers)
+	err = i.do(ctx, http.MethodGet, i.path+"/docs", "docs", i.path, http.StatusOK, nil, out, headers, i.options)
 	if IsErrorStatusCode(err, http.StatusNotModified) {
 		err = nil
 	}
@@ -235,10 +582,44 @@ func (i *personChangeFeedIterator) Next(ctx context.Context, maxItemCount int) (
 }
 
 func (i *personChangeFeedIterator) Continuation() string {
+	if i.feed != nil {
+		return i.feed.Continuation()
+	}
+
 	return i.continuation
 }
 
+func (i *personChangeFeedIterator) Items(ctx context.Context) func(func(*pkg.Person, error) bool) {
+	return personItems(ctx, i)
+}
+
+func (i *personChangeFeedIterator) ForEach(ctx context.Context, fn func(*pkg.Person) error) error {
+	return personForEach(ctx, i, fn)
+}
+
+func (i *personChangeFeedIterator) Stream(ctx context.Context, buffer int) (<-chan *pkg.People, func() error) {
+	return personStream(ctx, i, buffer)
+}
+
+func (i *personChangeFeedItemIterator) Next(ctx context.Context, maxItemCount int) (items *PersonChangeFeedItems, err error) {
+	err = i.feed.nextRaw(ctx, maxItemCount, &items)
+	return
+}
+
+func (i *personChangeFeedItemIterator) Continuation() string {
+	return i.feed.Continuation()
+}
+
 func (i *personListIterator) Next(ctx context.Context, maxItemCount int) (people *pkg.People, err error) {
+	err = i.next(ctx, maxItemCount, &people)
+	return
+}
+
+func (i *personListIterator) NextDocument(ctx context.Context) (*pkg.Person, error) {
+	return i.nextDocument(ctx, &i.reader, func(body *io.ReadCloser) error { return i.next(ctx, -1, body) })
+}
+
+func (i *personListIterator) next(ctx context.Context, maxItemCount int, out interface{}) (err error) {
 	if i.done {
 		return
 	}
@@ -254,7 +635,7 @@ func (i *personListIterator) Next(ctx context.Context, maxItemCount int) (people
 		return
 	}
 
-	err = i.do(ctx, http.MethodGet, i.path+"/docs", "docs", i.path, http.StatusOK, nil, &people, headers)
+	err = i.do(ctx, http.MethodGet, i.path+"/docs", "docs", i.path, http.StatusOK, nil, out, headers, i.options)
 	if err != nil {
 		return
 	}
@@ -269,22 +650,59 @@ func (i *personListIterator) Continuation() string {
 	return i.continuation
 }
 
+func (i *personListIterator) Items(ctx context.Context) func(func(*pkg.Person, error) bool) {
+	return personItems(ctx, i)
+}
+
+func (i *personListIterator) ForEach(ctx context.Context, fn func(*pkg.Person) error) error {
+	return personForEach(ctx, i, fn)
+}
+
+func (i *personListIterator) Stream(ctx context.Context, buffer int) (<-chan *pkg.People, func() error) {
+	return personStream(ctx, i, buffer)
+}
+
 func (i *personQueryIterator) Next(ctx context.Context, maxItemCount int) (people *pkg.People, err error) {
 	err = i.NextRaw(ctx, maxItemCount, &people)
 	return
 }
 
+func (i *personQueryIterator) NextDocument(ctx context.Context) (person *pkg.Person, err error) {
+	err = i.route(ctx)
+	if err != nil {
+		return
+	}
+
+	if i.crossPartition != nil {
+		var ok bool
+		ok, err = i.crossPartition.nextDocument(ctx, &person)
+		if !ok {
+			person = nil
+		}
+		return
+	}
+
+	return i.nextDocument(ctx, &i.reader, func(body *io.ReadCloser) error { return i.NextRaw(ctx, -1, body) })
+}
+
 func (i *personQueryIterator) NextRaw(ctx context.Context, maxItemCount int, raw interface{}) (err error) {
+	err = i.route(ctx)
+	if err != nil {
+		return
+	}
+
+	if i.crossPartition != nil {
+		return i.crossPartition.nextRaw(ctx, maxItemCount, raw)
+	}
+
 	if i.done {
 		return
 	}
 
 	headers := http.Header{}
 	headers.Set("X-Ms-Max-Item-Count", strconv.Itoa(maxItemCount))
-	headers.Set("X-Ms-Documentdb-Isquery", "True")
-	headers.Set("Content-Type", "application/query+json")
 	if i.partitionkey != "" {
-		headers.Set("X-Ms-Documentdb-Partitionkey", `["`+i.partitionkey+`"]`)
+		headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(i.partitionkey))
 	} else {
 		headers.Set("X-Ms-Documentdb-Query-Enablecrosspartition", "True")
 	}
@@ -297,17 +715,240 @@ func (i *personQueryIterator) NextRaw(ctx context.Context, maxItemCount int, raw
 		return
 	}
 
-	err = i.do(ctx, http.MethodPost, i.path+"/docs", "docs", i.path, http.StatusOK, &i.query, &raw, headers)
+	err = i.doQuery(ctx, i.path+"/docs", "docs", i.path, i.query, raw, headers, i.options)
 	if err != nil {
 		return
 	}
 
+	i.metrics.add(headers)
+
 	i.continuation = headers.Get("X-Ms-Continuation")
 	i.done = i.continuation == ""
 
 	return
 }
 
+// route makes a query by a prefix of a hierarchical partition key a
+// cross-partition query of the partition key ranges which may hold its results
+func (i *personQueryIterator) route(ctx context.Context) (err error) {
+	if i.routed || i.crossPartition != nil {
+		return
+	}
+
+	i.crossPartition, err = i.prefixQuery(ctx, i.path, i.partitionkey, i.query, i.options, i.continuation)
+	if err != nil {
+		return
+	}
+
+	i.routed = true
+	return
+}
+
 func (i *personQueryIterator) Continuation() string {
+	if i.crossPartition != nil {
+		return i.crossPartition.Continuation()
+	}
+
 	return i.continuation
 }
+
+func (i *personQueryIterator) QueryMetrics() *QueryMetrics {
+	if i.crossPartition != nil {
+		metrics := i.crossPartition.metrics
+		return &metrics
+	}
+
+	metrics := i.metrics
+	return &metrics
+}
+
+func (i *personQueryIterator) Items(ctx context.Context) func(func(*pkg.Person, error) bool) {
+	return personItems(ctx, i)
+}
+
+func (i *personQueryIterator) ForEach(ctx context.Context, fn func(*pkg.Person) error) error {
+	return personForEach(ctx, i, fn)
+}
+
+func (i *personQueryIterator) Stream(ctx context.Context, buffer int) (<-chan *pkg.People, func() error) {
+	return personStream(ctx, i, buffer)
+}
+
+// personItems yields each person returned by i, fetching pages of the
+// server's default size
+func personItems(ctx context.Context, i PersonIterator) func(func(*pkg.Person, error) bool) {
+	next := func(ctx context.Context) (*pkg.People, error) { return i.Next(ctx, -1) }
+	return items(ctx, next, func(people *pkg.People) []*pkg.Person { return people.People })
+}
+
+// personForEach calls fn with each person returned by i, fetching pages of
+// the server's default size, until fn returns an error
+func personForEach(ctx context.Context, i PersonIterator, fn func(*pkg.Person) error) error {
+	return forEach(i.Items(ctx), fn)
+}
+
+// personStream fetches pages of the server's default size from i in a
+// goroutine, so that they can be processed while the next is fetched
+func personStream(ctx context.Context, i PersonIterator, buffer int) (<-chan *pkg.People, func() error) {
+	return stream(ctx, func(ctx context.Context) (*pkg.People, error) { return i.Next(ctx, -1) }, buffer)
+}
+
+// nextDocument decodes the next document from the page being read by
+// *reader, calling next to fetch a new page when it is exhausted
+func (c *personClient) nextDocument(ctx context.Context, reader **documentReader, next func(*io.ReadCloser) error) (*pkg.Person, error) {
+	for {
+		if *reader == nil {
+			var body io.ReadCloser
+			err := next(&body)
+			if err != nil || body == nil {
+				return nil, err
+			}
+
+			*reader, err = newDocumentReader(body, c.getSerializer())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var person *pkg.Person
+		ok, err := (*reader).next(&person)
+		if err != nil || !ok {
+			*reader = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return person, nil
+		}
+	}
+}
+
+// PersonBatch is a transactional batch of operations on People which
+// share a partition key
+type PersonBatch struct {
+	operations []batchOperation
+}
+
+// NewPersonBatch returns a new, empty PersonBatch
+func NewPersonBatch() *PersonBatch {
+	return &PersonBatch{}
+}
+
+// Create adds an operation to create person
+func (b *PersonBatch) Create(person *pkg.Person) *PersonBatch {
+	b.operations = append(b.operations, batchOperation{OperationType: BatchOperationTypeCreate, ResourceBody: person})
+	return b
+}
+
+// Upsert adds an operation to create or replace person
+func (b *PersonBatch) Upsert(person *pkg.Person) *PersonBatch {
+	b.operations = append(b.operations, batchOperation{OperationType: BatchOperationTypeUpsert, ResourceBody: person})
+	return b
+}
+
+// Read adds an operation to read the Person with the given ID
+func (b *PersonBatch) Read(personid string) *PersonBatch {
+	b.operations = append(b.operations, batchOperation{OperationType: BatchOperationTypeRead, ID: personid})
+	return b
+}
+
+// Replace adds an operation to replace person
+func (b *PersonBatch) Replace(person *pkg.Person) *PersonBatch {
+	b.operations = append(b.operations, batchOperation{OperationType: BatchOperationTypeReplace, ID: person.ID, ResourceBody: person, IfMatch: person.ETag})
+	return b
+}
+
+// Delete adds an operation to delete person
+func (b *PersonBatch) Delete(person *pkg.Person) *PersonBatch {
+	b.operations = append(b.operations, batchOperation{OperationType: BatchOperationTypeDelete, ID: person.ID, IfMatch: person.ETag})
+	return b
+}
+
+// Patch adds an operation to patch the Person with the given ID
+func (b *PersonBatch) Patch(personid string, operations []PatchOperation) *PersonBatch {
+	b.operations = append(b.operations, batchOperation{OperationType: BatchOperationTypePatch, ID: personid, ResourceBody: &patchRequest{Operations: operations}})
+	return b
+}
+
+// PersonBulk is a set of operations on People with any partition keys,
+// to be executed in bulk
+type PersonBulk struct {
+	partitionkeys []string
+	operations    []batchOperation
+}
+
+// NewPersonBulk returns a new, empty PersonBulk
+func NewPersonBulk() *PersonBulk {
+	return &PersonBulk{}
+}
+
+func (b *PersonBulk) add(partitionkey string, op batchOperation) *PersonBulk {
+	b.partitionkeys = append(b.partitionkeys, partitionkey)
+	b.operations = append(b.operations, op)
+	return b
+}
+
+// Create adds an operation to create person
+func (b *PersonBulk) Create(partitionkey string, person *pkg.Person) *PersonBulk {
+	return b.add(partitionkey, batchOperation{OperationType: BatchOperationTypeCreate, ResourceBody: person})
+}
+
+// Upsert adds an operation to create or replace person
+func (b *PersonBulk) Upsert(partitionkey string, person *pkg.Person) *PersonBulk {
+	return b.add(partitionkey, batchOperation{OperationType: BatchOperationTypeUpsert, ResourceBody: person})
+}
+
+// Read adds an operation to read the Person with the given ID
+func (b *PersonBulk) Read(partitionkey, personid string) *PersonBulk {
+	return b.add(partitionkey, batchOperation{OperationType: BatchOperationTypeRead, ID: personid})
+}
+
+// Replace adds an operation to replace person
+func (b *PersonBulk) Replace(partitionkey string, person *pkg.Person) *PersonBulk {
+	return b.add(partitionkey, batchOperation{OperationType: BatchOperationTypeReplace, ID: person.ID, ResourceBody: person, IfMatch: person.ETag})
+}
+
+// Delete adds an operation to delete person
+func (b *PersonBulk) Delete(partitionkey string, person *pkg.Person) *PersonBulk {
+	return b.add(partitionkey, batchOperation{OperationType: BatchOperationTypeDelete, ID: person.ID, IfMatch: person.ETag})
+}
+
+// Patch adds an operation to patch the Person with the given ID
+func (b *PersonBulk) Patch(partitionkey, personid string, operations []PatchOperation) *PersonBulk {
+	return b.add(partitionkey, batchOperation{OperationType: BatchOperationTypePatch, ID: personid, ResourceBody: &patchRequest{Operations: operations}})
+}
+
+// PersonBatchResponse is the response of a transactional batch.  If the
+// batch failed, StatusCode is that of the operation which caused it to fail
+// and the other operations have status 424 Failed Dependency.
+type PersonBatchResponse struct {
+	StatusCode int
+	Results    []*PersonBatchResult
+}
+
+// PersonBatchResult is the result of an operation of a transactional batch
+type PersonBatchResult struct {
+	StatusCode    int
+	SubStatusCode int
+	RequestCharge float64
+	ETag          string
+	Person        *pkg.Person
+}
+
+// Success returns true if every operation of the batch succeeded
+func (r *PersonBatchResponse) Success() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// ErrorIndex returns the index of the operation which caused the batch to
+// fail, or -1 if it succeeded
+func (r *PersonBatchResponse) ErrorIndex() int {
+	for i, result := range r.Results {
+		if result.StatusCode >= 300 && result.StatusCode != http.StatusFailedDependency {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -3,10 +3,13 @@
 package cosmosdb
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/ugorji/go/codec"
 
@@ -120,7 +123,12 @@ func (c *FakePersonClient) apply(ctx context.Context, partitionkey string, perso
 	}
 
 	if options != nil {
-		err := c.processPreTriggers(ctx, person, options)
+		err := options.validateTriggers()
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.processPreTriggers(ctx, person, options)
 		if err != nil {
 			return nil, err
 		}
@@ -138,7 +146,7 @@ func (c *FakePersonClient) apply(ctx context.Context, partitionkey string, perso
 			return nil, &Error{StatusCode: http.StatusNotFound}
 		}
 
-		if !options.NoETag && person.ETag != existingPerson.ETag {
+		if (options == nil || !options.NoETag) && person.ETag != existingPerson.ETag {
 			return nil, &Error{StatusCode: http.StatusPreconditionFailed}
 		}
 	}
@@ -148,7 +156,7 @@ func (c *FakePersonClient) apply(ctx context.Context, partitionkey string, perso
 			if c.conflictChecker(personToCheck, person) {
 				return nil, &Error{
 					StatusCode: http.StatusConflict,
-					Message:    "Entity with the specified id already exists in the system",
+					Message:    uniqueKeyViolationMessage,
 				}
 			}
 		}
@@ -176,6 +184,169 @@ func (c *FakePersonClient) Replace(ctx context.Context, partitionkey string, per
 	return c.apply(ctx, partitionkey, person, options, false)
 }
 
+// Patch applies patch operations to a Person in the database.  Conditional
+// patches are not implemented.
+func (c *FakePersonClient) Patch(ctx context.Context, partitionkey string, id string, operations []PatchOperation, options *Options) (*pkg.Person, error) {
+	if options != nil && options.PatchCondition != "" {
+		return nil, ErrNotImplemented
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	existingPerson, exists := c.people[id]
+	if !exists {
+		return nil, &Error{StatusCode: http.StatusNotFound}
+	}
+
+	var b []byte
+	err := codec.NewEncoderBytes(&b, c.jsonHandle).Encode(existingPerson)
+	if err != nil {
+		return nil, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var doc interface{}
+	err = d.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err = applyPatch(doc, operations)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err = json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var person *pkg.Person
+	err = codec.NewDecoderBytes(b, c.jsonHandle).Decode(&person)
+	if err != nil {
+		return nil, err
+	}
+
+	person.ETag = fmt.Sprint(c.etag)
+	c.etag++
+
+	c.people[person.ID] = person
+
+	if err = c.updateChangeFeeds(person); err != nil {
+		return nil, err
+	}
+
+	return c.deepCopy(person)
+}
+
+// SetTTL sets the TTL of a Person in the database.  People do not
+// expire.
+func (c *FakePersonClient) SetTTL(ctx context.Context, partitionkey string, id string, ttl int, options *Options) (*pkg.Person, error) {
+	return c.Patch(ctx, partitionkey, id, []PatchOperation{ttlPatch(ttl)}, options)
+}
+
+// ExecuteBatch executes the operations of a PersonBatch, rolling back the
+// database if one fails.  The batch is not isolated from concurrent operations
+// and rolled back changes are not removed from change feeds.
+func (c *FakePersonClient) ExecuteBatch(ctx context.Context, partitionkey string, batch *PersonBatch, options *Options) (*PersonBatchResponse, error) {
+	operations, err := prepareBatch(batch.operations, options)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	snapshot := make(map[string]*pkg.Person, len(c.people))
+	for id, person := range c.people {
+		snapshot[id] = person
+	}
+	c.lock.Unlock()
+
+	response := &PersonBatchResponse{
+		StatusCode: http.StatusOK,
+		Results:    make([]*PersonBatchResult, len(operations)),
+	}
+
+	for i, op := range operations {
+		var person *pkg.Person
+		statusCode := http.StatusOK
+
+		opOptions := &Options{NoETag: op.IfMatch == ""}
+
+		switch op.OperationType {
+		case BatchOperationTypeCreate:
+			person, err = c.Create(ctx, partitionkey, op.ResourceBody.(*pkg.Person), nil)
+			statusCode = http.StatusCreated
+		case BatchOperationTypeUpsert:
+			person, err = c.Replace(ctx, partitionkey, op.ResourceBody.(*pkg.Person), &Options{NoETag: true})
+			if IsErrorStatusCode(err, http.StatusNotFound) {
+				person, err = c.Create(ctx, partitionkey, op.ResourceBody.(*pkg.Person), nil)
+				statusCode = http.StatusCreated
+			}
+		case BatchOperationTypeRead:
+			person, err = c.Get(ctx, partitionkey, op.ID, nil)
+		case BatchOperationTypeReplace:
+			person, err = c.Replace(ctx, partitionkey, op.ResourceBody.(*pkg.Person), opOptions)
+		case BatchOperationTypeDelete:
+			err = c.Delete(ctx, partitionkey, &pkg.Person{ID: op.ID, ETag: op.IfMatch}, opOptions)
+			statusCode = http.StatusNoContent
+		case BatchOperationTypePatch:
+			person, err = c.Patch(ctx, partitionkey, op.ID, op.ResourceBody.(*patchRequest).Operations, nil)
+		default:
+			err = ErrNotImplemented
+		}
+
+		if err, ok := err.(*Error); ok {
+			statusCode = err.StatusCode
+		} else if err != nil {
+			return nil, err
+		}
+
+		response.Results[i] = &PersonBatchResult{StatusCode: statusCode, Person: person}
+		if person != nil {
+			response.Results[i].ETag = person.ETag
+		}
+
+		if statusCode >= 300 {
+			c.lock.Lock()
+			c.people = snapshot
+			c.lock.Unlock()
+
+			response.StatusCode = statusCode
+			for j := range response.Results {
+				if j != i {
+					response.Results[j] = &PersonBatchResult{StatusCode: http.StatusFailedDependency}
+				}
+			}
+			break
+		}
+	}
+
+	return response, nil
+}
+
+// ExecuteBulk executes the operations of a PersonBulk one at a time
+func (c *FakePersonClient) ExecuteBulk(ctx context.Context, bulk *PersonBulk, options *Options) ([]*PersonBatchResult, error) {
+	results := make([]*PersonBatchResult, len(bulk.operations))
+
+	for i, op := range bulk.operations {
+		response, err := c.ExecuteBatch(ctx, bulk.partitionkeys[i], &PersonBatch{operations: []batchOperation{op}}, options)
+		if err != nil {
+			return nil, err
+		}
+
+		results[i] = response.Results[0]
+	}
+
+	return results, nil
+}
+
 // List returns a PersonIterator to list all People in the database
 func (c *FakePersonClient) List(*Options) PersonIterator {
 	c.lock.RLock()
@@ -207,6 +378,11 @@ func (c *FakePersonClient) ListAll(ctx context.Context, options *Options) (*pkg.
 	return iter.Next(ctx, -1)
 }
 
+// ForEach calls fn with each Person in the database
+func (c *FakePersonClient) ForEach(ctx context.Context, options *Options, fn func(*pkg.Person) error) error {
+	return c.List(options).ForEach(ctx, fn)
+}
+
 // Get gets a Person from the database
 func (c *FakePersonClient) Get(ctx context.Context, partitionkey string, id string, options *Options) (*pkg.Person, error) {
 	c.lock.RLock()
@@ -221,9 +397,35 @@ func (c *FakePersonClient) Get(ctx context.Context, partitionkey string, id stri
 		return nil, &Error{StatusCode: http.StatusNotFound}
 	}
 
+	if options != nil && options.IfNoneMatch != "" && options.IfNoneMatch == person.ETag {
+		return nil, &Error{StatusCode: http.StatusNotModified}
+	}
+
 	return c.deepCopy(person)
 }
 
+// ReadMany reads People from the database
+func (c *FakePersonClient) ReadMany(ctx context.Context, items []ItemIdentity, options *Options) (*pkg.People, []ItemIdentity, error) {
+	people := &pkg.People{}
+	var missing []ItemIdentity
+
+	for _, item := range items {
+		person, err := c.Get(ctx, item.PartitionKey, item.ID, options)
+		if IsErrorStatusCode(err, http.StatusNotFound) {
+			missing = append(missing, item)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		people.People = append(people.People, person)
+	}
+	people.Count = len(people.People)
+
+	return people, missing, nil
+}
+
 // Delete deletes a Person from the database
 func (c *FakePersonClient) Delete(ctx context.Context, partitionKey string, person *pkg.Person, options *Options) error {
 	c.lock.Lock()
@@ -262,6 +464,86 @@ func (c *FakePersonClient) ChangeFeed(*Options) PersonIterator {
 	return newIter
 }
 
+// AllVersionsChangeFeed is not implemented by the fake
+func (c *FakePersonClient) AllVersionsChangeFeed(*Options) PersonChangeFeedItemIterator {
+	return &fakePersonErroringChangeFeedItemIterator{err: ErrNotImplemented}
+}
+
+type fakePersonErroringChangeFeedItemIterator struct {
+	err error
+}
+
+func (i *fakePersonErroringChangeFeedItemIterator) Next(context.Context, int) (*PersonChangeFeedItems, error) {
+	return nil, i.err
+}
+
+func (i *fakePersonErroringChangeFeedItemIterator) Continuation() string {
+	return ""
+}
+
+// ChangeFeedProcessor returns a ChangeFeedProcessor which polls a ChangeFeed
+// of the fake, calling handler with each batch of changes.  Leases are not
+// used, so a single processor receives every change.
+func (c *FakePersonClient) ChangeFeedProcessor(leasec CollectionClient, leaseCollID string, handler func(context.Context, *pkg.People) error, options *ChangeFeedProcessorOptions) ChangeFeedProcessor {
+	pollDelay := DefaultFeedPollDelay
+	if options != nil && options.FeedPollDelay != 0 {
+		pollDelay = options.FeedPollDelay
+	}
+
+	return &fakePersonChangeFeedProcessor{
+		c:         c,
+		iterator:  c.ChangeFeed(nil),
+		handler:   handler,
+		pollDelay: pollDelay,
+	}
+}
+
+type fakePersonChangeFeedProcessor struct {
+	c         *FakePersonClient
+	iterator  PersonIterator
+	handler   func(context.Context, *pkg.People) error
+	pollDelay time.Duration
+}
+
+func (p *fakePersonChangeFeedProcessor) Run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		p.c.lock.RLock()
+		people, err := p.iterator.Next(ctx, -1)
+		p.c.lock.RUnlock()
+		if err != nil {
+			return err
+		}
+
+		if people != nil && len(people.People) > 0 {
+			err = p.handler(ctx, people)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(p.pollDelay):
+		}
+	}
+
+	return nil
+}
+
+// ChangeFeedEstimator is not implemented by the fake
+func (c *FakePersonClient) ChangeFeedEstimator(leasec CollectionClient, leaseCollID string, options *ChangeFeedProcessorOptions) ChangeFeedEstimator {
+	return &fakePersonErroringChangeFeedEstimator{err: ErrNotImplemented}
+}
+
+type fakePersonErroringChangeFeedEstimator struct {
+	err error
+}
+
+func (e *fakePersonErroringChangeFeedEstimator) Estimate(context.Context) ([]ChangeFeedEstimate, error) {
+	return nil, e.err
+}
+
 func (c *FakePersonClient) updateChangeFeeds(person *pkg.Person) error {
 	for _, currentIterator := range c.changeFeedIterators {
 		newTpl, err := c.deepCopy(person)
@@ -317,6 +599,36 @@ func (c *FakePersonClient) QueryAll(ctx context.Context, partitionkey string, qu
 	return iter.Next(ctx, -1)
 }
 
+// QueryPage calls a query handler to implement database querying, returning
+// the requested page of its results by number
+func (c *FakePersonClient) QueryPage(ctx context.Context, partitionkey string, query *Query, page *PageRequest, options *Options) (*pkg.People, *PageRequest, error) {
+	if page.PageSize <= 0 {
+		return nil, nil, ErrInvalidPageRequest
+	}
+
+	people, err := c.QueryAll(ctx, partitionkey, query, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	if people == nil {
+		people = &pkg.People{}
+	}
+
+	start := (page.number() - 1) * page.PageSize
+	if start > len(people.People) {
+		start = len(people.People)
+	}
+	end := start + page.PageSize
+	if end > len(people.People) {
+		end = len(people.People)
+	}
+
+	people.People = people.People[start:end]
+	people.Count = len(people.People)
+
+	return people, page.next(people.Count, ""), nil
+}
+
 func NewFakePersonIterator(people []*pkg.Person, continuation int) PersonRawIterator {
 	return &fakePersonIterator{people: people, continuation: continuation}
 }
@@ -357,6 +669,15 @@ func (i *fakePersonIterator) Next(ctx context.Context, maxItemCount int) (*pkg.P
 	}, nil
 }
 
+func (i *fakePersonIterator) NextDocument(ctx context.Context) (*pkg.Person, error) {
+	people, err := i.Next(ctx, 1)
+	if err != nil || people == nil || len(people.People) == 0 {
+		return nil, err
+	}
+
+	return people.People[0], nil
+}
+
 func (i *fakePersonIterator) Continuation() string {
 	if i.continuation >= len(i.people) {
 		return ""
@@ -364,6 +685,22 @@ func (i *fakePersonIterator) Continuation() string {
 	return fmt.Sprintf("%d", i.continuation)
 }
 
+func (i *fakePersonIterator) QueryMetrics() *QueryMetrics {
+	return &QueryMetrics{}
+}
+
+func (i *fakePersonIterator) Items(ctx context.Context) func(func(*pkg.Person, error) bool) {
+	return personItems(ctx, i)
+}
+
+func (i *fakePersonIterator) ForEach(ctx context.Context, fn func(*pkg.Person) error) error {
+	return personForEach(ctx, i, fn)
+}
+
+func (i *fakePersonIterator) Stream(ctx context.Context, buffer int) (<-chan *pkg.People, func() error) {
+	return personStream(ctx, i, buffer)
+}
+
 // NewFakePersonErroringRawIterator returns a PersonRawIterator which
 // whose methods return the given error
 func NewFakePersonErroringRawIterator(err error) PersonRawIterator {
@@ -378,6 +715,10 @@ func (i *fakePersonErroringRawIterator) Next(ctx context.Context, maxItemCount i
 	return nil, i.err
 }
 
+func (i *fakePersonErroringRawIterator) NextDocument(context.Context) (*pkg.Person, error) {
+	return nil, i.err
+}
+
 func (i *fakePersonErroringRawIterator) NextRaw(context.Context, int, interface{}) error {
 	return i.err
 }
@@ -385,3 +726,19 @@ func (i *fakePersonErroringRawIterator) NextRaw(context.Context, int, interface{
 func (i *fakePersonErroringRawIterator) Continuation() string {
 	return ""
 }
+
+func (i *fakePersonErroringRawIterator) QueryMetrics() *QueryMetrics {
+	return &QueryMetrics{}
+}
+
+func (i *fakePersonErroringRawIterator) Items(ctx context.Context) func(func(*pkg.Person, error) bool) {
+	return personItems(ctx, i)
+}
+
+func (i *fakePersonErroringRawIterator) ForEach(ctx context.Context, fn func(*pkg.Person) error) error {
+	return personForEach(ctx, i, fn)
+}
+
+func (i *fakePersonErroringRawIterator) Stream(ctx context.Context, buffer int) (<-chan *pkg.People, func() error) {
+	return personStream(ctx, i, buffer)
+}
@@ -4,6 +4,7 @@ package cosmosdb
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 )
 
@@ -46,6 +47,40 @@ type Triggers struct {
 	Triggers   []*Trigger `json:"Triggers,omitempty"`
 }
 
+// TriggerOptionsError is returned, without a request being sent, if
+// Options.PreTriggers or Options.PostTriggers is invalid: an operation may run
+// at most one trigger of each type, and trigger names must not be empty
+type TriggerOptionsError struct {
+	TriggerType TriggerType
+	Triggers    []string
+}
+
+func (e *TriggerOptionsError) Error() string {
+	return fmt.Sprintf("%s triggers %q are invalid", e.TriggerType, e.Triggers)
+}
+
+// validateTriggers returns a *TriggerOptionsError if the triggers of o are
+// invalid
+func (o *Options) validateTriggers() error {
+	if o == nil {
+		return nil
+	}
+
+	for _, t := range []struct {
+		triggerType TriggerType
+		triggers    []string
+	}{
+		{TriggerTypePre, o.PreTriggers},
+		{TriggerTypePost, o.PostTriggers},
+	} {
+		if len(t.triggers) > 1 || len(t.triggers) == 1 && t.triggers[0] == "" {
+			return &TriggerOptionsError{TriggerType: t.triggerType, Triggers: t.triggers}
+		}
+	}
+
+	return nil
+}
+
 type triggerClient struct {
 	*databaseClient
 	path string
@@ -56,13 +91,17 @@ type TriggerClient interface {
 	Create(context.Context, *Trigger) (*Trigger, error)
 	List() TriggerIterator
 	ListAll(context.Context) (*Triggers, error)
+	Query(*Query) TriggerIterator
+	QueryAll(context.Context, *Query) (*Triggers, error)
 	Get(context.Context, string) (*Trigger, error)
 	Delete(context.Context, *Trigger) error
 	Replace(context.Context, *Trigger) (*Trigger, error)
+	Upsert(context.Context, *Trigger) (*Trigger, error)
 }
 
 type triggerListIterator struct {
 	*triggerClient
+	query        *Query
 	continuation string
 	done         bool
 }
@@ -70,6 +109,7 @@ type triggerListIterator struct {
 // TriggerIterator is a trigger iterator
 type TriggerIterator interface {
 	Next(context.Context) (*Triggers, error)
+	Items(context.Context) func(func(*Trigger, error) bool)
 }
 
 // NewTriggerClient returns a new trigger client
@@ -101,7 +141,7 @@ func (c *triggerClient) all(ctx context.Context, i TriggerIterator) (*Triggers,
 }
 
 func (c *triggerClient) Create(ctx context.Context, newtrigger *Trigger) (trigger *Trigger, err error) {
-	err = c.do(ctx, http.MethodPost, c.path+"/triggers", "triggers", c.path, http.StatusCreated, &newtrigger, &trigger, nil)
+	err = c.do(ctx, http.MethodPost, c.path+"/triggers", "triggers", c.path, http.StatusCreated, &newtrigger, &trigger, nil, nil)
 	return
 }
 
@@ -113,8 +153,16 @@ func (c *triggerClient) ListAll(ctx context.Context) (*Triggers, error) {
 	return c.all(ctx, c.List())
 }
 
+func (c *triggerClient) Query(query *Query) TriggerIterator {
+	return &triggerListIterator{triggerClient: c, query: query}
+}
+
+func (c *triggerClient) QueryAll(ctx context.Context, query *Query) (*Triggers, error) {
+	return c.all(ctx, c.Query(query))
+}
+
 func (c *triggerClient) Get(ctx context.Context, triggerid string) (trigger *Trigger, err error) {
-	err = c.do(ctx, http.MethodGet, c.path+"/triggers/"+triggerid, "triggers", c.path+"/triggers/"+triggerid, http.StatusOK, nil, &trigger, nil)
+	err = c.do(ctx, http.MethodGet, c.path+"/triggers/"+triggerid, "triggers", c.path+"/triggers/"+triggerid, http.StatusOK, nil, &trigger, nil, nil)
 	return
 }
 
@@ -124,14 +172,36 @@ func (c *triggerClient) Delete(ctx context.Context, trigger *Trigger) error {
 	}
 	headers := http.Header{}
 	headers.Set("If-Match", trigger.ETag)
-	return c.do(ctx, http.MethodDelete, c.path+"/triggers/"+trigger.ID, "triggers", c.path+"/triggers/"+trigger.ID, http.StatusNoContent, nil, nil, headers)
+	return c.do(ctx, http.MethodDelete, c.path+"/triggers/"+trigger.ID, "triggers", c.path+"/triggers/"+trigger.ID, http.StatusNoContent, nil, nil, headers, nil)
 }
 
 func (c *triggerClient) Replace(ctx context.Context, newtrigger *Trigger) (trigger *Trigger, err error) {
-	err = c.do(ctx, http.MethodPost, c.path+"/triggers/"+newtrigger.ID, "triggers", c.path+"/triggers/"+newtrigger.ID, http.StatusCreated, &newtrigger, &trigger, nil)
+	headers := http.Header{}
+	if newtrigger.ETag != "" {
+		headers.Set("If-Match", newtrigger.ETag)
+	}
+
+	err = c.do(ctx, http.MethodPut, c.path+"/triggers/"+newtrigger.ID, "triggers", c.path+"/triggers/"+newtrigger.ID, http.StatusOK, &newtrigger, &trigger, headers, nil)
 	return
 }
 
+// Upsert replaces the trigger with newtrigger's ID, or creates it if it does
+// not exist
+func (c *triggerClient) Upsert(ctx context.Context, newtrigger *Trigger) (*Trigger, error) {
+	trigger, err := c.Replace(ctx, newtrigger)
+	if !IsErrorStatusCode(err, http.StatusNotFound) {
+		return trigger, err
+	}
+
+	trigger, err = c.Create(ctx, newtrigger)
+	if !IsErrorStatusCode(err, http.StatusConflict) {
+		return trigger, err
+	}
+
+	// the trigger was created concurrently
+	return c.Replace(ctx, newtrigger)
+}
+
 func (i *triggerListIterator) Next(ctx context.Context) (triggers *Triggers, err error) {
 	if i.done {
 		return
@@ -142,7 +212,11 @@ func (i *triggerListIterator) Next(ctx context.Context) (triggers *Triggers, err
 		headers.Set("X-Ms-Continuation", i.continuation)
 	}
 
-	err = i.do(ctx, http.MethodGet, i.path+"/triggers", "triggers", i.path, http.StatusOK, nil, &triggers, headers)
+	if i.query != nil {
+		err = i.doQuery(ctx, i.path+"/triggers", "triggers", i.path, i.query, &triggers, headers, nil)
+	} else {
+		err = i.do(ctx, http.MethodGet, i.path+"/triggers", "triggers", i.path, http.StatusOK, nil, &triggers, headers, nil)
+	}
 	if err != nil {
 		return
 	}
@@ -152,3 +226,7 @@ func (i *triggerListIterator) Next(ctx context.Context) (triggers *Triggers, err
 
 	return
 }
+
+func (i *triggerListIterator) Items(ctx context.Context) func(func(*Trigger, error) bool) {
+	return items(ctx, i.Next, func(triggers *Triggers) []*Trigger { return triggers.Triggers })
+}
@@ -2,22 +2,29 @@ package example
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"os"
 	"testing"
+	"testing/fstest"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"github.com/ugorji/go/codec"
 
 	"github.com/bennerv/go-cosmosdb/example/cosmosdb"
 	"github.com/bennerv/go-cosmosdb/example/types"
+	"github.com/bennerv/go-cosmosdb/pkg/prommetrics"
 )
 
 const (
 	dbid      = "testdb"
 	collid    = "people"
 	triggerid = "trigger"
+	sprocid   = "sproc"
+	countid   = "count"
+	udfid     = "fullName"
 	personid  = "jim"
 	userid    = "reader"
 	permid    = "reader-perm"
@@ -29,6 +36,25 @@ const (
 	body["updateTime"] = ts.getTime();
 	request.setBody(body);
 }`
+
+	udfbody = `function fullName(person) {
+	return person.givenName + " " + person.surname;
+}`
+
+	sprocbody = `function sproc(name) {
+	console.log("greeting " + name);
+	getContext().getResponse().setBody("hello, " + name);
+}`
+
+	countbody = `function count(n, continuation) {
+	var start = continuation || 0;
+	var end = Math.min(start + 2, n);
+	var results = [];
+	for (var i = start; i < end; i++) {
+		results.push(i);
+	}
+	getContext().getResponse().setBody({results: results, continuation: end < n ? end : null});
+}`
 )
 
 const oneYear = time.Hour * 24 * 365
@@ -60,7 +86,23 @@ func TestE2E(t *testing.T) {
 		t.Error(err)
 	}
 
-	dbc := cosmosdb.NewDatabaseClient(log, http.DefaultClient, jsonHandle, account+".documents.azure.com", keyAuthorizer)
+	hc := &http.Client{Transport: cosmosdb.NewTransport(&cosmosdb.TransportOptions{MaxIdleConnsPerHost: 32})}
+
+	dbc := cosmosdb.NewDatabaseClient(log, hc, jsonHandle, account+".documents.azure.com", keyAuthorizer)
+	dbc.SetRetryPolicy(&cosmosdb.DefaultRetryPolicy{Attempts: 5})
+	dbc.EnableCircuitBreaker(nil)
+
+	metrics, err := prommetrics.NewMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbc.SetMetrics(metrics)
+
+	dbaccount, err := dbc.GetDatabaseAccount(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", dbaccount)
 
 	db, err := dbc.Create(ctx, &cosmosdb.Database{ID: dbid})
 	if err != nil {
@@ -100,16 +142,61 @@ func TestE2E(t *testing.T) {
 	}
 	t.Logf("%#v\n", user)
 
+	user, err = userc.Replace(ctx, user)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", user)
+
 	collc := cosmosdb.NewCollectionClient(dbc, dbid)
 
-	coll, err := collc.Create(ctx, &cosmosdb.Collection{
-		ID: collid,
+	coll, err := collc.CreateWithThroughput(ctx, &cosmosdb.Collection{
+		ID:         collid,
+		DefaultTTL: cosmosdb.TTLNoExpiry,
+		IndexingPolicy: &cosmosdb.IndexingPolicy{
+			IndexingMode: cosmosdb.IndexingPolicyModeConsistent,
+			IncludedPaths: []cosmosdb.IncludedPath{
+				{
+					Path: "/*",
+				},
+			},
+			SpatialIndexes: []cosmosdb.SpatialIndex{
+				{
+					Path: "/location/*",
+					Types: []cosmosdb.IndexDataType{
+						cosmosdb.IndexDataTypePoint,
+					},
+				},
+			},
+		},
+		GeospatialConfig: &cosmosdb.GeospatialConfig{
+			Type: cosmosdb.GeospatialConfigTypeGeography,
+		},
+		ComputedProperties: []cosmosdb.ComputedProperty{
+			{
+				Name:  "lowerSurname",
+				Query: "SELECT VALUE LOWER(c.surname) FROM c",
+			},
+		},
 		PartitionKey: &cosmosdb.PartitionKey{
 			Paths: []string{
 				"/id",
 			},
 		},
-	})
+		UniqueKeyPolicy: &cosmosdb.UniqueKeyPolicy{
+			UniqueKeys: []cosmosdb.UniqueKey{
+				{
+					Paths: []string{
+						"/surname",
+					},
+				},
+			},
+		},
+		ConflictResolutionPolicy: &cosmosdb.ConflictResolutionPolicy{
+			Mode:                   cosmosdb.ConflictResolutionPolicyModeLastWriterWins,
+			ConflictResolutionPath: "/_ts",
+		},
+	}, &cosmosdb.Throughput{Throughput: 400})
 	if err != nil {
 		t.Error(err)
 	}
@@ -127,12 +214,41 @@ func TestE2E(t *testing.T) {
 	}
 	t.Logf("%#v\n", coll)
 
+	coll.IndexingPolicy.ExcludedPaths = append(coll.IndexingPolicy.ExcludedPaths, cosmosdb.ExcludedPath{
+		Path: "/address/*",
+	})
+	coll, err = collc.Replace(ctx, coll)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", coll.IndexingPolicy)
+
 	pkrs, err := collc.PartitionKeyRanges(ctx, collid)
 	if err != nil {
 		t.Error(err)
 	}
 	t.Logf("%#v\n", pkrs)
 
+	pkr, err := collc.ResolvePartitionKeyRange(ctx, collid, personid)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", pkr)
+
+	conflicts, err := cosmosdb.NewConflictClient(collc, collid).ListAll(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", conflicts)
+
+	offerc := cosmosdb.NewOfferClient(dbc)
+
+	offers, err := offerc.ListAll(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", offers)
+
 	triggerc := cosmosdb.NewTriggerClient(collc, collid)
 
 	trigger, err := triggerc.Create(ctx, &cosmosdb.Trigger{
@@ -152,12 +268,115 @@ func TestE2E(t *testing.T) {
 	}
 	t.Logf("%#v\n", triggers)
 
+	triggers, err = triggerc.QueryAll(ctx, &cosmosdb.Query{
+		Query: "SELECT * FROM root WHERE STARTSWITH(root.id, @prefix)",
+		Parameters: []cosmosdb.Parameter{
+			{
+				Name:  "@prefix",
+				Value: triggerid[:3],
+			},
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(triggers.Triggers) != 1 {
+		t.Error(len(triggers.Triggers))
+	}
+
 	trigger, err = triggerc.Get(ctx, triggerid)
 	if err != nil {
 		t.Error(err)
 	}
 	t.Logf("%#v\n", trigger)
 
+	trigger, err = triggerc.Replace(ctx, trigger)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", trigger)
+
+	trigger, err = triggerc.Upsert(ctx, trigger)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", trigger)
+
+	sprocc := cosmosdb.NewStoredProcedureClient(collc, collid)
+
+	sproc, err := sprocc.Create(ctx, &cosmosdb.StoredProcedure{
+		ID:   sprocid,
+		Body: sprocbody,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", sproc)
+
+	sprocs, err := sprocc.ListAll(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", sprocs)
+
+	sproc, err = sprocc.Replace(ctx, sproc)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", sproc)
+
+	err = cosmosdb.SyncScripts(ctx, collc, collid, fstest.MapFS{
+		"sprocs/" + sprocid + ".js": {Data: []byte(sprocbody)},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	udfc := cosmosdb.NewUDFClient(collc, collid)
+
+	udf, err := udfc.Create(ctx, &cosmosdb.UDF{
+		ID:   udfid,
+		Body: udfbody,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", udf)
+
+	udfs, err := udfc.ListAll(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", udfs)
+
+	var greeting string
+	info := &cosmosdb.ResponseInfo{}
+	err = sprocc.Execute(ctx, sprocid, personid, []interface{}{"world"}, &greeting, &cosmosdb.Options{EnableScriptLogging: true, ResponseInfo: info})
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v %#v\n", greeting, info.ScriptLog)
+	if greeting != "hello, world" {
+		t.Error(greeting)
+	}
+
+	_, err = sprocc.Create(ctx, &cosmosdb.StoredProcedure{
+		ID:   countid,
+		Body: countbody,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	var counts []int
+	err = sprocc.ExecuteAll(ctx, countid, personid, []interface{}{5}, &counts, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(counts) != 5 {
+		t.Error(counts)
+	}
+
 	permc := cosmosdb.NewPermissionClient(userc, userid)
 
 	perm, err := permc.Create(ctx, &cosmosdb.Permission{
@@ -182,6 +401,12 @@ func TestE2E(t *testing.T) {
 	}
 	t.Logf("%#v\n", perm)
 
+	perm, err = permc.Replace(ctx, perm)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", perm)
+
 	dc := cosmosdb.NewPersonClient(collc, collid)
 
 	doc, err := dc.Create(ctx, personid, &types.Person{
@@ -193,17 +418,105 @@ func TestE2E(t *testing.T) {
 	}
 	t.Logf("%#v\n", doc)
 
+	_, err = dc.Create(ctx, personid, &types.Person{
+		ID:      personid,
+		Surname: "Minter",
+	}, nil)
+	if !cosmosdb.IsDuplicateID(err) {
+		t.Error(err)
+	}
+
+	_, err = dc.Create(ctx, personid, &types.Person{
+		ID:      personid,
+		Surname: "Minter",
+	}, &cosmosdb.Options{PreTriggers: []string{triggerid, triggerid}})
+	if _, ok := err.(*cosmosdb.TriggerOptionsError); !ok {
+		t.Error(err)
+	}
+
+	attc := cosmosdb.NewAttachmentClient(collc, collid, personid, personid)
+
+	att, err := attc.Create(ctx, &cosmosdb.Attachment{
+		ID:          "photo",
+		ContentType: "image/jpeg",
+		Media:       "https://example.blob.core.windows.net/photos/jim.jpg",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", att)
+
+	att, err = attc.CreateMedia(ctx, "notes", "text/plain", []byte("hello"))
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", att)
+
+	media, err := attc.GetMedia(ctx, att)
+	if err != nil {
+		t.Error(err)
+	} else {
+		b, err := io.ReadAll(media)
+		media.Close()
+		if err != nil || string(b) != "hello" {
+			t.Error(string(b), err)
+		}
+	}
+
+	atts, err := attc.ListAll(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", atts)
+
 	docs, err := dc.ListAll(ctx, nil)
 	if err != nil {
 		t.Error(err)
 	}
 	t.Logf("%#v\n", docs)
 
-	doc, err = dc.Get(ctx, personid, personid, nil)
+	pages, wait := dc.List(nil).Stream(ctx, 1)
+	for page := range pages {
+		t.Logf("%#v\n", page)
+	}
+	err = wait()
+	if err != nil {
+		t.Error(err)
+	}
+
+	first, err := dc.List(nil).NextDocument(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", first)
+
+	var foreachCount int
+	err = dc.ForEach(ctx, nil, func(person *types.Person) error {
+		foreachCount++
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if foreachCount != len(docs.People) {
+		t.Error(foreachCount)
+	}
+
+	info = &cosmosdb.ResponseInfo{}
+	doc, err = dc.Get(ctx, personid, personid, &cosmosdb.Options{Timeout: 10 * time.Second, MaxRetryDuration: 5 * time.Second, ResponseInfo: info})
 	if err != nil {
 		t.Error(err)
 	}
 	t.Logf("%#v\n", doc)
+	t.Logf("request charge %f, activity id %s\n", info.RequestCharge, info.ActivityID)
+	if pkr != nil && info.PartitionKeyRangeID != pkr.ID {
+		t.Errorf("partition key range %s, resolved %s", info.PartitionKeyRangeID, pkr.ID)
+	}
+
+	_, err = dc.Get(ctx, personid, personid, &cosmosdb.Options{IfNoneMatch: doc.ETag})
+	if !cosmosdb.IsNotModified(err) {
+		t.Error(err)
+	}
 
 	docs, err = dc.QueryAll(ctx, personid, &cosmosdb.Query{
 		Query: "SELECT * FROM people WHERE people.surname = @surname",
@@ -219,6 +532,70 @@ func TestE2E(t *testing.T) {
 	}
 	t.Logf("%#v\n", docs)
 
+	docs, err = dc.QueryAll(ctx, personid, cosmosdb.NewQueryBuilder().WhereEquals("c.surname", "Minter").OrderBy("c.id").Build(), nil)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", docs)
+
+	docs, page, err := dc.QueryPage(ctx, personid, cosmosdb.NewQueryBuilder().OrderBy("c.id").Build(), &cosmosdb.PageRequest{Page: 1, PageSize: 10}, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v %#v\n", docs, page)
+
+	surnames, err := cosmosdb.QueryAs[struct {
+		Surname string `json:"surname"`
+	}, cosmosdb.PersonRawIterator](ctx, dc, personid, &cosmosdb.Query{Query: "SELECT people.surname FROM people"}, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", surnames)
+
+	qi := dc.Query(personid, &cosmosdb.Query{Query: "SELECT * FROM people"}, &cosmosdb.Options{PopulateQueryMetrics: true, PopulateIndexMetrics: true})
+	docs, err = qi.Next(ctx, -1)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", docs)
+	t.Logf("%#v\n", qi.QueryMetrics())
+
+	var count struct {
+		Documents []int
+	}
+	err = dc.Query("", &cosmosdb.Query{Query: "SELECT VALUE COUNT(1) FROM people"}, nil).NextRaw(ctx, -1, &count)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", count)
+	if len(count.Documents) != 1 || count.Documents[0] != 1 {
+		t.Error(count.Documents)
+	}
+
+	feedRanges, err := collc.FeedRanges(ctx, collid)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", feedRanges)
+	if len(feedRanges) > 0 {
+		halves, err := feedRanges[0].Split(2)
+		if err != nil {
+			t.Error(err)
+		}
+		total := 0
+		for i := range halves {
+			docs, err := dc.QueryAll(ctx, "", &cosmosdb.Query{Query: "SELECT * FROM people"}, &cosmosdb.Options{FeedRange: &halves[i]})
+			if err != nil {
+				t.Error(err)
+			}
+			total += len(docs.People)
+		}
+		t.Logf("%#v\n", total)
+		if total != 1 {
+			t.Error(total)
+		}
+	}
+
 	i := dc.ChangeFeed(nil)
 	docs, err = i.Next(ctx, 1)
 	if err != nil {
@@ -238,6 +615,24 @@ func TestE2E(t *testing.T) {
 		t.Error(docs)
 	}
 
+	docs, err = dc.ChangeFeed(&cosmosdb.Options{PartitionKey: personid}).Next(ctx, -1)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", docs)
+	if len(docs.People) != 1 {
+		t.Error(len(docs.People))
+	}
+
+	docs, err = dc.ChangeFeed(&cosmosdb.Options{ChangeFeedStartFrom: &cosmosdb.ChangeFeedStartFrom{Now: true}}).Next(ctx, 1)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", docs)
+	if docs != nil {
+		t.Error(docs)
+	}
+
 	oldETag := doc.ETag
 	doc, err = dc.Replace(ctx, personid, &types.Person{
 		ID:      personid,
@@ -276,6 +671,54 @@ func TestE2E(t *testing.T) {
 		t.Error(docs)
 	}
 
+	doc, err = dc.Patch(ctx, personid, personid, []cosmosdb.PatchOperation{
+		{
+			Op:    cosmosdb.PatchOperationTypeSet,
+			Path:  "/surname",
+			Value: "Henson",
+		},
+	}, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", doc)
+	if doc.Surname != "Henson" {
+		t.Error(doc.Surname)
+	}
+
+	doc, err = dc.SetTTL(ctx, personid, personid, 3600, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", doc)
+	if doc.TTL != 3600 {
+		t.Error(doc.TTL)
+	}
+
+	batch, err := dc.ExecuteBatch(ctx, personid, cosmosdb.NewPersonBatch().Read(personid), nil)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", batch)
+	if !batch.Success() {
+		t.Error(batch.ErrorIndex())
+	}
+
+	results, err := dc.ExecuteBulk(ctx, cosmosdb.NewPersonBulk().Read(personid, personid), nil)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", results)
+
+	docs, missing, err := dc.ReadMany(ctx, []cosmosdb.ItemIdentity{{ID: personid, PartitionKey: personid}, {ID: "missing", PartitionKey: "missing"}}, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v %#v\n", docs, missing)
+	if len(docs.People) != 1 || len(missing) != 1 {
+		t.Error(len(docs.People), len(missing))
+	}
+
 	// dummy token expiration auth
 	tokenAuth := cosmosdb.NewTokenAuthorizer(perm.Token, time.Now().Add(oneYear), func(ctx context.Context) (token string, newExpiration time.Time, err error) {
 		return perm.Token, time.Now().Add(oneYear), nil
@@ -291,6 +734,69 @@ func TestE2E(t *testing.T) {
 	}
 	t.Logf("%#v\n", doc)
 
+	resourceTokenAuth := cosmosdb.NewResourceTokenAuthorizer()
+	resourceTokenAuth.SetPermission(perm)
+
+	resourcetokendbc := cosmosdb.NewDatabaseClient(log, http.DefaultClient, jsonHandle, account+".documents.azure.com", resourceTokenAuth)
+	resourcetokencollc := cosmosdb.NewCollectionClient(resourcetokendbc, dbid)
+	resourcetokendc := cosmosdb.NewPersonClient(resourcetokencollc, collid)
+
+	doc, err = resourcetokendc.Get(ctx, personid, personid, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", doc)
+
+	// a broker for writes replaces the read-only permission of a broker for
+	// reads
+	readbrokerdbc := cosmosdb.NewDatabaseClient(log, http.DefaultClient, jsonHandle, account+".documents.azure.com", cosmosdb.NewResourceTokenBroker(permc, cosmosdb.PermissionModeRead))
+	readbrokerdc := cosmosdb.NewPersonClient(cosmosdb.NewCollectionClient(readbrokerdbc, dbid), collid)
+
+	doc, err = readbrokerdc.Get(ctx, personid, personid, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", doc)
+
+	brokerdbc := cosmosdb.NewDatabaseClient(log, http.DefaultClient, jsonHandle, account+".documents.azure.com", cosmosdb.NewResourceTokenBroker(permc, cosmosdb.PermissionModeAll))
+	brokerdc := cosmosdb.NewPersonClient(cosmosdb.NewCollectionClient(brokerdbc, dbid), collid)
+
+	doc, err = brokerdc.Replace(ctx, personid, doc, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", doc)
+
+	perms, err = permc.ListAll(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if perms != nil {
+		for _, p := range perms.Permissions {
+			if p.ID != permid && p.PermissionMode != cosmosdb.PermissionModeAll {
+				t.Error(p.ID, p.PermissionMode)
+			}
+		}
+	}
+
+	jsondbc := cosmosdb.NewDatabaseClient(log, http.DefaultClient, jsonHandle, account+".documents.azure.com", keyAuthorizer)
+	jsondbc.SetSerializer(&cosmosdb.JSONSerializer{})
+	jsondc := cosmosdb.NewPersonClient(cosmosdb.NewCollectionClient(jsondbc, dbid), collid)
+
+	doc, err = jsondc.Get(ctx, personid, personid, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%#v\n", doc)
+
+	rawdc := cosmosdb.NewRawDocumentClient(collc, collid)
+
+	rawdoc, err := rawdc.Get(ctx, personid, personid, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Logf("%s\n", rawdoc)
+
 	err = permc.Delete(ctx, perm)
 	if err != nil {
 		t.Error(err)
@@ -13,6 +13,7 @@ type Person struct {
 
 	Surname    string `json:"surname,omitempty"`
 	UpdateTime string `json:"updateTime,omitempty"`
+	TTL        int    `json:"ttl,omitempty"`
 }
 
 // People represents people
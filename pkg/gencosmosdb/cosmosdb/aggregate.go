@@ -0,0 +1,436 @@
+package cosmosdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// aggregator folds the partial results of a single aggregate function
+type aggregator struct {
+	kind  string
+	sum   float64
+	count float64
+	value interface{}
+	found bool
+}
+
+func newAggregator(kind string) (*aggregator, error) {
+	switch kind {
+	case "Count", "Sum", "Min", "Max", "Average":
+	default:
+		return nil, fmt.Errorf("unsupported aggregate %q", kind)
+	}
+
+	return &aggregator{kind: kind}, nil
+}
+
+// add folds the aggregate item {"item": partial} into a.  The item is empty
+// when the partial aggregate is undefined.
+func (a *aggregator) add(item json.RawMessage) error {
+	var wrapper map[string]json.RawMessage
+	err := json.Unmarshal(item, &wrapper)
+	if err != nil {
+		return err
+	}
+
+	partial, found := wrapper["item"]
+	if !found {
+		return nil
+	}
+
+	switch a.kind {
+	case "Count", "Sum":
+		var n float64
+		err := json.Unmarshal(partial, &n)
+		if err != nil {
+			return err
+		}
+		a.sum += n
+
+	case "Average":
+		var avg struct {
+			Sum   *float64 `json:"sum"`
+			Count float64  `json:"count"`
+		}
+		err := json.Unmarshal(partial, &avg)
+		if err != nil {
+			return err
+		}
+		if avg.Sum == nil || avg.Count == 0 {
+			return nil
+		}
+		a.sum += *avg.Sum
+		a.count += avg.Count
+
+	case "Min", "Max":
+		var v interface{}
+		err := json.Unmarshal(partial, &v)
+		if err != nil {
+			return err
+		}
+
+		// newer gateways return {"min"|"max": value, "count": n}
+		if m, ok := v.(map[string]interface{}); ok {
+			if count, ok := m["count"].(float64); ok {
+				if count == 0 {
+					return nil
+				}
+				key := "min"
+				if a.kind == "Max" {
+					key = "max"
+				}
+				if v, ok = m[key]; !ok {
+					return nil
+				}
+			}
+		}
+
+		if !a.found ||
+			a.kind == "Min" && compareValues(v, a.value) < 0 ||
+			a.kind == "Max" && compareValues(v, a.value) > 0 {
+			a.value = v
+		}
+	}
+
+	a.found = true
+
+	return nil
+}
+
+// result returns the combined aggregate, or false if it is undefined
+func (a *aggregator) result() (interface{}, bool) {
+	switch a.kind {
+	case "Count":
+		return a.sum, true
+	case "Sum":
+		return a.sum, a.found
+	case "Average":
+		if a.count == 0 {
+			return nil, false
+		}
+		return a.sum / a.count, true
+	}
+
+	return a.value, a.found
+}
+
+// group combines the payloads returned for a single group of a query.
+// SELECT VALUE queries have a single, unnamed projection; other queries have
+// one projection per alias, each of which is either aggregated or taken from
+// the first payload which defines it.
+type group struct {
+	hasSelectValue bool
+	aliases        []string
+	aggregators    map[string]*aggregator
+	values         map[string]json.RawMessage
+}
+
+func newGroup(info *queryInfo) (*group, error) {
+	g := &group{
+		hasSelectValue: info.HasSelectValue,
+		aggregators:    map[string]*aggregator{},
+		values:         map[string]json.RawMessage{},
+	}
+
+	if info.HasSelectValue {
+		switch len(info.Aggregates) {
+		case 0:
+		case 1:
+			a, err := newAggregator(info.Aggregates[0])
+			if err != nil {
+				return nil, err
+			}
+			g.aggregators[""] = a
+		default:
+			return nil, fmt.Errorf("unsupported query: SELECT VALUE with %d aggregates", len(info.Aggregates))
+		}
+
+		return g, nil
+	}
+
+	g.aliases = info.GroupByAliases
+	if len(g.aliases) == 0 {
+		for alias := range info.GroupByAliasToAggregateType {
+			g.aliases = append(g.aliases, alias)
+		}
+		sort.Strings(g.aliases)
+	}
+
+	for alias, kind := range info.GroupByAliasToAggregateType {
+		if kind == nil {
+			continue
+		}
+		a, err := newAggregator(*kind)
+		if err != nil {
+			return nil, err
+		}
+		g.aggregators[alias] = a
+	}
+
+	return g, nil
+}
+
+// add folds a payload into g
+func (g *group) add(payload json.RawMessage) error {
+	if g.hasSelectValue {
+		return g.addProjection("", payload)
+	}
+
+	var projections map[string]json.RawMessage
+	err := json.Unmarshal(payload, &projections)
+	if err != nil {
+		return err
+	}
+
+	for alias, projection := range projections {
+		err = g.addProjection(alias, projection)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *group) addProjection(alias string, projection json.RawMessage) error {
+	if a, found := g.aggregators[alias]; found {
+		return a.add(projection)
+	}
+
+	if _, found := g.values[alias]; !found {
+		g.values[alias] = projection
+	}
+
+	return nil
+}
+
+// result returns the combined projections of g, or nil if the result of a
+// SELECT VALUE query is undefined
+func (g *group) result() (json.RawMessage, error) {
+	if g.hasSelectValue {
+		return g.projection("")
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for _, alias := range g.aliases {
+		projection, err := g.projection(alias)
+		if err != nil {
+			return nil, err
+		}
+		if projection == nil {
+			continue
+		}
+
+		name, err := json.Marshal(alias)
+		if err != nil {
+			return nil, err
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(projection)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+func (g *group) projection(alias string) (json.RawMessage, error) {
+	if a, found := g.aggregators[alias]; found {
+		v, ok := a.result()
+		if !ok {
+			return nil, nil
+		}
+		return json.Marshal(v)
+	}
+
+	return g.values[alias], nil
+}
+
+// groupAggregator combines the results of an aggregate or GROUP BY query from
+// every partition key range.  Documents returned by the rewritten query have
+// the form {"groupByItems": [...], "payload": ...} for GROUP BY queries,
+// [payload] for SELECT VALUE aggregates and {"payload": ...} otherwise.
+type groupAggregator struct {
+	info    *queryInfo
+	groupBy bool
+	keys    []string
+	groups  map[string]*group
+}
+
+func newGroupAggregator(info *queryInfo) (*groupAggregator, error) {
+	a := &groupAggregator{
+		info:    info,
+		groupBy: len(info.GroupByExpressions) > 0,
+		groups:  map[string]*group{},
+	}
+
+	// validate the plan up front
+	_, err := newGroup(info)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// add folds a document returned by the rewritten query into a
+func (a *groupAggregator) add(doc json.RawMessage) error {
+	var key string
+	var payload json.RawMessage
+
+	switch {
+	case a.groupBy:
+		var item struct {
+			GroupByItems json.RawMessage `json:"groupByItems"`
+			Payload      json.RawMessage `json:"payload"`
+		}
+		err := json.Unmarshal(doc, &item)
+		if err != nil {
+			return err
+		}
+		// canonicalise the grouping values, which may be encoded differently
+		// by each partition key range
+		var items interface{}
+		err = json.Unmarshal(item.GroupByItems, &items)
+		if err != nil {
+			return err
+		}
+		b, err := json.Marshal(items)
+		if err != nil {
+			return err
+		}
+		key, payload = string(b), item.Payload
+
+	case a.info.HasSelectValue:
+		var items []json.RawMessage
+		err := json.Unmarshal(doc, &items)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		payload = items[0]
+
+	default:
+		var item struct {
+			Payload json.RawMessage `json:"payload"`
+		}
+		err := json.Unmarshal(doc, &item)
+		if err != nil {
+			return err
+		}
+		payload = item.Payload
+	}
+
+	if payload == nil {
+		return nil
+	}
+
+	g, err := a.group(key)
+	if err != nil {
+		return err
+	}
+
+	return g.add(payload)
+}
+
+func (a *groupAggregator) group(key string) (*group, error) {
+	if g, found := a.groups[key]; found {
+		return g, nil
+	}
+
+	g, err := newGroup(a.info)
+	if err != nil {
+		return nil, err
+	}
+
+	a.keys = append(a.keys, key)
+	a.groups[key] = g
+
+	return g, nil
+}
+
+// results returns the combined result of each group in the order in which
+// the groups were first seen.  An aggregate query without GROUP BY always
+// has a single group.
+func (a *groupAggregator) results() ([]json.RawMessage, error) {
+	if !a.groupBy {
+		_, err := a.group("")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]json.RawMessage, 0, len(a.keys))
+	for _, key := range a.keys {
+		result, err := a.groups[key].result()
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+// compareValues compares two decoded JSON values in Cosmos DB order:
+// undefined, null, booleans, numbers, strings, then arrays and objects
+func compareValues(a, b interface{}) int {
+	ra, rb := valueRank(a), valueRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+
+	switch a := a.(type) {
+	case bool:
+		switch {
+		case a == b.(bool):
+			return 0
+		case !a:
+			return -1
+		}
+		return 1
+	case float64:
+		switch {
+		case a < b.(float64):
+			return -1
+		case a > b.(float64):
+			return 1
+		}
+	case string:
+		switch {
+		case a < b.(string):
+			return -1
+		case a > b.(string):
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func valueRank(v interface{}) int {
+	switch v.(type) {
+	case undefined:
+		return -1
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	}
+	return 4
+}
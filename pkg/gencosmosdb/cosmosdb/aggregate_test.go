@@ -0,0 +1,128 @@
+package cosmosdb
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestGroupAggregator(t *testing.T) {
+	kind := func(s string) *string { return &s }
+
+	for _, tt := range []struct {
+		name string
+		info *queryInfo
+		docs []string
+		want []string
+	}{
+		{
+			name: "select value count",
+			info: &queryInfo{HasSelectValue: true, Aggregates: []string{"Count"}},
+			docs: []string{`[{"item":3}]`, `[{"item":4}]`},
+			want: []string{`7`},
+		},
+		{
+			name: "select value count, no documents",
+			info: &queryInfo{HasSelectValue: true, Aggregates: []string{"Count"}},
+			want: []string{`0`},
+		},
+		{
+			name: "select value sum, undefined",
+			info: &queryInfo{HasSelectValue: true, Aggregates: []string{"Sum"}},
+			docs: []string{`[{}]`, `[{}]`},
+			want: []string{},
+		},
+		{
+			name: "select value average",
+			info: &queryInfo{HasSelectValue: true, Aggregates: []string{"Average"}},
+			docs: []string{`[{"item":{"sum":10,"count":2}}]`, `[{"item":{"sum":null,"count":0}}]`, `[{"item":{"sum":20,"count":3}}]`},
+			want: []string{`6`},
+		},
+		{
+			name: "select value min",
+			info: &queryInfo{HasSelectValue: true, Aggregates: []string{"Min"}},
+			docs: []string{`[{"item":5}]`, `[{"item":"a"}]`, `[{"item":{"min":2,"count":1}}]`, `[{"item":{"count":0}}]`},
+			want: []string{`2`},
+		},
+		{
+			name: "select value max, mixed types",
+			info: &queryInfo{HasSelectValue: true, Aggregates: []string{"Max"}},
+			docs: []string{`[{"item":5}]`, `[{"item":"a"}]`, `[{"item":true}]`},
+			want: []string{`"a"`},
+		},
+		{
+			name: "aliases",
+			info: &queryInfo{GroupByAliasToAggregateType: map[string]*string{"n": kind("Count"), "total": kind("Sum")}},
+			docs: []string{`{"payload":{"n":{"item":1},"total":{"item":2.5}}}`, `{"payload":{"n":{"item":2},"total":{"item":1}}}`},
+			want: []string{`{"n":3,"total":3.5}`},
+		},
+		{
+			name: "group by",
+			info: &queryInfo{
+				GroupByExpressions:          []string{"c.city"},
+				GroupByAliases:              []string{"city", "n"},
+				GroupByAliasToAggregateType: map[string]*string{"city": nil, "n": kind("Count")},
+			},
+			docs: []string{
+				`{"groupByItems":[{"item":"paris"}],"payload":{"city":"paris","n":{"item":1}}}`,
+				`{"groupByItems":[{"item":"rome"}],"payload":{"city":"rome","n":{"item":2}}}`,
+				`{"groupByItems":[ { "item" : "paris" } ],"payload":{"city":"paris","n":{"item":3}}}`,
+			},
+			want: []string{`{"city":"paris","n":4}`, `{"city":"rome","n":2}`},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := newGroupAggregator(tt.info)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, doc := range tt.docs {
+				err = a.add(json.RawMessage(doc))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			results, err := a.results()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, len(results))
+			for i, result := range results {
+				got[i] = string(result)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGroupAggregatorUnsupported(t *testing.T) {
+	for _, info := range []*queryInfo{
+		{HasSelectValue: true, Aggregates: []string{"Count", "Sum"}},
+		{HasSelectValue: true, Aggregates: []string{"Median"}},
+	} {
+		_, err := newGroupAggregator(info)
+		if err == nil {
+			t.Errorf("%#v: expected error", info)
+		}
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	// values in ascending Cosmos DB order
+	values := []interface{}{undefined{}, nil, false, true, -1.5, 0.0, 2.0, "", "a", "b", []interface{}{}}
+
+	for i, a := range values {
+		for j, b := range values {
+			c := compareValues(a, b)
+			switch {
+			case i < j && c >= 0, i > j && c <= 0, i == j && c != 0:
+				t.Errorf("compareValues(%#v, %#v) = %d", a, b, c)
+			}
+		}
+	}
+}
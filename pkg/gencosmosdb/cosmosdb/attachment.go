@@ -0,0 +1,191 @@
+package cosmosdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Attachment represents an attachment.  Media is the link to the attachment's
+// media: for managed media, stored by Cosmos DB, it is /media/{id}; otherwise
+// it is an arbitrary URL, for example of a blob.
+type Attachment struct {
+	ID          string `json:"id,omitempty"`
+	ResourceID  string `json:"_rid,omitempty"`
+	Timestamp   int    `json:"_ts,omitempty"`
+	Self        string `json:"_self,omitempty"`
+	ETag        string `json:"_etag,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Media       string `json:"media,omitempty"`
+}
+
+// Attachments represents attachments
+type Attachments struct {
+	Count       int           `json:"_count,omitempty"`
+	ResourceID  string        `json:"_rid,omitempty"`
+	Attachments []*Attachment `json:"Attachments,omitempty"`
+}
+
+// ErrUnmanagedMedia is returned when reading the media of an attachment which
+// is not stored by Cosmos DB
+var ErrUnmanagedMedia = fmt.Errorf("attachment media is not managed")
+
+type attachmentClient struct {
+	*databaseClient
+	path         string
+	partitionkey string
+}
+
+// AttachmentClient is an attachment client.  Create and Replace store
+// attachments which link to unmanaged media; CreateMedia uploads managed
+// media, which GetMedia downloads.
+type AttachmentClient interface {
+	Create(context.Context, *Attachment) (*Attachment, error)
+	CreateMedia(context.Context, string, string, []byte) (*Attachment, error)
+	List() AttachmentIterator
+	ListAll(context.Context) (*Attachments, error)
+	Get(context.Context, string) (*Attachment, error)
+	GetMedia(context.Context, *Attachment) (io.ReadCloser, error)
+	Delete(context.Context, *Attachment) error
+	Replace(context.Context, *Attachment) (*Attachment, error)
+}
+
+type attachmentListIterator struct {
+	*attachmentClient
+	continuation string
+	done         bool
+}
+
+// AttachmentIterator is an attachment iterator
+type AttachmentIterator interface {
+	Next(context.Context) (*Attachments, error)
+	Items(context.Context) func(func(*Attachment, error) bool)
+}
+
+// NewAttachmentClient returns a new attachment client for the attachments of
+// the document docid, with partition key partitionkey, in the collection
+// collid
+func NewAttachmentClient(collc CollectionClient, collid, docid, partitionkey string) AttachmentClient {
+	return &attachmentClient{
+		databaseClient: collc.(*collectionClient).databaseClient,
+		path:           collc.(*collectionClient).path + "/colls/" + collid + "/docs/" + docid,
+		partitionkey:   partitionkey,
+	}
+}
+
+// headers returns new request headers which identify the partition of the
+// document
+func (c *attachmentClient) headers() http.Header {
+	headers := http.Header{}
+	if c.partitionkey != "" {
+		headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(c.partitionkey))
+	}
+	return headers
+}
+
+func (c *attachmentClient) all(ctx context.Context, i AttachmentIterator) (*Attachments, error) {
+	allattachments := &Attachments{}
+
+	for {
+		attachments, err := i.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if attachments == nil {
+			break
+		}
+
+		allattachments.Count += attachments.Count
+		allattachments.ResourceID = attachments.ResourceID
+		allattachments.Attachments = append(allattachments.Attachments, attachments.Attachments...)
+	}
+
+	return allattachments, nil
+}
+
+func (c *attachmentClient) Create(ctx context.Context, newattachment *Attachment) (attachment *Attachment, err error) {
+	err = c.do(ctx, http.MethodPost, c.path+"/attachments", "attachments", c.path, http.StatusCreated, &newattachment, &attachment, c.headers(), nil)
+	return
+}
+
+// CreateMedia uploads media of type contentType as the managed media of a new
+// attachment attachmentid
+func (c *attachmentClient) CreateMedia(ctx context.Context, attachmentid, contentType string, media []byte) (attachment *Attachment, err error) {
+	headers := c.headers()
+	headers.Set("Content-Type", contentType)
+	headers.Set("Slug", attachmentid)
+
+	err = c.do(ctx, http.MethodPost, c.path+"/attachments", "attachments", c.path, http.StatusCreated, rawBody(media), &attachment, headers, nil)
+	return
+}
+
+func (c *attachmentClient) List() AttachmentIterator {
+	return &attachmentListIterator{attachmentClient: c}
+}
+
+func (c *attachmentClient) ListAll(ctx context.Context) (*Attachments, error) {
+	return c.all(ctx, c.List())
+}
+
+func (c *attachmentClient) Get(ctx context.Context, attachmentid string) (attachment *Attachment, err error) {
+	err = c.do(ctx, http.MethodGet, c.path+"/attachments/"+attachmentid, "attachments", c.path+"/attachments/"+attachmentid, http.StatusOK, nil, &attachment, c.headers(), nil)
+	return
+}
+
+// GetMedia returns the managed media of attachment, which the caller must
+// close
+func (c *attachmentClient) GetMedia(ctx context.Context, attachment *Attachment) (media io.ReadCloser, err error) {
+	mediaid := strings.TrimPrefix(attachment.Media, "/media/")
+	if mediaid == attachment.Media {
+		return nil, ErrUnmanagedMedia
+	}
+
+	err = c.do(ctx, http.MethodGet, "media/"+mediaid, "media", strings.ToLower(mediaid), http.StatusOK, nil, &media, nil, nil)
+	return
+}
+
+func (c *attachmentClient) Delete(ctx context.Context, attachment *Attachment) error {
+	if attachment.ETag == "" {
+		return ErrETagRequired
+	}
+	headers := c.headers()
+	headers.Set("If-Match", attachment.ETag)
+	return c.do(ctx, http.MethodDelete, c.path+"/attachments/"+attachment.ID, "attachments", c.path+"/attachments/"+attachment.ID, http.StatusNoContent, nil, nil, headers, nil)
+}
+
+func (c *attachmentClient) Replace(ctx context.Context, newattachment *Attachment) (attachment *Attachment, err error) {
+	headers := c.headers()
+	if newattachment.ETag != "" {
+		headers.Set("If-Match", newattachment.ETag)
+	}
+
+	err = c.do(ctx, http.MethodPut, c.path+"/attachments/"+newattachment.ID, "attachments", c.path+"/attachments/"+newattachment.ID, http.StatusOK, &newattachment, &attachment, headers, nil)
+	return
+}
+
+func (i *attachmentListIterator) Next(ctx context.Context) (attachments *Attachments, err error) {
+	if i.done {
+		return
+	}
+
+	headers := i.headers()
+	if i.continuation != "" {
+		headers.Set("X-Ms-Continuation", i.continuation)
+	}
+
+	err = i.do(ctx, http.MethodGet, i.path+"/attachments", "attachments", i.path, http.StatusOK, nil, &attachments, headers, nil)
+	if err != nil {
+		return
+	}
+
+	i.continuation = headers.Get("X-Ms-Continuation")
+	i.done = i.continuation == ""
+
+	return
+}
+
+func (i *attachmentListIterator) Items(ctx context.Context) func(func(*Attachment, error) bool) {
+	return items(ctx, i.Next, func(attachments *Attachments) []*Attachment { return attachments.Attachments })
+}
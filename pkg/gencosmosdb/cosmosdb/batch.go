@@ -0,0 +1,109 @@
+package cosmosdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// batchAPIVersion is the API version which introduced transactional batches
+const batchAPIVersion = "2020-07-15"
+
+// MaxBatchOperations is the maximum number of operations in a transactional
+// batch
+const MaxBatchOperations = 100
+
+// ErrBatchTooLarge is returned if a transactional batch has more than
+// MaxBatchOperations operations
+var ErrBatchTooLarge = fmt.Errorf("batch has more than %d operations", MaxBatchOperations)
+
+// BatchOperationType represents a batch operation type
+type BatchOperationType string
+
+// BatchOperationType constants
+const (
+	BatchOperationTypeCreate  BatchOperationType = "Create"
+	BatchOperationTypeUpsert  BatchOperationType = "Upsert"
+	BatchOperationTypeRead    BatchOperationType = "Read"
+	BatchOperationTypeReplace BatchOperationType = "Replace"
+	BatchOperationTypeDelete  BatchOperationType = "Delete"
+	BatchOperationTypePatch   BatchOperationType = "Patch"
+)
+
+// batchOperation is an operation of a transactional batch
+type batchOperation struct {
+	OperationType BatchOperationType `json:"operationType"`
+	ID            string             `json:"id,omitempty"`
+	ResourceBody  interface{}        `json:"resourceBody,omitempty"`
+	IfMatch       string             `json:"ifMatch,omitempty"`
+
+	// PartitionKey is set on the operations of a bulk execution, which are
+	// sent to a partition key range rather than to a single partition key
+	PartitionKey string `json:"partitionKey,omitempty"`
+}
+
+// batchResult is the result of an operation of a transactional batch
+type batchResult struct {
+	StatusCode             int             `json:"statusCode"`
+	SubStatusCode          int             `json:"subStatusCode"`
+	RequestCharge          float64         `json:"requestCharge"`
+	ETag                   string          `json:"eTag"`
+	ResourceBody           json.RawMessage `json:"resourceBody"`
+	RetryAfterMilliseconds int             `json:"retryAfterMilliseconds"`
+}
+
+// batchResponse is the response of a transactional batch, which lists the
+// result of each operation whether or not the batch succeeded
+type batchResponse struct {
+	statusCode int
+	results    []batchResult
+}
+
+func (r *batchResponse) decodeMultiStatus(statusCode int, body []byte) error {
+	r.statusCode = statusCode
+	return json.Unmarshal(body, &r.results)
+}
+
+// setBatchHeaders sets the headers of a batch request, other than those
+// which address its partition key or partition key range.  The operations of
+// a batch which is not atomic succeed or fail independently.
+func setBatchHeaders(headers http.Header, atomic bool) {
+	headers.Set("X-Ms-Version", batchAPIVersion)
+	headers.Set("X-Ms-Cosmos-Is-Batch-Request", "True")
+	if atomic {
+		headers.Set("X-Ms-Cosmos-Batch-Atomic", "True")
+	} else {
+		headers.Set("X-Ms-Cosmos-Batch-Atomic", "False")
+		headers.Set("X-Ms-Cosmos-Batch-Continue-On-Error", "True")
+	}
+}
+
+// prepareBatch validates the operations of a transactional batch against
+// options as Replace and Delete would, returning them ready to send
+func prepareBatch(operations []batchOperation, options *Options) ([]batchOperation, error) {
+	if len(operations) > MaxBatchOperations {
+		return nil, ErrBatchTooLarge
+	}
+
+	return prepareOperations(operations, options)
+}
+
+// prepareOperations validates batch operations against options as Replace and
+// Delete would, returning them ready to send
+func prepareOperations(operations []batchOperation, options *Options) ([]batchOperation, error) {
+	prepared := make([]batchOperation, len(operations))
+	copy(prepared, operations)
+
+	for i, op := range prepared {
+		if options == nil || options.NoETag {
+			prepared[i].IfMatch = ""
+			continue
+		}
+
+		if (op.OperationType == BatchOperationTypeReplace || op.OperationType == BatchOperationTypeDelete) && op.IfMatch == "" {
+			return nil, ErrETagRequired
+		}
+	}
+
+	return prepared, nil
+}
@@ -0,0 +1,213 @@
+package cosmosdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// bulkBatch is a batch of the operations of a bulk execution which belong to
+// the same partition key range, identified by their index in the bulk
+// execution.  attempt is the number of attempts already made to send them.
+type bulkBatch struct {
+	pkr     PartitionKeyRange
+	indexes []int
+	attempt int
+}
+
+// groupBulk groups indexes, those of operations with the corresponding
+// effective partition keys epks, by the range of ranges to which they belong,
+// in batches of up to MaxBatchOperations
+func groupBulk(ranges []PartitionKeyRange, epks []string, indexes []int, attempt int) ([]*bulkBatch, error) {
+	var batches []*bulkBatch
+	open := map[string]*bulkBatch{}
+
+	for _, index := range indexes {
+		pkr, err := searchPartitionKeyRanges(ranges, epks[index])
+		if err != nil {
+			return nil, err
+		}
+
+		b := open[pkr.ID]
+		if b == nil || len(b.indexes) == MaxBatchOperations {
+			b = &bulkBatch{pkr: pkr, attempt: attempt}
+			open[pkr.ID] = b
+			batches = append(batches, b)
+		}
+		b.indexes = append(b.indexes, index)
+	}
+
+	return batches, nil
+}
+
+// executeBulk executes operations, each with the corresponding partition key,
+// as non-atomic batches against the collection at path, one for each
+// partition key range to which the operations belong, returning the result of
+// each operation.  Operations which are throttled are retried, independently
+// for each partition key range, and those sent to a range which has been split
+// are regrouped by the ranges which replaced it.
+func (c *databaseClient) executeBulk(ctx context.Context, path string, partitionkeys []string, operations []batchOperation, options *Options) ([]batchResult, error) {
+	operations, err := prepareOperations(operations, options)
+	if err != nil {
+		return nil, err
+	}
+
+	def, err := c.partitionKeyDefinition(ctx, path, options)
+	if err != nil {
+		return nil, err
+	}
+
+	epks := make([]string, len(operations))
+	indexes := make([]int, len(operations))
+	for i, partitionkey := range partitionkeys {
+		epks[i], err = EffectivePartitionKey(def, partitionkey)
+		if err != nil {
+			return nil, err
+		}
+		operations[i].PartitionKey = encodePartitionKey(partitionkey)
+		indexes[i] = i
+	}
+
+	ranges, err := c.partitionKeyRanges(ctx, path, options)
+	if err != nil {
+		return nil, err
+	}
+
+	batches, err := groupBulk(ranges, epks, indexes, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]batchResult, len(operations))
+
+	var mu sync.Mutex
+	parallel(options.maxDegreeOfParallelism(), len(batches), func(i int) {
+		e := c.executeBulkBatch(ctx, path, batches[i], epks, operations, results, options)
+		if e != nil {
+			mu.Lock()
+			if err == nil {
+				err = e
+			}
+			mu.Unlock()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// executeBulkBatch executes the operations of b, storing their results
+func (c *databaseClient) executeBulkBatch(ctx context.Context, path string, b *bulkBatch, epks []string, operations []batchOperation, results []batchResult, options *Options) error {
+	retryPolicy := c.getRetryPolicy()
+	indexes := b.indexes
+
+	for retry := b.attempt; len(indexes) > 0; retry++ {
+		batch := make([]batchOperation, len(indexes))
+		for i, index := range indexes {
+			batch[i] = operations[index]
+		}
+
+		headers := http.Header{}
+		headers.Set("X-Ms-Documentdb-Partitionkeyrangeid", b.pkr.ID)
+		setBatchHeaders(headers, false)
+		if options != nil && options.IndexingDirective != "" {
+			headers.Set("X-Ms-Indexing-Directive", string(options.IndexingDirective))
+		}
+
+		resp := &batchResponse{}
+		err := c.do(ctx, http.MethodPost, path+"/docs", "docs", path, http.StatusOK, batch, resp, headers, options)
+		if isPartitionKeyRangeGone(err) && retry+1 < retryPolicy.MaxAttempts() {
+			return c.splitBulkBatch(ctx, path, b.pkr, indexes, retry+1, epks, operations, results, options, err)
+		}
+		if err, ok := err.(*Error); ok {
+			for _, index := range indexes {
+				results[index] = batchResult{StatusCode: err.StatusCode, SubStatusCode: err.SubStatusCode}
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(resp.results) != len(indexes) {
+			return fmt.Errorf("batch returned %d results for %d operations", len(resp.results), len(indexes))
+		}
+
+		var throttled []int
+		var delay time.Duration
+		for i, result := range resp.results {
+			results[indexes[i]] = result
+
+			if result.StatusCode == http.StatusTooManyRequests && retry+1 < retryPolicy.MaxAttempts() {
+				throttled = append(throttled, indexes[i])
+				if d := time.Duration(result.RetryAfterMilliseconds) * time.Millisecond; d > delay {
+					delay = d
+				}
+			}
+		}
+
+		if len(throttled) > 0 {
+			// the delay of the retry policy applies even if the server does
+			// not request one
+			if d := retryPolicy.Delay(&RetryAttempt{
+				Attempt: retry,
+				Method:  http.MethodPost,
+				Path:    path + "/docs",
+				Err:     &Error{StatusCode: http.StatusTooManyRequests},
+			}); d > delay {
+				delay = d
+			}
+
+			c.getLogger().Warn("bulk operations throttled", "path", path, "partitionKeyRange", b.pkr.ID, "attempt", retry, "count", len(throttled))
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		indexes = throttled
+	}
+
+	return nil
+}
+
+// splitBulkBatch executes indexes, operations which were sent to the partition
+// key range parent and found it to be gone with err, against the ranges which
+// have replaced it.  If the ranges have not yet been replaced, as while a split
+// completes, the operations are resent to parent after the delay of the retry
+// policy.
+func (c *databaseClient) splitBulkBatch(ctx context.Context, path string, parent PartitionKeyRange, indexes []int, attempt int, epks []string, operations []batchOperation, results []batchResult, options *Options, err error) error {
+	ranges, err2 := c.refreshPartitionKeyRanges(ctx, path, options, parent)
+	if err2 != nil {
+		return err2
+	}
+
+	batches, err2 := groupBulk(ranges, epks, indexes, attempt)
+	if err2 != nil {
+		return err2
+	}
+
+	if len(batches) == 1 && batches[0].pkr.ID == parent.ID {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.getRetryPolicy().Delay(&RetryAttempt{Attempt: attempt - 1, Method: http.MethodPost, Path: path + "/docs", Err: err})):
+		}
+	} else {
+		c.getLogger().Warn("partition key range is gone: regrouping bulk operations", "path", path, "partitionKeyRange", parent.ID, "batches", len(batches))
+	}
+
+	for _, b := range batches {
+		err := c.executeBulkBatch(ctx, path, b, epks, operations, results, options)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,334 @@
+package cosmosdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// allVersionsAndDeletesAPIVersion is the API version which introduced the
+// all versions and deletes change feed
+const allVersionsAndDeletesAPIVersion = "2020-07-15"
+
+// allVersionsAndDeletesWireFormatVersion is the wire format of the items of
+// the all versions and deletes change feed
+const allVersionsAndDeletesWireFormatVersion = "2021-09-15"
+
+// ChangeFeedOperationType represents the operation which made a change
+type ChangeFeedOperationType string
+
+// ChangeFeedOperationType constants
+const (
+	ChangeFeedOperationTypeCreate  ChangeFeedOperationType = "create"
+	ChangeFeedOperationTypeReplace ChangeFeedOperationType = "replace"
+	ChangeFeedOperationTypeDelete  ChangeFeedOperationType = "delete"
+)
+
+// ChangeFeedMetadata represents the metadata of a change in an all versions
+// and deletes change feed
+type ChangeFeedMetadata struct {
+	ID                          string                  `json:"id,omitempty"`
+	OperationType               ChangeFeedOperationType `json:"operationType,omitempty"`
+	LSN                         int64                   `json:"lsn,omitempty"`
+	PreviousLSN                 int64                   `json:"previousImageLSN,omitempty"`
+	ConflictResolutionTimestamp int64                   `json:"crts,omitempty"`
+	TimeToLiveExpired           bool                    `json:"timeToLiveExpired,omitempty"`
+}
+
+// ChangeFeedStartFrom represents the position from which a change feed is
+// read in partition key ranges for which it has no continuation.  If neither
+// field is set, the change feed is read from the beginning.  It is ignored by
+// the all versions and deletes change feed, which is always read from now.
+type ChangeFeedStartFrom struct {
+	// Now reads only the changes made from now on
+	Now bool
+
+	// Time, if non-zero, reads the changes made since Time
+	Time time.Time
+}
+
+// setHeaders sets the headers of a change feed request without a
+// continuation which start it from s
+func (s *ChangeFeedStartFrom) setHeaders(headers http.Header) {
+	switch {
+	case s == nil:
+	case s.Now:
+		headers.Set("If-None-Match", "*")
+	case !s.Time.IsZero():
+		headers.Set("If-Modified-Since", s.Time.UTC().Format(http.TimeFormat))
+	}
+}
+
+// changeFeedContinuation is the position of a changeFeed in a partition key
+// range.  Ranges are identified by their bounds so that the position survives
+// changes to the set of partition key ranges.
+type changeFeedContinuation struct {
+	MinInclusive string `json:"min"`
+	MaxExclusive string `json:"max"`
+	Continuation string `json:"token,omitempty"`
+}
+
+// changeFeedRange is a partition key range of a changeFeed and the ETag of
+// the last change read from it
+type changeFeedRange struct {
+	pkr          PartitionKeyRange
+	continuation string
+}
+
+// changeFeed reads the incremental change feed of each partition key range
+// of a collection in turn, presenting the changes as a single stream of
+// pages.  Each range is read from the ETag of the last change read from it,
+// and the ranges are visited round robin so that a busy range does not starve
+// the others.  A page is nil once every range has been found to have no
+// further changes, but later calls return any changes made since.
+//
+// If a partition key range is split, the ranges are refreshed and the feed
+// continues on the child ranges, each of which inherits the ETag of its
+// parent.
+//
+// If Options.PartitionKey is set, the feed is restricted to the documents
+// with that partition key.
+//
+// If allVersions is set, the feed is the all versions and deletes change
+// feed, which returns every change, including deletes, rather than the latest
+// version of each changed document.  It can only be read from the time at
+// which it is first read, so ranges without a continuation are read from now.
+type changeFeed struct {
+	*databaseClient
+	path        string
+	options     *Options
+	allVersions bool
+	ranges      []changeFeedRange
+	loaded      bool
+	index       int
+	resume      []changeFeedContinuation
+	legacy      string
+	pending     []json.RawMessage
+}
+
+// newChangeFeed returns a new changeFeed of the collection at path, resuming
+// from continuation if it is set.  A continuation which is a bare ETag, as
+// returned by earlier versions of this package, applies to every range; this
+// is only correct for a collection with a single partition key range.
+func (c *databaseClient) newChangeFeed(path string, options *Options, continuation string) *changeFeed {
+	f := &changeFeed{
+		databaseClient: c,
+		path:           path,
+		options:        options,
+	}
+
+	if strings.HasPrefix(continuation, "[") {
+		if json.Unmarshal([]byte(continuation), &f.resume) == nil {
+			return f
+		}
+	}
+	f.legacy = continuation
+
+	return f
+}
+
+func (f *changeFeed) load(ctx context.Context) error {
+	var ranges []PartitionKeyRange
+	if f.options != nil && f.options.PartitionKey != "" {
+		// the gateway routes a request with a partition key to the range
+		// which contains it, so the feed is read as a single range
+		ranges = []PartitionKeyRange{{MinInclusive: "", MaxExclusive: maxEffectivePartitionKey}}
+	} else {
+		var err error
+		ranges, err = f.partitionKeyRanges(ctx, f.path, f.options)
+		if err != nil {
+			return err
+		}
+		ranges = f.options.feedRange().scope(ranges)
+	}
+
+	f.ranges = make([]changeFeedRange, len(ranges))
+	for i, r := range ranges {
+		f.ranges[i] = changeFeedRange{pkr: r, continuation: f.resumeContinuation(r)}
+	}
+
+	f.resume = nil
+	f.loaded = true
+
+	return nil
+}
+
+// resumeContinuation returns the continuation from which to resume reading r,
+// which is that of the range which contained its lower bound
+func (f *changeFeed) resumeContinuation(r PartitionKeyRange) string {
+	if f.resume == nil {
+		return f.legacy
+	}
+
+	i := sort.Search(len(f.resume), func(i int) bool { return f.resume[i].MaxExclusive > r.MinInclusive })
+	if i < len(f.resume) && f.resume[i].MinInclusive <= r.MinInclusive {
+		return f.resume[i].Continuation
+	}
+
+	return ""
+}
+
+// split replaces the current partition key range, which has been found to be
+// gone, with its child ranges
+func (f *changeFeed) split(ctx context.Context, err error) error {
+	parent := f.ranges[f.index]
+
+	children, err := f.childPartitionKeyRanges(ctx, f.path, f.options, parent.pkr, err)
+	if err != nil {
+		return err
+	}
+	children = f.options.feedRange().scope(children)
+
+	f.getLogger().Warn("partition key range is gone: continuing change feed on its children", "path", f.path, "partitionKeyRange", parent.pkr.ID, "children", len(children))
+
+	ranges := make([]changeFeedRange, 0, len(f.ranges)+len(children)-1)
+	ranges = append(ranges, f.ranges[:f.index]...)
+	for _, child := range children {
+		ranges = append(ranges, changeFeedRange{pkr: child, continuation: parent.continuation})
+	}
+	ranges = append(ranges, f.ranges[f.index+1:]...)
+	f.ranges = ranges
+
+	return nil
+}
+
+// fetchRange fetches the next page of changes from r, or nil if there are no
+// further changes
+func (f *changeFeed) fetchRange(ctx context.Context, r *changeFeedRange, maxItemCount int) (*queryPage, error) {
+	headers := http.Header{}
+	headers.Set("X-Ms-Max-Item-Count", strconv.Itoa(maxItemCount))
+	if r.pkr.ID != "" {
+		headers.Set("X-Ms-Documentdb-PartitionKeyRangeID", r.pkr.ID)
+	}
+	if f.allVersions {
+		headers.Set("A-IM", "Full-Fidelity Feed")
+		headers.Set("X-Ms-Version", allVersionsAndDeletesAPIVersion)
+		headers.Set("X-Ms-Cosmos-Changefeed-Wire-Format-Version", allVersionsAndDeletesWireFormatVersion)
+		headers.Set("If-None-Match", "*")
+	} else {
+		headers.Set("A-IM", "Incremental feed")
+	}
+	if r.continuation != "" {
+		headers.Set("If-None-Match", r.continuation)
+	} else if f.options != nil && !f.allVersions {
+		f.options.ChangeFeedStartFrom.setHeaders(headers)
+	}
+	f.options.feedRange().setHeaders(headers, r.pkr)
+	f.options.setFeedHeaders(headers)
+
+	var page *queryPage
+	err := f.do(ctx, http.MethodGet, f.path+"/docs", "docs", f.path, http.StatusOK, nil, &page, headers, f.options)
+	if IsErrorStatusCode(err, http.StatusNotModified) {
+		// a feed read from now is positioned by the ETag of its first read
+		if etag := headers.Get("Etag"); etag != "" {
+			r.continuation = etag
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.continuation = headers.Get("Etag")
+
+	return page, nil
+}
+
+// nextPage returns the next non-empty page of changes, or nil if no
+// partition key range has further changes
+func (f *changeFeed) nextPage(ctx context.Context, maxItemCount int) (*queryPage, error) {
+	if !f.loaded {
+		err := f.load(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if f.options != nil && f.options.MaxItemCount != 0 {
+		maxItemCount = f.options.MaxItemCount
+	}
+
+	for visited := 0; visited < len(f.ranges); {
+		if f.index >= len(f.ranges) {
+			f.index = 0
+		}
+
+		page, err := f.fetchRange(ctx, &f.ranges[f.index], maxItemCount)
+		if isPartitionKeyRangeGone(err) {
+			err = f.split(ctx, err)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		f.index++
+
+		if page != nil && len(page.Documents) > 0 {
+			return page, nil
+		}
+
+		visited++
+	}
+
+	return nil, nil
+}
+
+// nextRaw decodes the next non-empty page of changes into raw.  raw is left
+// untouched if there are no further changes.
+func (f *changeFeed) nextRaw(ctx context.Context, maxItemCount int, raw interface{}) error {
+	page, err := f.nextPage(ctx, maxItemCount)
+	if err != nil || page == nil {
+		return err
+	}
+
+	return f.decodePage(page, raw)
+}
+
+// nextDocument decodes the next change into out, returning false if there
+// are no further changes
+func (f *changeFeed) nextDocument(ctx context.Context, out interface{}) (bool, error) {
+	for len(f.pending) == 0 {
+		page, err := f.nextPage(ctx, -1)
+		if err != nil || page == nil {
+			return false, err
+		}
+		f.pending = page.Documents
+	}
+
+	doc := f.pending[0]
+	f.pending = f.pending[1:]
+
+	return true, f.decode(doc, out)
+}
+
+// Continuation returns the continuation of the change feed, from which a new
+// changeFeed reads the changes which this one has not yet returned
+func (f *changeFeed) Continuation() string {
+	if !f.loaded {
+		if f.resume == nil {
+			return f.legacy
+		}
+		b, _ := json.Marshal(f.resume)
+		return string(b)
+	}
+
+	continuations := make([]changeFeedContinuation, len(f.ranges))
+	for i, r := range f.ranges {
+		continuations[i] = changeFeedContinuation{
+			MinInclusive: r.pkr.MinInclusive,
+			MaxExclusive: r.pkr.MaxExclusive,
+			Continuation: r.continuation,
+		}
+	}
+
+	b, _ := json.Marshal(continuations)
+	return string(b)
+}
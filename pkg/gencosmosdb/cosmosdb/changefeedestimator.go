@@ -0,0 +1,100 @@
+package cosmosdb
+
+import (
+	"context"
+	"strings"
+)
+
+// ChangeFeedEstimate represents the estimated number of changes which remain
+// to be processed in the partition key range of a lease
+type ChangeFeedEstimate struct {
+	LeaseID             string
+	PartitionKeyRangeID string
+	Owner               string
+	EstimatedLag        int64
+}
+
+// ChangeFeedEstimator estimates the number of changes which the change feed
+// processors sharing a set of leases have yet to process, for example to
+// decide how many processors to run
+type ChangeFeedEstimator interface {
+	Estimate(context.Context) ([]ChangeFeedEstimate, error)
+}
+
+// Estimate returns the estimated lag of each lease.  The lag of a partition
+// key range is the difference between its latest LSN and that of the first
+// change after the lease's checkpoint.
+func (p *changeFeedProcessor) Estimate(ctx context.Context) ([]ChangeFeedEstimate, error) {
+	all, err := p.store.ListLeases(ctx, p.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	estimates := make([]ChangeFeedEstimate, 0, len(all))
+	for _, lease := range all {
+		pkr := PartitionKeyRange{
+			ID:           lease.PartitionKeyRangeID,
+			MinInclusive: lease.MinInclusive,
+			MaxExclusive: lease.MaxExclusive,
+		}
+
+		lag, err := p.estimateRange(ctx, pkr, lease.Continuation)
+		if isPartitionKeyRangeGone(err) {
+			// the lease has yet to be split: estimate its child ranges
+			var children []PartitionKeyRange
+			children, err = p.childPartitionKeyRanges(ctx, p.path, nil, pkr, err)
+			for _, child := range children {
+				var childLag int64
+				childLag, err = p.estimateRange(ctx, child, lease.Continuation)
+				if err != nil {
+					break
+				}
+				lag += childLag
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		estimates = append(estimates, ChangeFeedEstimate{
+			LeaseID:             lease.ID,
+			PartitionKeyRangeID: lease.PartitionKeyRangeID,
+			Owner:               lease.Owner,
+			EstimatedLag:        lag,
+		})
+	}
+
+	return estimates, nil
+}
+
+// estimateRange returns the number of changes in pkr after continuation
+func (p *changeFeedProcessor) estimateRange(ctx context.Context, pkr PartitionKeyRange, continuation string) (int64, error) {
+	info := &ResponseInfo{}
+	feed := &changeFeed{
+		databaseClient: p.databaseClient,
+		path:           p.path,
+		options:        &Options{ChangeFeedStartFrom: p.options.StartFrom, ResponseInfo: info},
+	}
+
+	page, err := feed.fetchRange(ctx, &changeFeedRange{pkr: pkr, continuation: continuation}, 1)
+	if err != nil || page == nil || len(page.Documents) == 0 {
+		return 0, err
+	}
+
+	var first struct {
+		LSN int64 `json:"_lsn"`
+	}
+	err = p.decode(page.Documents[0], &first)
+	if err != nil {
+		return 0, err
+	}
+
+	_, token, _ := strings.Cut(info.SessionToken, ":")
+	_, lsn := parseSessionToken(token)
+
+	if lsn < first.LSN {
+		return 1, nil
+	}
+
+	return lsn - first.LSN + 1, nil
+}
@@ -0,0 +1,472 @@
+package cosmosdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Change feed processor defaults
+const (
+	DefaultLeaseRenewInterval      = 17 * time.Second
+	DefaultLeaseAcquireInterval    = 13 * time.Second
+	DefaultLeaseExpirationInterval = 60 * time.Second
+	DefaultFeedPollDelay           = 5 * time.Second
+)
+
+// leaseReleaseTimeout bounds the time spent releasing a lease when a change
+// feed processor stops
+const leaseReleaseTimeout = 10 * time.Second
+
+// Lease represents the lease of a partition key range of a collection by a
+// change feed processor host.  Leases are stored in a CheckpointStore, by
+// default as documents in a lease collection.
+type Lease struct {
+	ID                  string `json:"id,omitempty"`
+	ResourceID          string `json:"_rid,omitempty"`
+	Timestamp           int    `json:"_ts,omitempty"`
+	Self                string `json:"_self,omitempty"`
+	ETag                string `json:"_etag,omitempty"`
+	PartitionKeyRangeID string `json:"partitionKeyRangeId,omitempty"`
+	MinInclusive        string `json:"minInclusive"`
+	MaxExclusive        string `json:"maxExclusive,omitempty"`
+	Owner               string `json:"owner,omitempty"`
+	Continuation        string `json:"continuationToken,omitempty"`
+}
+
+// leases represents the documents of a lease collection
+type leases struct {
+	Count     int      `json:"_count,omitempty"`
+	Documents []*Lease `json:"Documents,omitempty"`
+}
+
+// ChangeFeedProcessorOptions represents the options of a change feed
+// processor.  Zero values are replaced by the defaults.
+type ChangeFeedProcessorOptions struct {
+	// HostName identifies this instance of the processor; by default it is
+	// derived from the hostname and process ID
+	HostName string
+
+	// LeasePrefix distinguishes the leases of processors of the same
+	// collection which share a lease collection
+	LeasePrefix string
+
+	// LeaseRenewInterval is the interval at which owned leases are renewed
+	LeaseRenewInterval time.Duration
+
+	// LeaseAcquireInterval is the interval at which leases are balanced
+	// between hosts
+	LeaseAcquireInterval time.Duration
+
+	// LeaseExpirationInterval is the time after which a lease which has not
+	// been renewed may be acquired by another host
+	LeaseExpirationInterval time.Duration
+
+	// FeedPollDelay is the delay before polling a partition key range which
+	// had no changes, or retrying a batch whose handler failed
+	FeedPollDelay time.Duration
+
+	// MaxItemCount, if non-zero, is the maximum number of changes passed to
+	// each call of the handler
+	MaxItemCount int
+
+	// StartFrom, if set, is the position from which the change feed is
+	// processed when the leases are first created, rather than the beginning
+	StartFrom *ChangeFeedStartFrom
+
+	// CheckpointStore, if set, stores the leases in place of the lease
+	// collection
+	CheckpointStore CheckpointStore
+}
+
+// ChangeFeedProcessor distributes the change feed of a collection between
+// the hosts which share a lease collection, calling a handler with each batch
+// of changes.  Run blocks until ctx is cancelled.
+//
+// A batch is checkpointed once its handler returns nil; if the handler
+// returns an error, the batch is retried after FeedPollDelay.  Changes are
+// therefore delivered at least once, in order within each partition key.
+type ChangeFeedProcessor interface {
+	Run(context.Context) error
+}
+
+type changeFeedProcessor struct {
+	*databaseClient
+	path    string
+	store   CheckpointStore
+	prefix  string
+	handler func(context.Context, *queryPage) error
+	options ChangeFeedProcessorOptions
+
+	mu      sync.Mutex
+	workers map[string]bool
+}
+
+// newChangeFeedProcessor returns a new changeFeedProcessor of the collection
+// at path, storing its leases in the collection leaseCollID of leasec unless
+// options specify a CheckpointStore
+func (c *databaseClient) newChangeFeedProcessor(path string, leasec CollectionClient, leaseCollID string, handler func(context.Context, *queryPage) error, options *ChangeFeedProcessorOptions) *changeFeedProcessor {
+	p := &changeFeedProcessor{
+		databaseClient: c,
+		path:           path,
+		handler:        handler,
+		workers:        map[string]bool{},
+	}
+
+	if options != nil {
+		p.options = *options
+	}
+	p.store = p.options.CheckpointStore
+	if p.store == nil {
+		p.store = NewCollectionCheckpointStore(leasec, leaseCollID)
+	}
+	if p.options.HostName == "" {
+		hostname, _ := os.Hostname()
+		p.options.HostName = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	if p.options.LeaseRenewInterval == 0 {
+		p.options.LeaseRenewInterval = DefaultLeaseRenewInterval
+	}
+	if p.options.LeaseAcquireInterval == 0 {
+		p.options.LeaseAcquireInterval = DefaultLeaseAcquireInterval
+	}
+	if p.options.LeaseExpirationInterval == 0 {
+		p.options.LeaseExpirationInterval = DefaultLeaseExpirationInterval
+	}
+	if p.options.FeedPollDelay == 0 {
+		p.options.FeedPollDelay = DefaultFeedPollDelay
+	}
+
+	p.prefix = p.options.LeasePrefix + strings.ReplaceAll(path, "/", "_") + ".."
+
+	return p
+}
+
+func (p *changeFeedProcessor) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	t := time.NewTicker(p.options.LeaseAcquireInterval)
+	defer t.Stop()
+
+	for {
+		err := p.balance(ctx, &wg)
+		if err != nil && ctx.Err() == nil {
+			p.getLogger().Warn("change feed processor failed to balance leases", "path", p.path, "host", p.options.HostName, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// balance creates the leases of the collection if there are none, then
+// acquires expired leases and, if this host owns fewer than its share,
+// steals a lease from the host which owns the most
+func (p *changeFeedProcessor) balance(ctx context.Context, wg *sync.WaitGroup) error {
+	all, err := p.store.ListLeases(ctx, p.prefix)
+	if err != nil {
+		return err
+	}
+
+	if len(all) == 0 {
+		err = p.createLeases(ctx)
+		if err != nil {
+			return err
+		}
+
+		all, err = p.store.ListLeases(ctx, p.prefix)
+		if err != nil {
+			return err
+		}
+	}
+
+	owned := map[string][]*Lease{p.options.HostName: nil}
+	var available []*Lease
+	for _, lease := range all {
+		if lease.Owner == p.options.HostName {
+			owned[lease.Owner] = append(owned[lease.Owner], lease)
+			if !p.working(lease.ID) {
+				p.start(ctx, wg, lease)
+			}
+			continue
+		}
+
+		if lease.Owner == "" || p.expired(lease) {
+			available = append(available, lease)
+		} else {
+			owned[lease.Owner] = append(owned[lease.Owner], lease)
+		}
+	}
+
+	target := (len(all) + len(owned) - 1) / len(owned)
+	count := len(owned[p.options.HostName])
+
+	for _, lease := range available {
+		if count >= target {
+			return nil
+		}
+
+		if p.acquire(ctx, wg, lease) {
+			count++
+		}
+	}
+
+	if count < target {
+		var busiest []*Lease
+		for owner, leases := range owned {
+			if owner != p.options.HostName && len(leases) > target && len(leases) > len(busiest) {
+				busiest = leases
+			}
+		}
+
+		if len(busiest) > 0 {
+			p.acquire(ctx, wg, busiest[0])
+		}
+	}
+
+	return nil
+}
+
+// expired returns true if lease has not been renewed within the lease
+// expiration interval
+func (p *changeFeedProcessor) expired(lease *Lease) bool {
+	return time.Since(time.Unix(int64(lease.Timestamp), 0)) > p.options.LeaseExpirationInterval
+}
+
+// acquire takes ownership of lease and starts its worker, returning false if
+// another host changed the lease first
+func (p *changeFeedProcessor) acquire(ctx context.Context, wg *sync.WaitGroup, lease *Lease) bool {
+	previous := lease.Owner
+	lease.Owner = p.options.HostName
+
+	lease, err := p.replaceLease(ctx, lease)
+	if err != nil {
+		if !IsErrorStatusCode(err, http.StatusPreconditionFailed) && !IsErrorStatusCode(err, http.StatusNotFound) {
+			p.getLogger().Warn("change feed processor failed to acquire lease", "path", p.path, "host", p.options.HostName, "lease", lease.ID, "error", err)
+		}
+		return false
+	}
+
+	if previous != "" {
+		p.getLogger().Info("change feed processor took lease", "path", p.path, "host", p.options.HostName, "lease", lease.ID, "previousOwner", previous)
+	}
+
+	p.start(ctx, wg, lease)
+
+	return true
+}
+
+func (p *changeFeedProcessor) working(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.workers[id]
+}
+
+// start starts a worker processing the partition key range of lease
+func (p *changeFeedProcessor) start(ctx context.Context, wg *sync.WaitGroup, lease *Lease) {
+	p.mu.Lock()
+	p.workers[lease.ID] = true
+	p.mu.Unlock()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			p.mu.Lock()
+			delete(p.workers, lease.ID)
+			p.mu.Unlock()
+		}()
+
+		p.work(ctx, lease)
+	}()
+}
+
+// work processes the change feed of the partition key range of lease until
+// ctx is cancelled, the lease is lost, or the range is split
+func (p *changeFeedProcessor) work(ctx context.Context, lease *Lease) {
+	feed := &changeFeed{
+		databaseClient: p.databaseClient,
+		path:           p.path,
+		options:        &Options{ChangeFeedStartFrom: p.options.StartFrom},
+	}
+	r := &changeFeedRange{
+		pkr: PartitionKeyRange{
+			ID:           lease.PartitionKeyRangeID,
+			MinInclusive: lease.MinInclusive,
+			MaxExclusive: lease.MaxExclusive,
+		},
+		continuation: lease.Continuation,
+	}
+
+	maxItemCount := -1
+	if p.options.MaxItemCount != 0 {
+		maxItemCount = p.options.MaxItemCount
+	}
+
+	renewed := time.Now()
+	var err error
+
+	for ctx.Err() == nil {
+		if time.Since(renewed) >= p.options.LeaseRenewInterval {
+			lease, err = p.replaceLease(ctx, lease)
+			if p.lost(lease, err) {
+				return
+			}
+			if err == nil {
+				renewed = time.Now()
+			}
+		}
+
+		// the continuation with which the page was fetched, from which it is
+		// fetched again if the handler fails
+		fetched := r.continuation
+
+		var page *queryPage
+		page, err = feed.fetchRange(ctx, r, maxItemCount)
+		if isPartitionKeyRangeGone(err) {
+			p.split(ctx, lease, err)
+			return
+		}
+
+		if err == nil && page != nil && len(page.Documents) > 0 {
+			err = p.handler(ctx, page)
+			if err != nil {
+				// retry the batch, which has not been checkpointed
+				r.continuation = fetched
+			} else {
+				checkpoint := *lease
+				checkpoint.Continuation = r.continuation
+
+				lease, err = p.replaceLease(ctx, &checkpoint)
+				if p.lost(lease, err) {
+					return
+				}
+				if err == nil {
+					renewed = time.Now()
+					continue
+				}
+			}
+		}
+
+		if err != nil && ctx.Err() == nil {
+			p.getLogger().Warn("change feed processor failed to process lease", "path", p.path, "host", p.options.HostName, "lease", lease.ID, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(p.options.FeedPollDelay):
+		}
+	}
+
+	p.release(lease)
+}
+
+// lost returns true if err indicates that lease has been taken by another
+// host or deleted
+func (p *changeFeedProcessor) lost(lease *Lease, err error) bool {
+	if IsErrorStatusCode(err, http.StatusPreconditionFailed) || IsErrorStatusCode(err, http.StatusNotFound) {
+		p.getLogger().Info("change feed processor lost lease", "path", p.path, "host", p.options.HostName, "lease", lease.ID)
+		return true
+	}
+
+	return false
+}
+
+// release gives up lease so that another host may acquire it without waiting
+// for it to expire
+func (p *changeFeedProcessor) release(lease *Lease) {
+	ctx, cancel := context.WithTimeout(context.Background(), leaseReleaseTimeout)
+	defer cancel()
+
+	released := *lease
+	released.Owner = ""
+
+	_, err := p.replaceLease(ctx, &released)
+	if err != nil {
+		p.getLogger().Warn("change feed processor failed to release lease", "path", p.path, "host", p.options.HostName, "lease", lease.ID, "error", err)
+	}
+}
+
+// split replaces lease, whose partition key range has been split, with
+// leases of the child ranges, each of which continues from the continuation
+// of lease
+func (p *changeFeedProcessor) split(ctx context.Context, lease *Lease, err error) {
+	parent := PartitionKeyRange{
+		ID:           lease.PartitionKeyRangeID,
+		MinInclusive: lease.MinInclusive,
+		MaxExclusive: lease.MaxExclusive,
+	}
+
+	children, err := p.childPartitionKeyRanges(ctx, p.path, nil, parent, err)
+	if err != nil {
+		p.getLogger().Warn("change feed processor failed to split lease", "path", p.path, "host", p.options.HostName, "lease", lease.ID, "error", err)
+		return
+	}
+
+	for _, child := range children {
+		err = p.createLease(ctx, child, lease.Continuation)
+		if err != nil {
+			p.getLogger().Warn("change feed processor failed to split lease", "path", p.path, "host", p.options.HostName, "lease", lease.ID, "error", err)
+			return
+		}
+	}
+
+	err = p.store.DeleteLease(ctx, lease)
+	if err != nil {
+		p.getLogger().Warn("change feed processor failed to split lease", "path", p.path, "host", p.options.HostName, "lease", lease.ID, "error", err)
+		return
+	}
+
+	p.getLogger().Info("change feed processor split lease", "path", p.path, "host", p.options.HostName, "lease", lease.ID, "children", len(children))
+}
+
+// createLeases creates a lease for each partition key range of the
+// collection.  Leases which another host has already created are left.
+func (p *changeFeedProcessor) createLeases(ctx context.Context) error {
+	ranges, err := p.partitionKeyRanges(ctx, p.path, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range ranges {
+		err = p.createLease(ctx, r, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createLease creates the lease of r, unless it already exists
+func (p *changeFeedProcessor) createLease(ctx context.Context, r PartitionKeyRange, continuation string) error {
+	lease := &Lease{
+		ID:                  p.prefix + r.ID,
+		PartitionKeyRangeID: r.ID,
+		MinInclusive:        r.MinInclusive,
+		MaxExclusive:        r.MaxExclusive,
+		Continuation:        continuation,
+	}
+
+	return p.store.CreateLease(ctx, lease)
+}
+
+// replaceLease replaces lease, provided that it has not changed since it was
+// read, and returns the result.  If the replace fails, lease is returned.
+func (p *changeFeedProcessor) replaceLease(ctx context.Context, lease *Lease) (*Lease, error) {
+	replaced, err := p.store.ReplaceLease(ctx, lease)
+	if err != nil {
+		return lease, err
+	}
+
+	return replaced, nil
+}
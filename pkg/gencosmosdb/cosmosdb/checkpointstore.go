@@ -0,0 +1,119 @@
+package cosmosdb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CheckpointStore stores the leases of a change feed processor, and with them
+// the checkpoint of each partition key range.  Updates must be conditional on
+// the ETag of the lease, failing with an *Error with StatusCode 412
+// Precondition Failed if it has changed, so that each lease is owned by one
+// host at a time.  Timestamp must be set to the time of each update, in
+// seconds since the epoch, as leases expire relative to it.
+type CheckpointStore interface {
+	// ListLeases returns the leases whose IDs start with prefix
+	ListLeases(ctx context.Context, prefix string) ([]*Lease, error)
+
+	// CreateLease creates lease, unless a lease with its ID already exists
+	CreateLease(ctx context.Context, lease *Lease) error
+
+	// ReplaceLease replaces lease and returns the result.  It fails with an
+	// *Error with StatusCode 404 Not Found if the lease has been deleted.
+	ReplaceLease(ctx context.Context, lease *Lease) (*Lease, error)
+
+	// DeleteLease deletes lease
+	DeleteLease(ctx context.Context, lease *Lease) error
+}
+
+// updatedLease returns a copy of lease with a new ETag and Timestamp, for
+// stores which do not maintain these themselves
+func updatedLease(lease *Lease) *Lease {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+
+	updated := *lease
+	updated.ETag = `"` + hex.EncodeToString(b) + `"`
+	updated.Timestamp = int(time.Now().Unix())
+
+	return &updated
+}
+
+type collectionCheckpointStore struct {
+	*databaseClient
+	path string
+}
+
+// NewCollectionCheckpointStore returns a CheckpointStore which stores leases
+// as documents in the collection collid of collc, which must be partitioned
+// on /id
+func NewCollectionCheckpointStore(collc CollectionClient, collid string) CheckpointStore {
+	return &collectionCheckpointStore{
+		databaseClient: collc.(*collectionClient).databaseClient,
+		path:           collc.(*collectionClient).path + "/colls/" + collid,
+	}
+}
+
+func (s *collectionCheckpointStore) ListLeases(ctx context.Context, prefix string) ([]*Lease, error) {
+	var all []*Lease
+	var continuation string
+
+	for {
+		headers := http.Header{}
+		if continuation != "" {
+			headers.Set("X-Ms-Continuation", continuation)
+		}
+
+		var page *leases
+		err := s.do(ctx, http.MethodGet, s.path+"/docs", "docs", s.path, http.StatusOK, nil, &page, headers, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if page != nil {
+			for _, lease := range page.Documents {
+				if strings.HasPrefix(lease.ID, prefix) {
+					all = append(all, lease)
+				}
+			}
+		}
+
+		continuation = headers.Get("X-Ms-Continuation")
+		if continuation == "" {
+			return all, nil
+		}
+	}
+}
+
+func (s *collectionCheckpointStore) CreateLease(ctx context.Context, lease *Lease) error {
+	headers := http.Header{}
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(lease.ID))
+
+	err := s.do(ctx, http.MethodPost, s.path+"/docs", "docs", s.path, http.StatusCreated, &lease, nil, headers, nil)
+	if IsErrorStatusCode(err, http.StatusConflict) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *collectionCheckpointStore) ReplaceLease(ctx context.Context, lease *Lease) (replaced *Lease, err error) {
+	headers := http.Header{}
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(lease.ID))
+	headers.Set("If-Match", lease.ETag)
+
+	err = s.do(ctx, http.MethodPut, s.path+"/docs/"+lease.ID, "docs", s.path+"/docs/"+lease.ID, http.StatusOK, &lease, &replaced, headers, nil)
+	return
+}
+
+func (s *collectionCheckpointStore) DeleteLease(ctx context.Context, lease *Lease) error {
+	headers := http.Header{}
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(lease.ID))
+	headers.Set("If-Match", lease.ETag)
+
+	return s.do(ctx, http.MethodDelete, s.path+"/docs/"+lease.ID, "docs", s.path+"/docs/"+lease.ID, http.StatusNoContent, nil, nil, headers, nil)
+}
@@ -0,0 +1,164 @@
+package cosmosdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned, without a request being sent, if the circuit
+// breaker of every endpoint to which the request could be sent is open
+var ErrCircuitOpen = fmt.Errorf("circuit breaker is open")
+
+// Defaults of CircuitBreakerOptions
+const (
+	defaultCircuitBreakerFailureThreshold = 5
+	defaultCircuitBreakerOpenDuration     = 30 * time.Second
+)
+
+// CircuitBreakerOptions represents the options of the circuit breakers of a
+// client's endpoints
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failed requests to an
+	// endpoint after which its circuit breaker opens; if zero, 5
+	FailureThreshold int
+
+	// OpenDuration is the time for which an open circuit breaker rejects
+	// requests before letting one through to probe the endpoint; if zero, 30s
+	OpenDuration time.Duration
+}
+
+type circuitBreaker struct {
+	mu        sync.Mutex
+	options   CircuitBreakerOptions
+	endpoints map[string]*circuitBreakerEndpoint
+}
+
+type circuitBreakerEndpoint struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// EnableCircuitBreaker enables a circuit breaker for each endpoint to which
+// the client sends requests.  A circuit breaker opens after consecutive
+// requests fail with 503 Service Unavailable, time out or fail with a network
+// error.  While it is open, requests are sent to another region if endpoint
+// discovery is enabled and one is available, and otherwise fail with
+// ErrCircuitOpen.  Once OpenDuration has passed, a single request probes the
+// endpoint: the circuit breaker closes if it succeeds and opens again if it
+// fails.
+func (c *databaseClient) EnableCircuitBreaker(options *CircuitBreakerOptions) {
+	cb := &circuitBreaker{
+		endpoints: map[string]*circuitBreakerEndpoint{},
+	}
+	if options != nil {
+		cb.options = *options
+	}
+	if cb.options.FailureThreshold == 0 {
+		cb.options.FailureThreshold = defaultCircuitBreakerFailureThreshold
+	}
+	if cb.options.OpenDuration == 0 {
+		cb.options.OpenDuration = defaultCircuitBreakerOpenDuration
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.circuitBreaker = cb
+}
+
+func (c *databaseClient) getCircuitBreaker() *circuitBreaker {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.circuitBreaker
+}
+
+// available returns true if a request could be sent to hostname
+func (cb *circuitBreaker) available(hostname string) bool {
+	if cb == nil {
+		return true
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	e := cb.endpoints[hostname]
+	return e == nil || !e.probing && time.Now().After(e.openUntil)
+}
+
+// allow returns true if a request may be sent to hostname.  If the circuit
+// breaker of hostname is open but due a probe, the request becomes the probe.
+func (cb *circuitBreaker) allow(hostname string) bool {
+	if cb == nil {
+		return true
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	e := cb.endpoints[hostname]
+	if e == nil || e.openUntil.IsZero() {
+		return true
+	}
+
+	if e.probing || time.Now().Before(e.openUntil) {
+		return false
+	}
+
+	e.probing = true
+	return true
+}
+
+// record records the outcome, err, of a request to hostname which was
+// allowed
+func (cb *circuitBreaker) record(log Logger, hostname string, err error) {
+	if cb == nil {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	e := cb.endpoints[hostname]
+	if e == nil {
+		e = &circuitBreakerEndpoint{}
+		cb.endpoints[hostname] = e
+	}
+
+	probing := e.probing
+	e.probing = false
+
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// the request was abandoned, so says nothing about the endpoint
+
+	case !isEndpointFailure(err):
+		if !e.openUntil.IsZero() {
+			log.Info("circuit breaker closed", "hostname", hostname)
+		}
+		delete(cb.endpoints, hostname)
+
+	default:
+		e.failures++
+		if probing || e.openUntil.IsZero() && e.failures >= cb.options.FailureThreshold {
+			log.Warn("circuit breaker opened", "hostname", hostname, "failures", e.failures)
+			e.openUntil = time.Now().Add(cb.options.OpenDuration)
+		}
+	}
+}
+
+// isEndpointFailure returns true if err indicates that the endpoint to which
+// a request was sent is unhealthy
+func isEndpointFailure(err error) bool {
+	var netErr net.Error
+	return IsErrorStatusCode(err, http.StatusServiceUnavailable) ||
+		IsErrorStatusCode(err, http.StatusRequestTimeout) ||
+		IsRetriableError(err) ||
+		errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,42 @@
+package cosmosdb
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+)
+
+// Cloud represents the settings of an Azure cloud environment needed to reach
+// and authenticate against the Cosmos DB data plane
+type Cloud struct {
+	// Configuration is the AAD authority configuration of the cloud
+	Configuration cloud.Configuration
+
+	// Scope is the AAD scope of Cosmos DB data plane tokens
+	Scope string
+
+	// DatabaseHostnameSuffix is the DNS suffix of Cosmos DB accounts
+	DatabaseHostnameSuffix string
+}
+
+// Cloud environments
+var (
+	CloudPublic = Cloud{
+		Configuration:          cloud.AzurePublic,
+		Scope:                  DataPlaneScope,
+		DatabaseHostnameSuffix: "documents.azure.com",
+	}
+	CloudUSGovernment = Cloud{
+		Configuration:          cloud.AzureGovernment,
+		Scope:                  "https://cosmos.azure.us/.default",
+		DatabaseHostnameSuffix: "documents.azure.us",
+	}
+	CloudChina = Cloud{
+		Configuration:          cloud.AzureChina,
+		Scope:                  "https://cosmos.azure.cn/.default",
+		DatabaseHostnameSuffix: "documents.azure.cn",
+	}
+)
+
+// DatabaseHostname returns the hostname of the given account in the cloud
+func (c Cloud) DatabaseHostname(account string) string {
+	return account + "." + c.DatabaseHostnameSuffix
+}
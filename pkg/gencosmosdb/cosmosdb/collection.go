@@ -2,7 +2,9 @@ package cosmosdb
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strings"
 )
 
 // Collection represents a collection
@@ -17,21 +19,49 @@ type Collection struct {
 	Triggers                 string                    `json:"_triggers,omitempty"`
 	UserDefinedFunctions     string                    `json:"_udfs,omitempty"`
 	Conflicts                string                    `json:"_conflicts,omitempty"`
+	DefaultTTL               int                       `json:"defaultTtl,omitempty"`
+	AnalyticalStorageTTL     int                       `json:"analyticalStorageTtl,omitempty"`
 	IndexingPolicy           *IndexingPolicy           `json:"indexingPolicy,omitempty"`
 	PartitionKey             *PartitionKey             `json:"partitionKey,omitempty"`
 	UniqueKeyPolicy          *UniqueKeyPolicy          `json:"uniqueKeyPolicy,omitempty"`
 	ConflictResolutionPolicy *ConflictResolutionPolicy `json:"conflictResolutionPolicy,omitempty"`
 	AllowMaterializedViews   bool                      `json:"allowMaterializedViews,omitempty"`
 	GeospatialConfig         *GeospatialConfig         `json:"geospatialConfig,omitempty"`
+	ComputedProperties       []ComputedProperty        `json:"computedProperties,omitempty"`
 }
 
-// IndexingPolicy represents an indexing policy
+// ComputedProperty represents a computed property, a property of each
+// document derived from the document by Query, e.g. "SELECT VALUE
+// LOWER(c.name) FROM c".  Computed properties are not stored but can be
+// queried and, if their path is included in the indexing policy, indexed.
+type ComputedProperty struct {
+	Name  string `json:"name,omitempty"`
+	Query string `json:"query,omitempty"`
+}
+
+// computedPropertiesAPIVersion is the API version which is needed to define
+// computed properties
+const computedPropertiesAPIVersion = "2020-07-15"
+
+// collectionHeaders returns the headers of a request to create or replace
+// coll
+func collectionHeaders(coll *Collection) http.Header {
+	headers := http.Header{}
+	if len(coll.ComputedProperties) > 0 {
+		headers.Set("X-Ms-Version", computedPropertiesAPIVersion)
+	}
+	return headers
+}
+
+// IndexingPolicy represents an indexing policy.  Automatic, if nil, leaves
+// the server default of true; it must be false if IndexingMode is None.
 type IndexingPolicy struct {
-	Automatic        bool               `json:"automatic,omitempty"`
+	Automatic        *bool              `json:"automatic,omitempty"`
 	IndexingMode     IndexingPolicyMode `json:"indexingMode,omitempty"`
 	IncludedPaths    []IncludedPath     `json:"includedPaths,omitempty"`
-	ExcludedPaths    []IncludedPath     `json:"excludedPaths,omitempty"`
+	ExcludedPaths    []ExcludedPath     `json:"excludedPaths,omitempty"`
 	CompositeIndexes []CompositeIndex   `json:"compositeIndexes,omitempty"`
+	SpatialIndexes   []SpatialIndex     `json:"spatialIndexes,omitempty"`
 }
 
 // IndexingPolicyMode represents an indexing policy mode
@@ -41,6 +71,7 @@ type IndexingPolicyMode string
 const (
 	IndexingPolicyModeConsistent IndexingPolicyMode = "Consistent"
 	IndexingPolicyModeLazy       IndexingPolicyMode = "Lazy"
+	IndexingPolicyModeNone       IndexingPolicyMode = "None"
 )
 
 // IncludedPath represents an included path
@@ -61,11 +92,12 @@ type IndexDataType string
 
 // IndexDataType constants
 const (
-	IndexDataTypeString     IndexDataType = "String"
-	IndexDataTypeNumber     IndexDataType = "Number"
-	IndexDataTypePoint      IndexDataType = "Point"
-	IndexDataTypePolygon    IndexDataType = "Polygon"
-	IndexDataTypeLineString IndexDataType = "LineString"
+	IndexDataTypeString       IndexDataType = "String"
+	IndexDataTypeNumber       IndexDataType = "Number"
+	IndexDataTypePoint        IndexDataType = "Point"
+	IndexDataTypePolygon      IndexDataType = "Polygon"
+	IndexDataTypeLineString   IndexDataType = "LineString"
+	IndexDataTypeMultiPolygon IndexDataType = "MultiPolygon"
 )
 
 // IndexKind represents an index kind
@@ -83,6 +115,23 @@ type ExcludedPath struct {
 	Path string `json:"path,omitempty"`
 }
 
+// SpatialIndex represents a spatial index, which serves geospatial queries
+// such as ST_DISTANCE and ST_WITHIN on the values at Path.  BoundingBox is
+// required if the collection uses the Geometry geospatial config.
+type SpatialIndex struct {
+	Path        string          `json:"path,omitempty"`
+	Types       []IndexDataType `json:"types,omitempty"`
+	BoundingBox *BoundingBox    `json:"boundingBox,omitempty"`
+}
+
+// BoundingBox represents the bounds of the coordinates of a spatial index
+type BoundingBox struct {
+	XMin float64 `json:"xmin"`
+	YMin float64 `json:"ymin"`
+	XMax float64 `json:"xmax"`
+	YMax float64 `json:"ymax"`
+}
+
 // CompositeIndex represents a composite index
 type CompositeIndex []struct {
 	Path  string `json:"path,omitempty"`
@@ -98,7 +147,9 @@ const (
 	OrderDescending Order = "descending"
 )
 
-// PartitionKey represents a partition key
+// PartitionKey represents a partition key.  A hierarchical partition key has
+// Kind MultiHash, Version 2 and up to three paths; the partition keys of its
+// documents are made by HierarchicalPartitionKey.
 type PartitionKey struct {
 	Paths   []string         `json:"paths,omitempty"`
 	Kind    PartitionKeyKind `json:"kind,omitempty"`
@@ -110,9 +161,31 @@ type PartitionKeyKind string
 
 // PartitionKeyKind constants
 const (
-	PartitionKeyKindHash PartitionKeyKind = "Hash"
+	PartitionKeyKindHash      PartitionKeyKind = "Hash"
+	PartitionKeyKindMultiHash PartitionKeyKind = "MultiHash"
 )
 
+// maxHierarchicalPartitionKeyPaths is the maximum number of paths of a
+// MultiHash partition key
+const maxHierarchicalPartitionKeyPaths = 3
+
+// ErrInvalidPartitionKey is returned by Create if a MultiHash partition key
+// does not have Version 2 or has too many paths
+var ErrInvalidPartitionKey = fmt.Errorf("partition key is invalid")
+
+// validate returns ErrInvalidPartitionKey if p is invalid
+func (p *PartitionKey) validate() error {
+	if p == nil || p.Kind != PartitionKeyKindMultiHash {
+		return nil
+	}
+
+	if p.Version != 2 || len(p.Paths) == 0 || len(p.Paths) > maxHierarchicalPartitionKeyPaths {
+		return ErrInvalidPartitionKey
+	}
+
+	return nil
+}
+
 // UniqueKeyPolicy represents a unique key policy
 type UniqueKeyPolicy struct {
 	UniqueKeys []UniqueKey `json:"uniqueKeys,omitempty"`
@@ -123,13 +196,78 @@ type UniqueKey struct {
 	Paths []string `json:"paths,omitempty"`
 }
 
-// ConflictResolutionPolicy represents a conflict resolution policy
+// maxUniqueKeyPaths is the maximum number of paths in a unique key
+const maxUniqueKeyPaths = 16
+
+// ErrInvalidUniqueKeyPolicy is returned by Create if a unique key of a
+// collection has no paths, too many paths, or a path which is not absolute or
+// is repeated within the policy
+var ErrInvalidUniqueKeyPolicy = fmt.Errorf("unique key policy is invalid")
+
+// validate returns ErrInvalidUniqueKeyPolicy if p is invalid
+func (p *UniqueKeyPolicy) validate() error {
+	if p == nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for _, key := range p.UniqueKeys {
+		if len(key.Paths) == 0 || len(key.Paths) > maxUniqueKeyPaths {
+			return ErrInvalidUniqueKeyPolicy
+		}
+
+		for _, path := range key.Paths {
+			if !strings.HasPrefix(path, "/") || seen[path] {
+				return ErrInvalidUniqueKeyPolicy
+			}
+			seen[path] = true
+		}
+	}
+
+	return nil
+}
+
+// ConflictResolutionPolicy represents the conflict resolution policy of a
+// collection in an account with multiple write regions.  In LastWriterWins
+// mode, ConflictResolutionPath is the numeric property which decides the
+// winner, by default /_ts.  In Custom mode, ConflictResolutionProcedure is the
+// link of a stored procedure which resolves conflicts, e.g.
+// dbs/db/colls/coll/sprocs/resolver; if it is empty, conflicts are written to
+// the conflicts feed.
 type ConflictResolutionPolicy struct {
 	Mode                        ConflictResolutionPolicyMode `json:"mode,omitempty"`
 	ConflictResolutionPath      string                       `json:"conflictResolutionPath,omitempty"`
 	ConflictResolutionProcedure string                       `json:"conflictResolutionProcedure,omitempty"`
 }
 
+// ErrInvalidConflictResolutionPolicy is returned by Create if a conflict
+// resolution policy sets the field of the other mode, or its path is not
+// absolute
+var ErrInvalidConflictResolutionPolicy = fmt.Errorf("conflict resolution policy is invalid")
+
+// validate returns ErrInvalidConflictResolutionPolicy if p is invalid
+func (p *ConflictResolutionPolicy) validate() error {
+	if p == nil {
+		return nil
+	}
+
+	switch p.Mode {
+	case ConflictResolutionPolicyModeLastWriterWins, "":
+		if p.ConflictResolutionProcedure != "" ||
+			p.ConflictResolutionPath != "" && !strings.HasPrefix(p.ConflictResolutionPath, "/") {
+			return ErrInvalidConflictResolutionPolicy
+		}
+	case ConflictResolutionPolicyModeCustom:
+		if p.ConflictResolutionPath != "" {
+			return ErrInvalidConflictResolutionPolicy
+		}
+	default:
+		return ErrInvalidConflictResolutionPolicy
+	}
+
+	return nil
+}
+
 // ConflictResolutionPolicyMode represents a conflict resolution policy mode
 type ConflictResolutionPolicyMode string
 
@@ -150,6 +288,7 @@ type GeospatialConfigType string
 // GeospatialConfigType constants
 const (
 	GeospatialConfigTypeGeography GeospatialConfigType = "Geography"
+	GeospatialConfigTypeGeometry  GeospatialConfigType = "Geometry"
 )
 
 // Collections represents collections
@@ -221,12 +360,16 @@ type collectionClient struct {
 // CollectionClient is a collection client
 type CollectionClient interface {
 	Create(context.Context, *Collection) (*Collection, error)
+	CreateWithThroughput(context.Context, *Collection, *Throughput) (*Collection, error)
 	List() CollectionIterator
 	ListAll(context.Context) (*Collections, error)
 	Get(context.Context, string) (*Collection, error)
 	Delete(context.Context, *Collection) error
 	Replace(context.Context, *Collection) (*Collection, error)
 	PartitionKeyRanges(context.Context, string) (*PartitionKeyRanges, error)
+	FeedRanges(context.Context, string) ([]FeedRange, error)
+	FeedRangeForPartitionKey(context.Context, string, string) (*FeedRange, error)
+	ResolvePartitionKeyRange(context.Context, string, string) (*PartitionKeyRange, error)
 }
 
 type collectionListIterator struct {
@@ -238,6 +381,7 @@ type collectionListIterator struct {
 // CollectionIterator is a collection iterator
 type CollectionIterator interface {
 	Next(context.Context) (*Collections, error)
+	Items(context.Context) func(func(*Collection, error) bool)
 }
 
 // NewCollectionClient returns a new collection client
@@ -268,8 +412,36 @@ func (c *collectionClient) all(ctx context.Context, i CollectionIterator) (*Coll
 	return allcolls, nil
 }
 
-func (c *collectionClient) Create(ctx context.Context, newcoll *Collection) (coll *Collection, err error) {
-	err = c.do(ctx, http.MethodPost, c.path+"/colls", "colls", c.path, http.StatusCreated, &newcoll, &coll, nil)
+func (c *collectionClient) Create(ctx context.Context, newcoll *Collection) (*Collection, error) {
+	return c.CreateWithThroughput(ctx, newcoll, nil)
+}
+
+// CreateWithThroughput creates a collection with dedicated throughput
+// provisioned according to throughput
+func (c *collectionClient) CreateWithThroughput(ctx context.Context, newcoll *Collection, throughput *Throughput) (coll *Collection, err error) {
+	err = newcoll.PartitionKey.validate()
+	if err != nil {
+		return
+	}
+
+	err = newcoll.UniqueKeyPolicy.validate()
+	if err != nil {
+		return
+	}
+
+	err = newcoll.ConflictResolutionPolicy.validate()
+	if err != nil {
+		return
+	}
+
+	headers := collectionHeaders(newcoll)
+
+	err = throughput.setHeaders(headers)
+	if err != nil {
+		return
+	}
+
+	err = c.do(ctx, http.MethodPost, c.path+"/colls", "colls", c.path, http.StatusCreated, &newcoll, &coll, headers, nil)
 	return
 }
 
@@ -282,7 +454,7 @@ func (c *collectionClient) ListAll(ctx context.Context) (*Collections, error) {
 }
 
 func (c *collectionClient) Get(ctx context.Context, collid string) (coll *Collection, err error) {
-	err = c.do(ctx, http.MethodGet, c.path+"/colls/"+collid, "colls", c.path+"/colls/"+collid, http.StatusOK, nil, &coll, nil)
+	err = c.do(ctx, http.MethodGet, c.path+"/colls/"+collid, "colls", c.path+"/colls/"+collid, http.StatusOK, nil, &coll, nil, nil)
 	return
 }
 
@@ -292,19 +464,76 @@ func (c *collectionClient) Delete(ctx context.Context, coll *Collection) error {
 	}
 	headers := http.Header{}
 	headers.Set("If-Match", coll.ETag)
-	return c.do(ctx, http.MethodDelete, c.path+"/colls/"+coll.ID, "colls", c.path+"/colls/"+coll.ID, http.StatusNoContent, nil, nil, headers)
+	err := c.do(ctx, http.MethodDelete, c.path+"/colls/"+coll.ID, "colls", c.path+"/colls/"+coll.ID, http.StatusNoContent, nil, nil, headers, nil)
+	if err == nil {
+		c.pkRangeCache.invalidate(c.path + "/colls/" + coll.ID)
+	}
+	return err
 }
 
 func (c *collectionClient) Replace(ctx context.Context, newcoll *Collection) (coll *Collection, err error) {
-	err = c.do(ctx, http.MethodPost, c.path+"/colls/"+newcoll.ID, "colls", c.path+"/colls/"+newcoll.ID, http.StatusCreated, &newcoll, &coll, nil)
+	err = c.do(ctx, http.MethodPut, c.path+"/colls/"+newcoll.ID, "colls", c.path+"/colls/"+newcoll.ID, http.StatusOK, &newcoll, &coll, collectionHeaders(newcoll), nil)
 	return
 }
 
 func (c *collectionClient) PartitionKeyRanges(ctx context.Context, collid string) (pkrs *PartitionKeyRanges, err error) {
-	err = c.do(ctx, http.MethodGet, c.path+"/colls/"+collid+"/pkranges", "pkranges", c.path+"/colls/"+collid, http.StatusOK, nil, &pkrs, nil)
+	err = c.do(ctx, http.MethodGet, c.path+"/colls/"+collid+"/pkranges", "pkranges", c.path+"/colls/"+collid, http.StatusOK, nil, &pkrs, nil, nil)
 	return
 }
 
+// FeedRanges returns the feed ranges of the collection, sorted by lower bound
+func (c *collectionClient) FeedRanges(ctx context.Context, collid string) ([]FeedRange, error) {
+	ranges, err := c.partitionKeyRanges(ctx, c.path+"/colls/"+collid, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	feedRanges := make([]FeedRange, len(ranges))
+	for i, r := range ranges {
+		feedRanges[i] = FeedRange{MinInclusive: r.MinInclusive, MaxExclusive: r.MaxExclusive}
+	}
+
+	return feedRanges, nil
+}
+
+// FeedRangeForPartitionKey returns the feed range of the documents of the
+// collection with partition key partitionkey or, if it is a prefix of a
+// hierarchical partition key, of those whose partition keys extend it.  The
+// collection's partition key definition is cached after the first call.
+func (c *collectionClient) FeedRangeForPartitionKey(ctx context.Context, collid, partitionkey string) (*FeedRange, error) {
+	return c.feedRangeOf(ctx, c.path+"/colls/"+collid, nil, partitionkey)
+}
+
+// ResolvePartitionKeyRange returns the partition key range of the collection
+// which currently holds the documents with partition key partitionkey,
+// computing it from the cached partition key definition and ranges.  The
+// documents of a prefix of a hierarchical partition key may span ranges, so
+// partitionkey must be complete.
+func (c *collectionClient) ResolvePartitionKeyRange(ctx context.Context, collid, partitionkey string) (*PartitionKeyRange, error) {
+	path := c.path + "/colls/" + collid
+
+	def, err := c.partitionKeyDefinition(ctx, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	epk, err := EffectivePartitionKey(def, partitionkey)
+	if err != nil {
+		return nil, err
+	}
+
+	if def.Kind == PartitionKeyKindMultiHash && len(epk) != len(def.Paths)*effectivePartitionKeyV2Length {
+		return nil, ErrInvalidPartitionKey
+	}
+
+	pkr, err := c.partitionKeyRangeOf(ctx, path, nil, epk)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pkr, nil
+}
+
 func (i *collectionListIterator) Next(ctx context.Context) (colls *Collections, err error) {
 	if i.done {
 		return
@@ -315,7 +544,7 @@ func (i *collectionListIterator) Next(ctx context.Context) (colls *Collections,
 		headers.Set("X-Ms-Continuation", i.continuation)
 	}
 
-	err = i.do(ctx, http.MethodGet, i.path+"/colls", "colls", i.path, http.StatusOK, nil, &colls, headers)
+	err = i.do(ctx, http.MethodGet, i.path+"/colls", "colls", i.path, http.StatusOK, nil, &colls, headers, nil)
 	if err != nil {
 		return
 	}
@@ -325,3 +554,7 @@ func (i *collectionListIterator) Next(ctx context.Context) (colls *Collections,
 
 	return
 }
+
+func (i *collectionListIterator) Items(ctx context.Context) func(func(*Collection, error) bool) {
+	return items(ctx, i.Next, func(colls *Collections) []*Collection { return colls.Collections })
+}
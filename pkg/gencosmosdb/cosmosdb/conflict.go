@@ -0,0 +1,151 @@
+package cosmosdb
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Conflict represents a conflict between writes to a resource in different
+// regions, which was not resolved by the conflict resolution policy of its
+// collection
+type Conflict struct {
+	ID            string            `json:"id,omitempty"`
+	ResourceID    string            `json:"_rid,omitempty"`
+	Timestamp     int               `json:"_ts,omitempty"`
+	Self          string            `json:"_self,omitempty"`
+	ETag          string            `json:"_etag,omitempty"`
+	ResourceType  string            `json:"resourceType,omitempty"`
+	OperationType ConflictOperation `json:"operationType,omitempty"`
+	SourceID      string            `json:"resourceId,omitempty"`
+	Content       string            `json:"content,omitempty"`
+	ConflictLSN   int               `json:"conflict_lsn,omitempty"`
+}
+
+// ConflictOperation represents the operation which caused a conflict
+type ConflictOperation string
+
+// ConflictOperation constants
+const (
+	ConflictOperationCreate  ConflictOperation = "create"
+	ConflictOperationReplace ConflictOperation = "replace"
+	ConflictOperationDelete  ConflictOperation = "delete"
+)
+
+// DecodeContent decodes the version of the resource which lost the conflict
+// into v
+func (c *Conflict) DecodeContent(jsonHandle *codec.JsonHandle, v interface{}) error {
+	return codec.NewDecoderBytes([]byte(c.Content), jsonHandle).Decode(v)
+}
+
+// Conflicts represents conflicts
+type Conflicts struct {
+	Count      int         `json:"_count,omitempty"`
+	ResourceID string      `json:"_rid,omitempty"`
+	Conflicts  []*Conflict `json:"Conflicts,omitempty"`
+}
+
+type conflictClient struct {
+	*databaseClient
+	path string
+}
+
+// ConflictClient is a conflict client.  Get and Delete take the partition key
+// of the conflicting resource.
+type ConflictClient interface {
+	List() ConflictIterator
+	ListAll(context.Context) (*Conflicts, error)
+	Get(context.Context, string, string) (*Conflict, error)
+	Delete(context.Context, string, *Conflict) error
+}
+
+type conflictListIterator struct {
+	*conflictClient
+	continuation string
+	done         bool
+}
+
+// ConflictIterator is a conflict iterator
+type ConflictIterator interface {
+	Next(context.Context) (*Conflicts, error)
+	Items(context.Context) func(func(*Conflict, error) bool)
+}
+
+// NewConflictClient returns a new conflict client
+func NewConflictClient(collc CollectionClient, collid string) ConflictClient {
+	return &conflictClient{
+		databaseClient: collc.(*collectionClient).databaseClient,
+		path:           collc.(*collectionClient).path + "/colls/" + collid,
+	}
+}
+
+func (c *conflictClient) all(ctx context.Context, i ConflictIterator) (*Conflicts, error) {
+	allconflicts := &Conflicts{}
+
+	for {
+		conflicts, err := i.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if conflicts == nil {
+			break
+		}
+
+		allconflicts.Count += conflicts.Count
+		allconflicts.ResourceID = conflicts.ResourceID
+		allconflicts.Conflicts = append(allconflicts.Conflicts, conflicts.Conflicts...)
+	}
+
+	return allconflicts, nil
+}
+
+func (c *conflictClient) List() ConflictIterator {
+	return &conflictListIterator{conflictClient: c}
+}
+
+func (c *conflictClient) ListAll(ctx context.Context) (*Conflicts, error) {
+	return c.all(ctx, c.List())
+}
+
+func (c *conflictClient) Get(ctx context.Context, partitionkey, conflictid string) (conflict *Conflict, err error) {
+	headers := http.Header{}
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
+	err = c.do(ctx, http.MethodGet, c.path+"/conflicts/"+conflictid, "conflicts", c.path+"/conflicts/"+conflictid, http.StatusOK, nil, &conflict, headers, nil)
+	return
+}
+
+func (c *conflictClient) Delete(ctx context.Context, partitionkey string, conflict *Conflict) error {
+	if conflict.ETag == "" {
+		return ErrETagRequired
+	}
+	headers := http.Header{}
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
+	headers.Set("If-Match", conflict.ETag)
+	return c.do(ctx, http.MethodDelete, c.path+"/conflicts/"+conflict.ID, "conflicts", c.path+"/conflicts/"+conflict.ID, http.StatusNoContent, nil, nil, headers, nil)
+}
+
+func (i *conflictListIterator) Next(ctx context.Context) (conflicts *Conflicts, err error) {
+	if i.done {
+		return
+	}
+
+	headers := http.Header{}
+	if i.continuation != "" {
+		headers.Set("X-Ms-Continuation", i.continuation)
+	}
+
+	err = i.do(ctx, http.MethodGet, i.path+"/conflicts", "conflicts", i.path, http.StatusOK, nil, &conflicts, headers, nil)
+	if err != nil {
+		return
+	}
+
+	i.continuation = headers.Get("X-Ms-Continuation")
+	i.done = i.continuation == ""
+
+	return
+}
+
+func (i *conflictListIterator) Items(ctx context.Context) func(func(*Conflict, error) bool) {
+	return items(ctx, i.Next, func(conflicts *Conflicts) []*Conflict { return conflicts.Conflicts })
+}
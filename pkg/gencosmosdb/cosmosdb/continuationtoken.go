@@ -0,0 +1,163 @@
+package cosmosdb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ErrContinuationTokenMismatch is returned when a ContinuationToken is used to
+// resume a query or feed other than the one it was issued for
+var ErrContinuationTokenMismatch = fmt.Errorf("continuation token does not match query")
+
+// ContinuationToken is an opaque paging position which can be safely round
+// tripped through external clients, for example in an HTTP API.  It records
+// the identity of the query or feed it was issued for, so that it cannot be
+// used to resume a different one.
+//
+// If Key is set to an AES key of 16, 24 or 32 bytes, the token is encrypted
+// and authenticated when marshalled, and must be unmarshalled with the same
+// Key.
+type ContinuationToken struct {
+	Continuation string
+	Identity     string
+
+	Key []byte
+}
+
+// continuationToken is the serialized form of a ContinuationToken
+type continuationToken struct {
+	Continuation string `json:"c"`
+	Identity     string `json:"q"`
+}
+
+// NewContinuationToken returns a ContinuationToken holding the raw
+// continuation of a query with the given partition key, or of a feed if query
+// is nil
+func NewContinuationToken(continuation, partitionkey string, query *Query) (*ContinuationToken, error) {
+	identity, err := queryIdentity(partitionkey, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ContinuationToken{
+		Continuation: continuation,
+		Identity:     identity,
+	}, nil
+}
+
+// ContinuationFor returns the raw continuation held by t, suitable for
+// Options.Continuation, if t was issued for the same partition key and query
+func (t *ContinuationToken) ContinuationFor(partitionkey string, query *Query) (string, error) {
+	identity, err := queryIdentity(partitionkey, query)
+	if err != nil {
+		return "", err
+	}
+
+	if identity != t.Identity {
+		return "", ErrContinuationTokenMismatch
+	}
+
+	return t.Continuation, nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (t *ContinuationToken) MarshalText() ([]byte, error) {
+	b, err := json.Marshal(&continuationToken{
+		Continuation: t.Continuation,
+		Identity:     t.Identity,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Key != nil {
+		aead, err := continuationTokenAEAD(t.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		nonce := make([]byte, aead.NonceSize())
+		_, err = io.ReadFull(rand.Reader, nonce)
+		if err != nil {
+			return nil, err
+		}
+
+		b = aead.Seal(nonce, nonce, b, nil)
+	}
+
+	text := make([]byte, base64.RawURLEncoding.EncodedLen(len(b)))
+	base64.RawURLEncoding.Encode(text, b)
+
+	return text, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (t *ContinuationToken) UnmarshalText(text []byte) error {
+	b := make([]byte, base64.RawURLEncoding.DecodedLen(len(text)))
+	n, err := base64.RawURLEncoding.Decode(b, text)
+	if err != nil {
+		return err
+	}
+	b = b[:n]
+
+	if t.Key != nil {
+		aead, err := continuationTokenAEAD(t.Key)
+		if err != nil {
+			return err
+		}
+
+		if len(b) < aead.NonceSize() {
+			return fmt.Errorf("continuation token is too short")
+		}
+
+		b, err = aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	var token continuationToken
+	err = json.Unmarshal(b, &token)
+	if err != nil {
+		return err
+	}
+
+	t.Continuation = token.Continuation
+	t.Identity = token.Identity
+
+	return nil
+}
+
+func continuationTokenAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// queryIdentity returns a hash identifying a query and its partition key
+func queryIdentity(partitionkey string, query *Query) (string, error) {
+	b, err := json.Marshal(&struct {
+		PartitionKey string `json:"partitionKey"`
+		Query        *Query `json:"query"`
+	}{
+		PartitionKey: partitionkey,
+		Query:        query,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:16]), nil
+}
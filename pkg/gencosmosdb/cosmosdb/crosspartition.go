@@ -0,0 +1,677 @@
+package cosmosdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// queryPage is a page of query results whose documents are left undecoded
+type queryPage struct {
+	Count      int               `json:"_count,omitempty"`
+	ResourceID string            `json:"_rid,omitempty"`
+	Documents  []json.RawMessage `json:"Documents,omitempty"`
+}
+
+// decodePage decodes p into out, which is typically a pointer to a pointer to
+// a generated document list type
+func (c *databaseClient) decodePage(p *queryPage, out interface{}) error {
+	rid, err := json.Marshal(p.ResourceID)
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(`{"_count":`)
+	buf.WriteString(strconv.Itoa(len(p.Documents)))
+	buf.WriteString(`,"_rid":`)
+	buf.Write(rid)
+	buf.WriteString(`,"Documents":[`)
+	for i, doc := range p.Documents {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(doc)
+	}
+	buf.WriteString(`]}`)
+
+	return c.decode(buf.Bytes(), out)
+}
+
+// crossPartitionContinuation is the continuation of a crossPartitionQuery.
+// Ranges are identified by their lower bound so that the position survives
+// changes to the set of partition key ranges.
+type crossPartitionContinuation struct {
+	MinInclusive string            `json:"min"`
+	Continuation string            `json:"token,omitempty"`
+	Inherited    map[string]string `json:"inherited,omitempty"`
+}
+
+// SubStatusCodePartitionKeyRangeGone is the sub-status code returned with 410
+// Gone when a partition key range no longer exists because it has been split
+const SubStatusCodePartitionKeyRangeGone = 1002
+
+// SubStatusCodeCompletingSplit is the sub-status code returned with 410 Gone
+// while a partition key range is being split
+const SubStatusCodeCompletingSplit = 1007
+
+// isPartitionKeyRangeGone returns true if err indicates that a partition key
+// range has been split, and so the ranges of the collection must be refreshed
+func isPartitionKeyRangeGone(err error) bool {
+	if err, ok := err.(*Error); ok {
+		return err.StatusCode == http.StatusGone &&
+			(err.SubStatusCode == SubStatusCodePartitionKeyRangeGone || err.SubStatusCode == SubStatusCodeCompletingSplit)
+	}
+	return false
+}
+
+// ErrContinuationNotSupported is returned when resuming a cross-partition
+// query which is executed client-side
+var ErrContinuationNotSupported = fmt.Errorf("continuation is not supported by this cross-partition query")
+
+// crossPartitionQuery executes a query against each partition key range of a
+// collection in turn, presenting the results as a single stream of pages.
+//
+// Features of the query which the gateway cannot complete across partition
+// key ranges are identified by its query plan and completed client-side:
+// aggregate and GROUP BY queries are executed in full on the first call to
+// nextPage, ORDER BY results are merged across ranges, duplicate DISTINCT
+// results are removed, and TOP and OFFSET/LIMIT are applied.  With the
+// exception of DISTINCT, such queries cannot be resumed from a continuation,
+// and duplicate DISTINCT results are not removed across a resumed
+// continuation.
+//
+// If a partition key range is split during the query, the ranges are
+// refreshed and the query continues on the child ranges, each of which
+// inherits the continuation of its parent.
+//
+// If Options.MaxDegreeOfParallelism is set, the first pages of the ranges
+// following the current one are prefetched concurrently with it, and the
+// ranges of an ORDER BY query are filled concurrently.
+type crossPartitionQuery struct {
+	*databaseClient
+	path         string
+	query        *Query
+	options      *Options
+	ranges       []PartitionKeyRange
+	loaded       bool
+	index        int
+	continuation string
+	prefetched   map[string]*prefetchedPage
+	inherited    map[string]string
+	resume       *crossPartitionContinuation
+	resumable    bool
+	aggregator   *groupAggregator
+	aggregated   bool
+	results      []json.RawMessage
+	orderBy      *orderByMerge
+	distinct     *distinctFilter
+	skip         int
+	take         int
+	metrics      QueryMetrics
+	mu           sync.Mutex
+	pending      []json.RawMessage
+	err          error
+}
+
+// prefetchedPage is the result of fetching a page from a partition key range
+type prefetchedPage struct {
+	page         *queryPage
+	continuation string
+	err          error
+}
+
+// newCrossPartitionQuery returns a new crossPartitionQuery against the
+// collection at path, resuming from continuation if it is set
+func (c *databaseClient) newCrossPartitionQuery(path string, query *Query, options *Options, continuation string) *crossPartitionQuery {
+	q := &crossPartitionQuery{
+		databaseClient: c,
+		path:           path,
+		query:          query,
+		options:        options,
+		resumable:      true,
+		take:           -1,
+	}
+
+	if continuation != "" {
+		q.resume = &crossPartitionContinuation{}
+		q.err = json.Unmarshal([]byte(continuation), q.resume)
+	}
+
+	return q
+}
+
+func (q *crossPartitionQuery) load(ctx context.Context) error {
+	plan, err := q.getQueryPlan(ctx, q.path, q.query, q.options)
+	if err != nil {
+		return err
+	}
+
+	info := &plan.QueryInfo
+
+	if info.RewrittenQuery != "" {
+		q.query = &Query{
+			Query:      strings.ReplaceAll(info.RewrittenQuery, orderByFilterPlaceholder, "true"),
+			Parameters: q.query.Parameters,
+		}
+	}
+
+	if len(info.Aggregates) > 0 || len(info.GroupByExpressions) > 0 {
+		q.aggregator, err = newGroupAggregator(info)
+		if err != nil {
+			return err
+		}
+		q.resumable = false
+	}
+
+	if info.DistinctType != "" && info.DistinctType != "None" {
+		// duplicates of an ordered DISTINCT query are adjacent once the
+		// ORDER BY results are merged
+		q.distinct = newDistinctFilter(info.DistinctType == "Ordered" && len(info.OrderBy) > 0)
+	}
+
+	if info.Offset != nil {
+		q.skip = *info.Offset
+		q.resumable = false
+	}
+
+	switch {
+	case info.Limit != nil:
+		q.take = *info.Limit
+		q.resumable = false
+	case info.Top != nil:
+		q.take = *info.Top
+		q.resumable = false
+	}
+
+	if len(info.OrderBy) > 0 {
+		q.resumable = false
+	}
+
+	if q.resume != nil && !q.resumable {
+		return ErrContinuationNotSupported
+	}
+
+	q.ranges, err = q.loadRanges(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(info.OrderBy) > 0 {
+		q.orderBy = newOrderByMerge(info.OrderBy, q.ranges)
+	}
+
+	if q.resume != nil {
+		q.index = sort.Search(len(q.ranges), func(i int) bool { return q.ranges[i].MaxExclusive > q.resume.MinInclusive })
+		if q.index < len(q.ranges) && q.ranges[q.index].MinInclusive == q.resume.MinInclusive {
+			q.continuation = q.resume.Continuation
+		}
+		q.inherited = q.resume.Inherited
+		q.resume = nil
+	}
+
+	q.loaded = true
+
+	return nil
+}
+
+// loadRanges returns the partition key ranges of the collection, sorted by
+// lower bound
+func (q *crossPartitionQuery) loadRanges(ctx context.Context) ([]PartitionKeyRange, error) {
+	ranges, err := q.partitionKeyRanges(ctx, q.path, q.options)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.options.feedRange().scope(ranges), nil
+}
+
+// childRanges returns the partition key ranges which have replaced parent
+func (q *crossPartitionQuery) childRanges(ctx context.Context, parent PartitionKeyRange, err error) ([]PartitionKeyRange, error) {
+	children, err := q.childPartitionKeyRanges(ctx, q.path, q.options, parent, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.options.feedRange().scope(children), nil
+}
+
+// split replaces the current partition key range, which has been found to be
+// gone, with its child ranges
+func (q *crossPartitionQuery) split(ctx context.Context, err error) error {
+	children, err := q.childRanges(ctx, q.ranges[q.index], err)
+	if err != nil {
+		return err
+	}
+
+	q.getLogger().Warn("partition key range is gone: continuing on its children", "path", q.path, "partitionKeyRange", q.ranges[q.index].ID, "children", len(children))
+
+	ranges := make([]PartitionKeyRange, 0, len(q.ranges)+len(children)-1)
+	ranges = append(ranges, q.ranges[:q.index]...)
+	ranges = append(ranges, children...)
+	ranges = append(ranges, q.ranges[q.index+1:]...)
+	q.ranges = ranges
+
+	// the first child continues with the current continuation; the others
+	// inherit it when they are reached
+	if q.continuation != "" {
+		if q.inherited == nil {
+			q.inherited = map[string]string{}
+		}
+		for _, child := range children[1:] {
+			q.inherited[child.MinInclusive] = q.continuation
+		}
+	}
+
+	return nil
+}
+
+// nextPage returns the next non-empty page of results, or nil when all
+// partition key ranges are exhausted
+func (q *crossPartitionQuery) nextPage(ctx context.Context, maxItemCount int) (*queryPage, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	if !q.loaded {
+		err := q.load(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for q.take != 0 {
+		var page *queryPage
+		var err error
+		switch {
+		case q.aggregator != nil:
+			page, err = q.aggregate(ctx, maxItemCount)
+		case q.orderBy != nil:
+			page, err = q.mergeOrderBy(ctx, maxItemCount)
+		default:
+			page, err = q.fetchPage(ctx, maxItemCount)
+		}
+		if err != nil || page == nil {
+			return nil, err
+		}
+
+		if q.distinct != nil {
+			page.Documents, err = q.distinct.filter(page.Documents)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if q.skip > 0 {
+			n := q.skip
+			if n > len(page.Documents) {
+				n = len(page.Documents)
+			}
+			page.Documents = page.Documents[n:]
+			q.skip -= n
+		}
+
+		if q.take > 0 {
+			if len(page.Documents) > q.take {
+				page.Documents = page.Documents[:q.take]
+			}
+			q.take -= len(page.Documents)
+		}
+
+		if len(page.Documents) > 0 {
+			return page, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// fetchRange fetches a page of results from the partition key range pkr,
+// returning it with the continuation of the range
+func (q *crossPartitionQuery) fetchRange(ctx context.Context, pkr PartitionKeyRange, continuation string, maxItemCount int) (*queryPage, string, error) {
+	headers := http.Header{}
+	headers.Set("X-Ms-Max-Item-Count", strconv.Itoa(maxItemCount))
+	headers.Set("X-Ms-Documentdb-Query-Enablecrosspartition", "True")
+	headers.Set("X-Ms-Documentdb-PartitionKeyRangeID", pkr.ID)
+	if continuation != "" {
+		headers.Set("X-Ms-Continuation", continuation)
+	}
+	q.options.feedRange().setHeaders(headers, pkr)
+	q.options.setFeedHeaders(headers)
+
+	var page *queryPage
+	err := q.doQuery(ctx, q.path+"/docs", "docs", q.path, q.query, &page, headers, q.options)
+	if err != nil {
+		return nil, "", err
+	}
+
+	q.mu.Lock()
+	q.metrics.add(headers)
+	q.mu.Unlock()
+
+	return page, headers.Get("X-Ms-Continuation"), nil
+}
+
+// parallel calls f(0) ... f(n-1), up to dop at once, or all at once if dop is
+// -1, and waits for them to return
+func parallel(dop, n int, f func(int)) {
+	if dop < 0 || dop > n {
+		dop = n
+	}
+
+	sem := make(chan struct{}, dop)
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-sem }()
+			defer wg.Done()
+			f(i)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// prefetch fetches the next page of the current partition key range and,
+// within the maximum degree of parallelism, the first page of each following
+// range which has not already been fetched
+func (q *crossPartitionQuery) prefetch(ctx context.Context, maxItemCount int) {
+	indexes := []int{q.index}
+	continuations := []string{q.continuation}
+
+	dop := q.options.maxDegreeOfParallelism()
+	for i := q.index + 1; i < len(q.ranges) && (dop < 0 || len(indexes) < dop); i++ {
+		if q.prefetched[q.ranges[i].MinInclusive] == nil {
+			indexes = append(indexes, i)
+			continuations = append(continuations, q.inherited[q.ranges[i].MinInclusive])
+		}
+	}
+
+	pages := make([]*prefetchedPage, len(indexes))
+	parallel(q.options.maxDegreeOfParallelism(), len(indexes), func(j int) {
+		p := &prefetchedPage{}
+		p.page, p.continuation, p.err = q.fetchRange(ctx, q.ranges[indexes[j]], continuations[j], maxItemCount)
+		pages[j] = p
+	})
+
+	if q.prefetched == nil {
+		q.prefetched = map[string]*prefetchedPage{}
+	}
+	for j, i := range indexes {
+		q.prefetched[q.ranges[i].MinInclusive] = pages[j]
+	}
+}
+
+// fetchPage returns the next non-empty page of results from the current
+// partition key range onwards, or nil when all ranges are exhausted
+func (q *crossPartitionQuery) fetchPage(ctx context.Context, maxItemCount int) (*queryPage, error) {
+	for q.index < len(q.ranges) {
+		lower := q.ranges[q.index].MinInclusive
+		if q.prefetched[lower] == nil {
+			q.prefetch(ctx, maxItemCount)
+		}
+		p := q.prefetched[lower]
+		delete(q.prefetched, lower)
+
+		page, continuation, err := p.page, p.continuation, p.err
+		if isPartitionKeyRangeGone(err) {
+			err = q.split(ctx, err)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		q.continuation = continuation
+		if q.continuation == "" {
+			q.index++
+			if q.index < len(q.ranges) {
+				q.continuation = q.inherited[q.ranges[q.index].MinInclusive]
+				delete(q.inherited, q.ranges[q.index].MinInclusive)
+			}
+		}
+
+		if page != nil && len(page.Documents) > 0 {
+			return page, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// mergeOrderBy returns the next page of merged ORDER BY results.  Without a
+// maximum item count, it returns the results which can be merged before
+// another range must be fetched.
+func (q *crossPartitionQuery) mergeOrderBy(ctx context.Context, maxItemCount int) (*queryPage, error) {
+	page := &queryPage{}
+
+	for maxItemCount <= 0 || len(page.Documents) < maxItemCount {
+		if q.options.maxDegreeOfParallelism() != 1 {
+			err := q.fillOrderByRanges(ctx, maxItemCount)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// every range must have a result buffered to know which sorts first
+		for i := 0; i < len(q.orderBy.ranges); i++ {
+			for len(q.orderBy.ranges[i].results) == 0 && !q.orderBy.ranges[i].done {
+				if maxItemCount <= 0 && len(page.Documents) > 0 {
+					return page, nil
+				}
+
+				err := q.fetchOrderByRange(ctx, q.orderBy.ranges[i], maxItemCount)
+				if isPartitionKeyRangeGone(err) {
+					err = q.splitOrderByRange(ctx, i, err)
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		r := q.orderBy.next()
+		if r == nil {
+			break
+		}
+
+		page.Documents = append(page.Documents, r.results[0].payload)
+		r.results = r.results[1:]
+	}
+
+	if len(page.Documents) == 0 {
+		return nil, nil
+	}
+
+	return page, nil
+}
+
+func (q *crossPartitionQuery) fetchOrderByRange(ctx context.Context, r *orderByRange, maxItemCount int) error {
+	page, continuation, err := q.fetchRange(ctx, r.pkr, r.continuation, maxItemCount)
+	if err != nil {
+		return err
+	}
+
+	r.continuation = continuation
+	r.done = continuation == ""
+
+	if page == nil {
+		return nil
+	}
+
+	for _, doc := range page.Documents {
+		result, err := decodeOrderByResult(doc)
+		if err != nil {
+			return err
+		}
+		r.results = append(r.results, result)
+	}
+
+	return nil
+}
+
+// fillOrderByRanges concurrently fetches each ORDER BY range which has no
+// results buffered.  Ranges which are found to be gone are left to be split
+// when they are fetched again.
+func (q *crossPartitionQuery) fillOrderByRanges(ctx context.Context, maxItemCount int) error {
+	var ranges []*orderByRange
+	for _, r := range q.orderBy.ranges {
+		if len(r.results) == 0 && !r.done {
+			ranges = append(ranges, r)
+		}
+	}
+
+	errs := make([]error, len(ranges))
+	parallel(q.options.maxDegreeOfParallelism(), len(ranges), func(i int) {
+		errs[i] = q.fetchOrderByRange(ctx, ranges[i], maxItemCount)
+	})
+
+	for _, err := range errs {
+		if err != nil && !isPartitionKeyRangeGone(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitOrderByRange replaces the ORDER BY range at index i, which has been
+// found to be gone, with its child ranges, each of which inherits its
+// continuation
+func (q *crossPartitionQuery) splitOrderByRange(ctx context.Context, i int, err error) error {
+	parent := q.orderBy.ranges[i]
+
+	children, err := q.childRanges(ctx, parent.pkr, err)
+	if err != nil {
+		return err
+	}
+
+	q.getLogger().Warn("partition key range is gone: continuing on its children", "path", q.path, "partitionKeyRange", parent.pkr.ID, "children", len(children))
+
+	ranges := make([]*orderByRange, 0, len(q.orderBy.ranges)+len(children)-1)
+	ranges = append(ranges, q.orderBy.ranges[:i]...)
+	for _, child := range children {
+		ranges = append(ranges, &orderByRange{pkr: child, continuation: parent.continuation})
+	}
+	ranges = append(ranges, q.orderBy.ranges[i+1:]...)
+	q.orderBy.ranges = ranges
+
+	return nil
+}
+
+// aggregate drains every partition key range on its first call, and then
+// returns the combined results a page at a time
+func (q *crossPartitionQuery) aggregate(ctx context.Context, maxItemCount int) (*queryPage, error) {
+	if !q.aggregated {
+		for {
+			page, err := q.fetchPage(ctx, maxItemCount)
+			if err != nil {
+				return nil, err
+			}
+			if page == nil {
+				break
+			}
+
+			for _, doc := range page.Documents {
+				err = q.aggregator.add(doc)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		var err error
+		q.results, err = q.aggregator.results()
+		if err != nil {
+			return nil, err
+		}
+
+		q.aggregated = true
+	}
+
+	if len(q.results) == 0 {
+		return nil, nil
+	}
+
+	n := len(q.results)
+	if maxItemCount > 0 && maxItemCount < n {
+		n = maxItemCount
+	}
+
+	page := &queryPage{Documents: q.results[:n]}
+	q.results = q.results[n:]
+
+	return page, nil
+}
+
+// nextRaw decodes the next non-empty page of results into raw.  raw is left
+// untouched when all partition key ranges are exhausted.
+func (q *crossPartitionQuery) nextRaw(ctx context.Context, maxItemCount int, raw interface{}) error {
+	if q.options != nil && q.options.MaxItemCount != 0 {
+		maxItemCount = q.options.MaxItemCount
+	}
+
+	page, err := q.nextPage(ctx, maxItemCount)
+	if err != nil || page == nil {
+		return err
+	}
+
+	return q.decodePage(page, raw)
+}
+
+// nextDocument decodes the next result into out, returning false when all
+// partition key ranges are exhausted
+func (q *crossPartitionQuery) nextDocument(ctx context.Context, out interface{}) (bool, error) {
+	maxItemCount := -1
+	if q.options != nil && q.options.MaxItemCount != 0 {
+		maxItemCount = q.options.MaxItemCount
+	}
+
+	for len(q.pending) == 0 {
+		page, err := q.nextPage(ctx, maxItemCount)
+		if err != nil || page == nil {
+			return false, err
+		}
+		q.pending = page.Documents
+	}
+
+	doc := q.pending[0]
+	q.pending = q.pending[1:]
+
+	return true, q.decode(doc, out)
+}
+
+// Continuation returns the continuation of the query, or the empty string if
+// all partition key ranges are exhausted or the query cannot be resumed
+func (q *crossPartitionQuery) Continuation() string {
+	if q.loaded && (q.index >= len(q.ranges) || !q.resumable) {
+		return ""
+	}
+
+	if !q.loaded {
+		if q.resume == nil {
+			return ""
+		}
+		b, _ := json.Marshal(q.resume)
+		return string(b)
+	}
+
+	b, _ := json.Marshal(&crossPartitionContinuation{
+		MinInclusive: q.ranges[q.index].MinInclusive,
+		Continuation: q.continuation,
+		Inherited:    q.inherited,
+	})
+	return string(b)
+}
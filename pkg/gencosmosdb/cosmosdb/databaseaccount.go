@@ -0,0 +1,75 @@
+package cosmosdb
+
+import (
+	"context"
+	"net/http"
+)
+
+// DatabaseAccount represents a database account
+type DatabaseAccount struct {
+	MissingFields
+
+	ID                           string                     `json:"id,omitempty"`
+	ResourceID                   string                     `json:"_rid,omitempty"`
+	Self                         string                     `json:"_self,omitempty"`
+	Media                        string                     `json:"media,omitempty"`
+	Addresses                    string                     `json:"addresses,omitempty"`
+	Databases                    string                     `json:"_dbs,omitempty"`
+	WritableLocations            []*DatabaseAccountLocation `json:"writableLocations,omitempty"`
+	ReadableLocations            []*DatabaseAccountLocation `json:"readableLocations,omitempty"`
+	EnableMultipleWriteLocations bool                       `json:"enableMultipleWriteLocations,omitempty"`
+	UserReplicationPolicy        *ReplicationPolicy         `json:"userReplicationPolicy,omitempty"`
+	SystemReplicationPolicy      *ReplicationPolicy         `json:"systemReplicationPolicy,omitempty"`
+	UserConsistencyPolicy        *ConsistencyPolicy         `json:"userConsistencyPolicy,omitempty"`
+	ReadPolicy                   *ReadPolicy                `json:"readPolicy,omitempty"`
+	QueryEngineConfiguration     string                     `json:"queryEngineConfiguration,omitempty"`
+	Capabilities                 []*Capability              `json:"capabilities,omitempty"`
+}
+
+// DatabaseAccountLocation represents a database account location
+type DatabaseAccountLocation struct {
+	Name                    string `json:"name,omitempty"`
+	DatabaseAccountEndpoint string `json:"databaseAccountEndpoint,omitempty"`
+}
+
+// ReplicationPolicy represents a replication policy
+type ReplicationPolicy struct {
+	MinReplicaSetSize int `json:"minReplicaSetSize,omitempty"`
+	MaxReplicaSetSize int `json:"maxReplicasetSize,omitempty"`
+}
+
+// ConsistencyPolicy represents a consistency policy
+type ConsistencyPolicy struct {
+	DefaultConsistencyLevel       ConsistencyLevel `json:"defaultConsistencyLevel,omitempty"`
+	MaxStalenessPrefix            int              `json:"maxStalenessPrefix,omitempty"`
+	MaxStalenessIntervalInSeconds int              `json:"maxIntervalInSeconds,omitempty"`
+}
+
+// ConsistencyLevel represents a consistency level
+type ConsistencyLevel string
+
+// ConsistencyLevel constants
+const (
+	ConsistencyLevelStrong           ConsistencyLevel = "Strong"
+	ConsistencyLevelBoundedStaleness ConsistencyLevel = "BoundedStaleness"
+	ConsistencyLevelSession          ConsistencyLevel = "Session"
+	ConsistencyLevelConsistentPrefix ConsistencyLevel = "ConsistentPrefix"
+	ConsistencyLevelEventual         ConsistencyLevel = "Eventual"
+)
+
+// ReadPolicy represents a read policy
+type ReadPolicy struct {
+	PrimaryReadCoefficient   int `json:"primaryReadCoefficient,omitempty"`
+	SecondaryReadCoefficient int `json:"secondaryReadCoefficient,omitempty"`
+}
+
+// Capability represents a capability enabled on a database account
+type Capability struct {
+	Name string `json:"name,omitempty"`
+}
+
+// GetDatabaseAccount returns the database account
+func (c *databaseClient) GetDatabaseAccount(ctx context.Context) (account *DatabaseAccount, err error) {
+	err = c.do(ctx, http.MethodGet, "", "", "", http.StatusOK, nil, &account, nil, nil)
+	return
+}
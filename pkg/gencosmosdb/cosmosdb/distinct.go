@@ -0,0 +1,69 @@
+package cosmosdb
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+)
+
+// distinctFilter removes duplicate results of a DISTINCT query which are
+// returned by different partition key ranges.  Results are compared by a hash
+// of their canonical JSON encoding.  An ordered filter only remembers the
+// last result, since duplicates of an ordered stream are adjacent; an
+// unordered filter remembers every result seen.
+type distinctFilter struct {
+	ordered bool
+	last    *[sha256.Size]byte
+	seen    map[[sha256.Size]byte]struct{}
+}
+
+func newDistinctFilter(ordered bool) *distinctFilter {
+	return &distinctFilter{
+		ordered: ordered,
+		seen:    map[[sha256.Size]byte]struct{}{},
+	}
+}
+
+// filter returns the documents of docs which have not been seen before
+func (f *distinctFilter) filter(docs []json.RawMessage) ([]json.RawMessage, error) {
+	filtered := docs[:0]
+
+	for _, doc := range docs {
+		hash, err := distinctHash(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.ordered {
+			if f.last != nil && *f.last == hash {
+				continue
+			}
+			f.last = &hash
+		} else {
+			if _, found := f.seen[hash]; found {
+				continue
+			}
+			f.seen[hash] = struct{}{}
+		}
+
+		filtered = append(filtered, doc)
+	}
+
+	return filtered, nil
+}
+
+// distinctHash hashes the canonical encoding of doc, in which object keys are
+// sorted and numbers are normalised
+func distinctHash(doc json.RawMessage) ([sha256.Size]byte, error) {
+	var v interface{}
+	err := json.Unmarshal(doc, &v)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	return sha256.Sum256(b), nil
+}
@@ -1,13 +1,28 @@
 package cosmosdb
 
+import (
+	"context"
+	"net/http"
+)
+
 // Query represents a query
 type Query struct {
 	Query      string      `json:"query,omitempty"`
 	Parameters []Parameter `json:"parameters,omitempty"`
 }
 
-// Parameter represents a parameter
+// Parameter represents a parameter.  Value may be of any type which encodes to
+// JSON.
 type Parameter struct {
-	Name  string `json:"name,omitempty"`
-	Value string `json:"value,omitempty"`
+	Name  string      `json:"name,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// doQuery POSTs query to the feed at path and decodes the resulting page into
+// out
+func (c *databaseClient) doQuery(ctx context.Context, path, resourceType, resourceLink string, query *Query, out interface{}, headers http.Header, options *Options) error {
+	headers.Set("X-Ms-Documentdb-Isquery", "True")
+	headers.Set("Content-Type", "application/query+json")
+
+	return c.do(ctx, http.MethodPost, path, resourceType, resourceLink, http.StatusOK, &query, out, headers, options)
 }
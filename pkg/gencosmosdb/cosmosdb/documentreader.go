@@ -0,0 +1,101 @@
+package cosmosdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// documentReader decodes the documents of a page one at a time as they are
+// read from a response body, so that the page as a whole is never held in
+// memory.  The page envelope is walked token by token, and each document is
+// decoded with the client's Serializer.
+type documentReader struct {
+	body       io.ReadCloser
+	dec        *json.Decoder
+	serializer Serializer
+}
+
+// newDocumentReader returns a documentReader positioned at the first document
+// of the page in body
+func newDocumentReader(body io.ReadCloser, serializer Serializer) (*documentReader, error) {
+	r := &documentReader{
+		body:       body,
+		dec:        json.NewDecoder(body),
+		serializer: serializer,
+	}
+
+	err := r.seek()
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// seek advances r to the first element of the Documents array
+func (r *documentReader) seek() error {
+	err := r.expect(json.Delim('{'))
+	if err != nil {
+		return err
+	}
+
+	for r.dec.More() {
+		t, err := r.dec.Token()
+		if err != nil {
+			return err
+		}
+
+		if t == "Documents" {
+			return r.expect(json.Delim('['))
+		}
+
+		var skip json.RawMessage
+		err = r.dec.Decode(&skip)
+		if err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("page has no Documents")
+}
+
+func (r *documentReader) expect(delim json.Delim) error {
+	t, err := r.dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if t != delim {
+		return fmt.Errorf("unexpected token %v", t)
+	}
+
+	return nil
+}
+
+// next decodes the next document into out.  It returns false once the page is
+// exhausted; the body is then closed, as it is on error.
+func (r *documentReader) next(out interface{}) (bool, error) {
+	if !r.dec.More() {
+		return false, r.Close()
+	}
+
+	var doc json.RawMessage
+	err := r.dec.Decode(&doc)
+	if err == nil {
+		err = r.serializer.Decode(bytes.NewReader(doc), out)
+	}
+	if err != nil {
+		r.Close()
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Close closes the underlying response body
+func (r *documentReader) Close() error {
+	return r.body.Close()
+}
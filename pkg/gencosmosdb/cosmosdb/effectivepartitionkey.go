@@ -0,0 +1,320 @@
+package cosmosdb
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"unicode/utf16"
+)
+
+// Partition key component type markers, used when partition keys are encoded
+// for hashing
+const (
+	partitionKeyComponentTypeNumber = 0x05
+	partitionKeyComponentTypeString = 0x08
+)
+
+// effectivePartitionKeyV2Length is the length of an effective partition key
+// under version 2 of the hash algorithm, and of each component of the
+// effective partition key of a hierarchical partition key
+const effectivePartitionKeyV2Length = 32
+
+// maxPartitionKeyStringLength is the number of characters of a string
+// partition key which are hashed by version 1 of the hash algorithm
+const maxPartitionKeyStringLength = 100
+
+// EffectivePartitionKey returns the effective partition key of partitionkey,
+// a partition key value or one returned by HierarchicalPartitionKey, in a
+// collection with the partition key definition def.  A document belongs to
+// the partition key range whose bounds contain its effective partition key.
+//
+// The effective partition key of a prefix of a hierarchical partition key is
+// a prefix of those of the partition keys which extend it.
+func EffectivePartitionKey(def *PartitionKey, partitionkey string) (string, error) {
+	if def == nil || len(def.Paths) == 0 {
+		return "", ErrInvalidPartitionKey
+	}
+
+	var values []string
+	err := json.Unmarshal([]byte(encodePartitionKey(partitionkey)), &values)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case def.Kind == PartitionKeyKindMultiHash:
+		if len(values) == 0 || len(values) > len(def.Paths) {
+			return "", ErrInvalidPartitionKey
+		}
+
+		var epk string
+		for _, value := range values {
+			epk += effectivePartitionKeyV2(value)
+		}
+		return epk, nil
+
+	case len(values) != 1:
+		return "", ErrInvalidPartitionKey
+
+	case def.Version == 2:
+		return effectivePartitionKeyV2(values[0]), nil
+
+	default:
+		return effectivePartitionKeyV1(values[0]), nil
+	}
+}
+
+// effectivePartitionKeyV1 returns the effective partition key of value under
+// version 1 of the hash algorithm: the binary encoding of the 32-bit
+// MurmurHash3 of value followed by value itself
+func effectivePartitionKeyV1(value string) string {
+	// strings are truncated to a number of UTF-16 code units
+	if units := utf16.Encode([]rune(value)); len(units) > maxPartitionKeyStringLength {
+		value = string(utf16.Decode(units[:maxPartitionKeyStringLength]))
+	}
+
+	b := make([]byte, 0, len(value)+2)
+	b = append(b, partitionKeyComponentTypeString)
+	b = append(b, value...)
+	b = append(b, 0x00)
+
+	epk := appendEncodedNumber(nil, float64(murmurHash3x86_32(b, 0)))
+	epk = appendEncodedString(epk, value)
+
+	return fmt.Sprintf("%X", epk)
+}
+
+// effectivePartitionKeyV2 returns the effective partition key of value under
+// version 2 of the hash algorithm: its 128-bit MurmurHash3, less the top two
+// bits so that it sorts below the maximum effective partition key, "FF"
+func effectivePartitionKeyV2(value string) string {
+	b := make([]byte, 0, len(value)+2)
+	b = append(b, partitionKeyComponentTypeString)
+	b = append(b, value...)
+	b = append(b, 0xff)
+
+	h1, h2 := murmurHash3x64_128(b, 0)
+
+	hash := make([]byte, 16)
+	binary.BigEndian.PutUint64(hash, h2)
+	binary.BigEndian.PutUint64(hash[8:], h1)
+	hash[0] &= 0x3f
+
+	return fmt.Sprintf("%X", hash)
+}
+
+// appendEncodedNumber appends the order-preserving binary encoding of the
+// number component f to b
+func appendEncodedNumber(b []byte, f float64) []byte {
+	payload := math.Float64bits(f)
+	if payload&(1<<63) == 0 {
+		payload ^= 1 << 63
+	} else {
+		payload = ^payload
+	}
+
+	b = append(b, partitionKeyComponentTypeNumber, byte(payload>>56))
+	payload <<= 8
+
+	// the remaining bits are written seven to a byte, the low bit of each
+	// byte but the last being set
+	for {
+		next := byte(payload>>56) | 0x01
+		payload <<= 7
+		if payload == 0 {
+			return append(b, next&0xfe)
+		}
+		b = append(b, next)
+	}
+}
+
+// appendEncodedString appends the order-preserving binary encoding of the
+// string component s to b
+func appendEncodedString(b []byte, s string) []byte {
+	b = append(b, partitionKeyComponentTypeString)
+
+	short := len(s) <= maxPartitionKeyStringLength
+	n := len(s)
+	if !short {
+		n = maxPartitionKeyStringLength + 1
+	}
+
+	for i := 0; i < n; i++ {
+		c := s[i]
+		if c < 0xff {
+			c++
+		}
+		b = append(b, c)
+	}
+
+	if short {
+		b = append(b, 0x00)
+	}
+
+	return b
+}
+
+// murmurHash3x86_32 returns the 32-bit x86 variant of MurmurHash3 of b
+func murmurHash3x86_32(b []byte, seed uint32) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	h := seed
+	n := len(b) / 4 * 4
+
+	for i := 0; i < n; i += 4 {
+		k := binary.LittleEndian.Uint32(b[i:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	switch len(b) - n {
+	case 3:
+		k ^= uint32(b[n+2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(b[n+1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(b[n])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(b))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+
+	return h
+}
+
+// murmurHash3x64_128 returns the two halves of the 128-bit x64 variant of
+// MurmurHash3 of b
+func murmurHash3x64_128(b []byte, seed uint64) (uint64, uint64) {
+	const (
+		c1 = 0x87c37b91114253d5
+		c2 = 0x4cf5ad432745937f
+	)
+
+	h1, h2 := seed, seed
+	n := len(b) / 16 * 16
+
+	for i := 0; i < n; i += 16 {
+		k1 := binary.LittleEndian.Uint64(b[i:])
+		k2 := binary.LittleEndian.Uint64(b[i+8:])
+
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	var k1, k2 uint64
+	tail := b[n:]
+	switch len(tail) {
+	case 15:
+		k2 ^= uint64(tail[14]) << 48
+		fallthrough
+	case 14:
+		k2 ^= uint64(tail[13]) << 40
+		fallthrough
+	case 13:
+		k2 ^= uint64(tail[12]) << 32
+		fallthrough
+	case 12:
+		k2 ^= uint64(tail[11]) << 24
+		fallthrough
+	case 11:
+		k2 ^= uint64(tail[10]) << 16
+		fallthrough
+	case 10:
+		k2 ^= uint64(tail[9]) << 8
+		fallthrough
+	case 9:
+		k2 ^= uint64(tail[8])
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+		fallthrough
+	case 8:
+		k1 ^= uint64(tail[7]) << 56
+		fallthrough
+	case 7:
+		k1 ^= uint64(tail[6]) << 48
+		fallthrough
+	case 6:
+		k1 ^= uint64(tail[5]) << 40
+		fallthrough
+	case 5:
+		k1 ^= uint64(tail[4]) << 32
+		fallthrough
+	case 4:
+		k1 ^= uint64(tail[3]) << 24
+		fallthrough
+	case 3:
+		k1 ^= uint64(tail[2]) << 16
+		fallthrough
+	case 2:
+		k1 ^= uint64(tail[1]) << 8
+		fallthrough
+	case 1:
+		k1 ^= uint64(tail[0])
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+	}
+
+	h1 ^= uint64(len(b))
+	h2 ^= uint64(len(b))
+
+	h1 += h2
+	h2 += h1
+
+	h1 = fmix64(h1)
+	h2 = fmix64(h2)
+
+	h1 += h2
+	h2 += h1
+
+	return h1, h2
+}
+
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+
+	return k
+}
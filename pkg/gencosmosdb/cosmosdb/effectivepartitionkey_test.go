@@ -0,0 +1,104 @@
+package cosmosdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEffectivePartitionKey(t *testing.T) {
+	hash := &PartitionKey{Paths: []string{"/id"}, Kind: PartitionKeyKindHash}
+	hashV2 := &PartitionKey{Paths: []string{"/id"}, Kind: PartitionKeyKindHash, Version: 2}
+	multiHash := &PartitionKey{Paths: []string{"/tenantId", "/userId"}, Kind: PartitionKeyKindMultiHash, Version: 2}
+
+	for _, tt := range []struct {
+		name         string
+		def          *PartitionKey
+		partitionkey string
+		wantEPK      string
+		wantErr      error
+	}{
+		{
+			name:    "v1, empty string",
+			def:     hash,
+			wantEPK: "05C1CF33970FF80800",
+		},
+		{
+			name:    "v2, empty string",
+			def:     hashV2,
+			wantEPK: "32E9366E637A71B4E710384B2F4970A0",
+		},
+		{
+			name:         "v2",
+			def:          hashV2,
+			partitionkey: "redmond",
+			wantEPK:      "22E342F38A486A088463DFF7838A5963",
+		},
+		{
+			name:         "v2, single value hierarchical partition key",
+			def:          hashV2,
+			partitionkey: HierarchicalPartitionKey("redmond"),
+			wantEPK:      "22E342F38A486A088463DFF7838A5963",
+		},
+		{
+			name:         "multihash",
+			def:          multiHash,
+			partitionkey: HierarchicalPartitionKey("", "redmond"),
+			wantEPK:      "32E9366E637A71B4E710384B2F4970A0" + "22E342F38A486A088463DFF7838A5963",
+		},
+		{
+			name:         "multihash prefix",
+			def:          multiHash,
+			partitionkey: "redmond",
+			wantEPK:      "22E342F38A486A088463DFF7838A5963",
+		},
+		{
+			name:         "multihash, too many values",
+			def:          multiHash,
+			partitionkey: HierarchicalPartitionKey("a", "b", "c"),
+			wantErr:      ErrInvalidPartitionKey,
+		},
+		{
+			name:         "hash, hierarchical partition key",
+			def:          hashV2,
+			partitionkey: HierarchicalPartitionKey("a", "b"),
+			wantErr:      ErrInvalidPartitionKey,
+		},
+		{
+			name:    "no definition",
+			wantErr: ErrInvalidPartitionKey,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			epk, err := EffectivePartitionKey(tt.def, tt.partitionkey)
+			if err != tt.wantErr {
+				t.Fatal(err)
+			}
+			if epk != tt.wantEPK {
+				t.Error(epk)
+			}
+		})
+	}
+}
+
+func TestEffectivePartitionKeyV1Truncation(t *testing.T) {
+	// only the first 100 UTF-16 code units of a string are hashed
+	long := strings.Repeat("a", maxPartitionKeyStringLength)
+	if effectivePartitionKeyV1(long) != effectivePartitionKeyV1(long+"b") {
+		t.Error("strings were not truncated")
+	}
+	if effectivePartitionKeyV1(long[1:]) == effectivePartitionKeyV1(long[1:]+"b") {
+		t.Error("string was truncated early")
+	}
+}
+
+func TestEffectivePartitionKeyV2Bounds(t *testing.T) {
+	for _, value := range []string{"", "a", "redmond", strings.Repeat("x", 1000), "é\U0001f600"} {
+		epk := effectivePartitionKeyV2(value)
+		if len(epk) != effectivePartitionKeyV2Length {
+			t.Errorf("%q: %s", value, epk)
+		}
+		if epk >= "40" {
+			t.Errorf("%q: %s is not below 40", value, epk)
+		}
+	}
+}
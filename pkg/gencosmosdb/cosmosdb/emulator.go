@@ -0,0 +1,34 @@
+package cosmosdb
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+)
+
+// Well-known settings of the Cosmos DB emulator
+const (
+	EmulatorHostname  = "localhost:8081"
+	EmulatorMasterKey = "C2y6yDjf5/R+ob0N8A7Cgv30VRDJIWEHLM+4QDU5DE2nQ9nDuVTqobD4b8mGGyPMbIZnqyMsEcaGQy67XIw/Jw=="
+)
+
+// NewEmulatorClient returns a new database client for the Cosmos DB emulator
+// listening on EmulatorHostname.  The emulator's self-signed certificate is
+// not verified, so this client must only be used for local development and
+// testing.
+func NewEmulatorClient(log *logrus.Entry, jsonHandle *codec.JsonHandle) (DatabaseClient, error) {
+	authorizer, err := NewMasterKeyAuthorizer(EmulatorMasterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := NewTransport(&TransportOptions{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+	})
+
+	return NewDatabaseClient(log, &http.Client{Transport: transport}, jsonHandle, EmulatorHostname, authorizer), nil
+}
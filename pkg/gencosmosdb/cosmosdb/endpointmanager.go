@@ -0,0 +1,284 @@
+package cosmosdb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultEndpointRefreshInterval is the interval after which the regional
+// endpoints of a database account are rediscovered
+const DefaultEndpointRefreshInterval = 5 * time.Minute
+
+// endpointUnavailableDuration is the time for which a regional endpoint is
+// skipped after a request to it failed
+const endpointUnavailableDuration = 5 * time.Minute
+
+// SubStatusCodeWriteForbidden is the sub-status code returned with 403
+// Forbidden when a write is sent to a region which does not accept writes
+const SubStatusCodeWriteForbidden = 3
+
+// endpointManager routes requests to the regional endpoints of a database
+// account, in order of preferred region.  It falls back to the global
+// endpoint of the databaseClient if no regional endpoint is known.
+type endpointManager struct {
+	mu               sync.RWMutex
+	c                *databaseClient
+	preferredRegions []string
+	refreshInterval  time.Duration
+	readHostnames    []string
+	writeHostnames   []string
+	multipleWrites   bool
+	unavailable      map[string]time.Time
+	lastRefresh      time.Time
+	refreshing       bool
+}
+
+// EnableEndpointDiscovery discovers the regional endpoints of the database
+// account and routes subsequent requests to them: reads to the readable
+// regions and writes to the write region, each in the order given by
+// preferredRegions.  Regions which are not preferred are used only if no
+// preferred region is available.  The topology is refreshed in the background
+// every DefaultEndpointRefreshInterval.
+//
+// On accounts with multiple write locations, writes are sent to the most
+// preferred write region and tentative writes are allowed.  A request which
+// fails because its region is unavailable is retried once in the next region.
+func (c *databaseClient) EnableEndpointDiscovery(ctx context.Context, preferredRegions []string) error {
+	m := &endpointManager{
+		c:                c,
+		preferredRegions: preferredRegions,
+		refreshInterval:  DefaultEndpointRefreshInterval,
+		unavailable:      map[string]time.Time{},
+	}
+
+	err := m.refresh(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.endpointManager = m
+
+	return nil
+}
+
+func (m *endpointManager) refresh(ctx context.Context) error {
+	account, err := m.c.GetDatabaseAccount(ctx)
+	if err != nil {
+		return err
+	}
+
+	readHostnames := orderLocations(account.ReadableLocations, m.preferredRegions)
+	writeHostnames := orderLocations(account.WritableLocations, m.preferredRegions)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.readHostnames = readHostnames
+	m.writeHostnames = writeHostnames
+	m.multipleWrites = account.EnableMultipleWriteLocations
+	m.lastRefresh = time.Now()
+
+	return nil
+}
+
+// hostname returns the hostname to which a read or write request should be
+// sent, triggering a background refresh if the topology is stale
+func (m *endpointManager) hostname(read bool) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.refreshing && time.Since(m.lastRefresh) > m.refreshInterval {
+		m.refreshing = true
+		m.refreshAsync()
+	}
+
+	// with multiple write locations, writeHostnames holds every write region
+	// in order of preference; otherwise it holds the single write region
+	hostnames := m.writeHostnames
+	if read {
+		hostnames = m.readHostnames
+	}
+
+	cb := m.c.getCircuitBreaker()
+
+	now := time.Now()
+	for _, hostname := range hostnames {
+		if now.After(m.unavailable[hostname]) && cb.available(hostname) {
+			return hostname
+		}
+	}
+
+	if len(hostnames) == 0 {
+		return m.c.databaseHostname
+	}
+
+	return hostnames[0]
+}
+
+// refreshAsync refreshes the topology in the background.  It must be called
+// with m.mu held and m.refreshing set.
+func (m *endpointManager) refreshAsync() {
+	go func() {
+		err := m.refresh(context.Background())
+		if err != nil {
+			m.c.getLogger().Warn("endpoint refresh failed", "error", err)
+		}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		m.refreshing = false
+	}()
+}
+
+// markUnavailable causes hostname to be skipped for a while.  It returns
+// true if another endpoint is available to retry a read or write request on.
+func (m *endpointManager) markUnavailable(hostname string, read, refresh bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.unavailable[hostname] = now.Add(endpointUnavailableDuration)
+
+	if refresh && !m.refreshing {
+		m.refreshing = true
+		m.refreshAsync()
+	}
+
+	hostnames := m.writeHostnames
+	if read {
+		hostnames = m.readHostnames
+	}
+
+	for _, h := range hostnames {
+		if now.After(m.unavailable[h]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// orderLocations returns the hostnames of locations, those in
+// preferredRegions first in order of preference, followed by the rest in
+// their original order
+func orderLocations(locations []*DatabaseAccountLocation, preferredRegions []string) []string {
+	hostnames := make([]string, 0, len(locations))
+	used := make([]bool, len(locations))
+
+	for _, region := range preferredRegions {
+		for i, location := range locations {
+			if !used[i] && normalizeRegion(location.Name) == normalizeRegion(region) {
+				if hostname := locationHostname(location); hostname != "" {
+					hostnames = append(hostnames, hostname)
+				}
+				used[i] = true
+			}
+		}
+	}
+
+	for i, location := range locations {
+		if !used[i] {
+			if hostname := locationHostname(location); hostname != "" {
+				hostnames = append(hostnames, hostname)
+			}
+		}
+	}
+
+	return hostnames
+}
+
+func locationHostname(location *DatabaseAccountLocation) string {
+	u, err := url.Parse(location.DatabaseAccountEndpoint)
+	if err != nil {
+		return ""
+	}
+
+	return u.Host
+}
+
+func normalizeRegion(region string) string {
+	return strings.ToLower(strings.ReplaceAll(region, " ", ""))
+}
+
+// regionFailover returns true if a request to hostname which failed with err
+// should be retried in another region, marking hostname unavailable if so.  A
+// request which could not connect, or a write which the region refused, was
+// not processed, so is always retried.  A request which failed with 503
+// Service Unavailable may have been processed, so is only retried if
+// retriable is true: it is idempotent or the retry policy retries requests
+// which are not.
+func (c *databaseClient) regionFailover(hostname, method string, headers http.Header, retriable bool, err error) bool {
+	c.mu.RLock()
+	m := c.endpointManager
+	c.mu.RUnlock()
+
+	if m == nil || hostname == c.databaseHostname {
+		return false
+	}
+
+	read := isReadRequest(method, headers)
+
+	var opErr *net.OpError
+	switch {
+	case IsErrorStatusCode(err, http.StatusServiceUnavailable) && retriable:
+		return m.markUnavailable(hostname, read, false)
+	case IsErrorStatusCode(err, http.StatusForbidden) && err.(*Error).SubStatusCode == SubStatusCodeWriteForbidden:
+		return m.markUnavailable(hostname, read, true)
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return m.markUnavailable(hostname, read, false)
+	}
+
+	return false
+}
+
+// allowTentativeWrites returns true if a write request may be accepted
+// tentatively by a region of a multiple write location account
+func (c *databaseClient) allowTentativeWrites(method, path string, headers http.Header) bool {
+	c.mu.RLock()
+	m := c.endpointManager
+	c.mu.RUnlock()
+
+	if m == nil || path == "" || isReadRequest(method, headers) {
+		return false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.multipleWrites
+}
+
+// isReadRequest returns true if a request does not modify any resource
+func isReadRequest(method string, headers http.Header) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPost:
+		return headers.Get("X-Ms-Documentdb-Isquery") != ""
+	}
+	return false
+}
+
+// hostname returns the hostname to which a request should be sent
+func (c *databaseClient) hostname(method, path string, headers http.Header) string {
+	c.mu.RLock()
+	m := c.endpointManager
+	c.mu.RUnlock()
+
+	// the database account is always read from the global endpoint
+	if m == nil || path == "" {
+		return c.databaseHostname
+	}
+
+	return m.hostname(isReadRequest(method, headers))
+}
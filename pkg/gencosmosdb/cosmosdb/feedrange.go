@@ -0,0 +1,121 @@
+package cosmosdb
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidFeedRange is returned when a feed range is not a non-empty range
+// of hexadecimal effective partition key values
+var ErrInvalidFeedRange = fmt.Errorf("invalid feed range")
+
+// maxEffectivePartitionKey is the exclusive upper bound of the effective
+// partition key values of a collection
+const maxEffectivePartitionKey = "FF"
+
+// FeedRange represents a range of the effective partition key values of a
+// collection.  The feed ranges of a collection are initially those of its
+// partition key ranges; they can be split further so that each of a number of
+// consumers reads a deterministic share of a change feed or query by setting
+// Options.FeedRange.
+type FeedRange struct {
+	MinInclusive string `json:"min"`
+	MaxExclusive string `json:"max"`
+}
+
+// parseEffectivePartitionKey returns the value of the hexadecimal effective
+// partition key s, right-padded with zeros to width digits
+func parseEffectivePartitionKey(s string, width int) (*big.Int, bool) {
+	s += strings.Repeat("0", width-len(s))
+	if s == "" {
+		return new(big.Int), true
+	}
+	return new(big.Int).SetString(s, 16)
+}
+
+// Split splits r into n contiguous ranges of approximately equal size
+func (r FeedRange) Split(n int) ([]FeedRange, error) {
+	if n < 1 {
+		return nil, ErrInvalidFeedRange
+	}
+
+	width := len(r.MinInclusive)
+	if len(r.MaxExclusive) > width {
+		width = len(r.MaxExclusive)
+	}
+	width += width % 2
+
+	min, ok := parseEffectivePartitionKey(r.MinInclusive, width)
+	if !ok {
+		return nil, ErrInvalidFeedRange
+	}
+	max, ok := parseEffectivePartitionKey(r.MaxExclusive, width)
+	if !ok || min.Cmp(max) >= 0 {
+		return nil, ErrInvalidFeedRange
+	}
+
+	// widen the values until there are enough between min and max
+	size := new(big.Int).Sub(max, min)
+	for size.Cmp(big.NewInt(int64(n))) < 0 {
+		min.Lsh(min, 8)
+		size.Lsh(size, 8)
+		width += 2
+	}
+
+	ranges := make([]FeedRange, n)
+	lower := r.MinInclusive
+	for i := 1; i < n; i++ {
+		bound := new(big.Int).Mul(size, big.NewInt(int64(i)))
+		bound.Div(bound, big.NewInt(int64(n)))
+		bound.Add(bound, min)
+
+		upper := fmt.Sprintf("%0*X", width, bound)
+		ranges[i-1] = FeedRange{MinInclusive: lower, MaxExclusive: upper}
+		lower = upper
+	}
+	ranges[n-1] = FeedRange{MinInclusive: lower, MaxExclusive: r.MaxExclusive}
+
+	return ranges, nil
+}
+
+// overlaps returns true if r overlaps pkr
+func (r *FeedRange) overlaps(pkr PartitionKeyRange) bool {
+	return r.MinInclusive < pkr.MaxExclusive && r.MaxExclusive > pkr.MinInclusive
+}
+
+// scope returns those of ranges which overlap r, or all of them if r is nil
+func (r *FeedRange) scope(ranges []PartitionKeyRange) []PartitionKeyRange {
+	if r == nil {
+		return ranges
+	}
+
+	var scoped []PartitionKeyRange
+	for _, pkr := range ranges {
+		if r.overlaps(pkr) {
+			scoped = append(scoped, pkr)
+		}
+	}
+
+	return scoped
+}
+
+// setHeaders sets the headers which restrict a request against pkr to the
+// part of it which r covers
+func (r *FeedRange) setHeaders(headers http.Header, pkr PartitionKeyRange) {
+	if r == nil || (r.MinInclusive <= pkr.MinInclusive && r.MaxExclusive >= pkr.MaxExclusive) {
+		return
+	}
+
+	min, max := pkr.MinInclusive, pkr.MaxExclusive
+	if r.MinInclusive > min {
+		min = r.MinInclusive
+	}
+	if r.MaxExclusive < max {
+		max = r.MaxExclusive
+	}
+
+	headers.Set("X-Ms-Start-Epk", min)
+	headers.Set("X-Ms-End-Epk", max)
+}
@@ -0,0 +1,146 @@
+package cosmosdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type fileCheckpointStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileCheckpointStore returns a CheckpointStore which stores leases in the
+// JSON file at path.  Updates are serialised within a process only, so the
+// file must not be shared by change feed processors in different processes.
+func NewFileCheckpointStore(path string) CheckpointStore {
+	return &fileCheckpointStore{path: path}
+}
+
+// load returns the leases in the file, keyed by ID
+func (s *fileCheckpointStore) load() (map[string]*Lease, error) {
+	all := map[string]*Lease{}
+
+	b, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, &all)
+	if err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+// save replaces the file with all, atomically so that a crash cannot leave
+// it truncated
+func (s *fileCheckpointStore) save(all map[string]*Lease) error {
+	b, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(s.path+".tmp", b, 0666)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(s.path+".tmp", s.path)
+}
+
+func (s *fileCheckpointStore) ListLeases(ctx context.Context, prefix string) ([]*Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var leases []*Lease
+	for id, lease := range all {
+		if strings.HasPrefix(id, prefix) {
+			leases = append(leases, lease)
+		}
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].ID < leases[j].ID })
+
+	return leases, nil
+}
+
+func (s *fileCheckpointStore) CreateLease(ctx context.Context, lease *Lease) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if all[lease.ID] != nil {
+		return nil
+	}
+
+	all[lease.ID] = updatedLease(lease)
+
+	return s.save(all)
+}
+
+func (s *fileCheckpointStore) ReplaceLease(ctx context.Context, lease *Lease) (*Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := all[lease.ID]
+	if existing == nil {
+		return nil, &Error{StatusCode: http.StatusNotFound}
+	}
+	if existing.ETag != lease.ETag {
+		return nil, &Error{StatusCode: http.StatusPreconditionFailed}
+	}
+
+	all[lease.ID] = updatedLease(lease)
+
+	err = s.save(all)
+	if err != nil {
+		return nil, err
+	}
+
+	return all[lease.ID], nil
+}
+
+func (s *fileCheckpointStore) DeleteLease(ctx context.Context, lease *Lease) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	existing := all[lease.ID]
+	if existing == nil {
+		return &Error{StatusCode: http.StatusNotFound}
+	}
+	if existing.ETag != lease.ETag {
+		return &Error{StatusCode: http.StatusPreconditionFailed}
+	}
+
+	delete(all, lease.ID)
+
+	return s.save(all)
+}
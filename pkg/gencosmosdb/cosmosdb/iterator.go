@@ -0,0 +1,87 @@
+package cosmosdb
+
+import (
+	"context"
+)
+
+// items returns a function which yields each item of the pages returned by
+// next, fetching pages as they are needed, and stops at the first error.  The
+// function has the underlying type of iter.Seq2[T, error], so from Go 1.23 it
+// can be used in a range statement:
+//
+//	for item, err := range i.Items(ctx) {
+//		...
+//	}
+func items[P any, T any](ctx context.Context, next func(context.Context) (*P, error), list func(*P) []T) func(func(T, error) bool) {
+	return func(yield func(T, error) bool) {
+		for {
+			page, err := next(ctx)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			if page == nil {
+				return
+			}
+
+			for _, item := range list(page) {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// forEach calls fn with each item yielded by seq, as returned by items, until
+// seq is exhausted or either returns an error, which it returns.  Only a page
+// of items is held in memory at a time.
+func forEach[T any](seq func(func(T, error) bool), fn func(T) error) error {
+	var err error
+	seq(func(item T, itemErr error) bool {
+		err = itemErr
+		if err == nil {
+			err = fn(item)
+		}
+		return err == nil
+	})
+
+	return err
+}
+
+// stream calls next in a goroutine until it is exhausted, fails or ctx is
+// cancelled, sending each page on the returned channel, which buffers up to
+// buffer pages.  The channel is closed when streaming stops, after which the
+// returned function returns the error which stopped it, if any.  A consumer
+// which stops reading early must cancel ctx to stop the goroutine.
+func stream[P any](ctx context.Context, next func(context.Context) (*P, error), buffer int) (<-chan *P, func() error) {
+	ch := make(chan *P, buffer)
+	done := make(chan struct{})
+	var err error
+
+	go func() {
+		defer close(done)
+		defer close(ch)
+
+		for {
+			var page *P
+			page, err = next(ctx)
+			if err != nil || page == nil {
+				return
+			}
+
+			select {
+			case ch <- page:
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return ch, func() error {
+		<-done
+		return err
+	}
+}
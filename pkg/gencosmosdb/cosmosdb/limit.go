@@ -0,0 +1,100 @@
+package cosmosdb
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ResponseTooLargeError is returned if the body of a response exceeds the
+// maximum set with SetMaxResponseSize.  Continuation is the continuation with
+// which the page was requested, if any, from which a list or query may be
+// resumed with a smaller MaxItemCount.  The continuation of a cross-partition
+// query is instead that returned by its iterator.
+type ResponseTooLargeError struct {
+	MaxResponseSize int64
+	Continuation    string
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response exceeds maximum size of %d bytes", e.MaxResponseSize)
+}
+
+// SetMaxResponseSize limits the size of the response bodies which the client
+// and the clients derived from it decode, so that an unexpectedly large query
+// result cannot exhaust memory.  Documents which are streamed one at a time,
+// e.g. by NextDocument, are not limited.  If maxResponseSize is zero, there is
+// no limit.
+func (c *databaseClient) SetMaxResponseSize(maxResponseSize int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxResponseSize = maxResponseSize
+}
+
+func (c *databaseClient) getMaxResponseSize() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.maxResponseSize
+}
+
+// responseLimit limits the body of a response, failing reads with a
+// ResponseTooLargeError once the limit is exceeded
+type responseLimit struct {
+	io.ReadCloser
+	remaining int64
+	err       *ResponseTooLargeError
+}
+
+// limitResponse limits the body of resp, the response to req, to
+// maxResponseSize bytes.  If resp has a larger Content-Length, its body is not
+// read at all.
+func limitResponse(req *http.Request, resp *http.Response, maxResponseSize int64) *responseLimit {
+	l := &responseLimit{
+		ReadCloser: resp.Body,
+		remaining:  maxResponseSize,
+		err: &ResponseTooLargeError{
+			MaxResponseSize: maxResponseSize,
+			Continuation:    req.Header.Get("X-Ms-Continuation"),
+		},
+	}
+
+	if resp.ContentLength > maxResponseSize {
+		l.remaining = -1
+	}
+
+	resp.Body = l
+
+	return l
+}
+
+func (l *responseLimit) Read(b []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, l.err
+	}
+
+	// read one byte beyond the limit to tell whether it is exceeded
+	if int64(len(b)) > l.remaining+1 {
+		b = b[:l.remaining+1]
+	}
+
+	n, err := l.ReadCloser.Read(b)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return 0, l.err
+	}
+
+	return n, err
+}
+
+// check returns the ResponseTooLargeError of l if its limit was exceeded, as a
+// decoder may wrap the error or replace it with one of its own; otherwise it
+// returns err
+func (l *responseLimit) check(err error) error {
+	if l != nil && l.remaining < 0 {
+		return l.err
+	}
+
+	return err
+}
@@ -0,0 +1,84 @@
+package cosmosdb
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger logs the events of a client, such as retries, failovers and the
+// progress of change feed processors.  Its methods take a message followed by
+// alternating keys and values, e.g. "method", "GET", "attempt", 1, as do
+// those of *slog.Logger, which implements it.
+type Logger interface {
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+}
+
+// SetLogger sets the Logger of the client and of the clients derived from it
+func (c *databaseClient) SetLogger(log Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.log = log
+}
+
+func (c *databaseClient) getLogger() Logger {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.log
+}
+
+// statusCode returns the status code of resp, to be logged, or zero if no
+// response was received
+func statusCode(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+
+	return resp.StatusCode
+}
+
+type logrusLogger struct {
+	entry *logrus.Entry
+}
+
+// NewLogrusLogger returns a Logger which logs to entry, with the keys and
+// values of each event as fields
+func NewLogrusLogger(entry *logrus.Entry) Logger {
+	return &logrusLogger{entry: entry}
+}
+
+func (l *logrusLogger) Info(msg string, args ...interface{}) {
+	l.entry.WithFields(logrusFields(args)).Info(msg)
+}
+
+func (l *logrusLogger) Warn(msg string, args ...interface{}) {
+	l.entry.WithFields(logrusFields(args)).Warn(msg)
+}
+
+// logrusFields returns the alternating keys and values args as fields.  As
+// with slog, a value without a key is logged with the key "!BADKEY".
+func logrusFields(args []interface{}) logrus.Fields {
+	fields := make(logrus.Fields, (len(args)+1)/2)
+
+	for len(args) > 0 {
+		key, ok := args[0].(string)
+		if !ok || len(args) == 1 {
+			fields["!BADKEY"] = args[0]
+			args = args[1:]
+			continue
+		}
+
+		fields[key] = args[1]
+		args = args[2:]
+	}
+
+	return fields
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Info(string, ...interface{}) {}
+func (discardLogger) Warn(string, ...interface{}) {}
@@ -0,0 +1,51 @@
+package cosmosdb
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics receives a measurement of each attempt of each request made by a
+// client, from which it can count requests, throttled requests (status code
+// 429) and request units consumed, and record latencies.  Its arguments are
+// of built-in types only, so that an implementation, such as the Prometheus
+// adapter in github.com/bennerv/go-cosmosdb/pkg/prommetrics, can serve every
+// generated package.
+type Metrics interface {
+	// ObserveRequest is called after each attempt of a request with the
+	// operation it performed, e.g. "Read" or "Query", the type of resource it
+	// addressed, e.g. "docs", its status code, or zero if no response was
+	// received, its duration including the decoding of the response, and its
+	// request charge
+	ObserveRequest(operation, resourceType string, statusCode int, duration time.Duration, requestCharge float64)
+}
+
+// SetMetrics sets the Metrics which receive measurements of the requests of
+// the client and of the clients derived from it
+func (c *databaseClient) SetMetrics(metrics Metrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.metrics = metrics
+}
+
+func (c *databaseClient) getMetrics() Metrics {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.metrics
+}
+
+// observeRequest passes the measurement of an attempt of a request, which
+// started at start, to metrics
+func observeRequest(metrics Metrics, method, path, resourceType string, headers http.Header, start time.Time, resp *http.Response) {
+	var statusCode int
+	var requestCharge float64
+	if resp != nil {
+		statusCode = resp.StatusCode
+		requestCharge, _ = strconv.ParseFloat(resp.Header.Get("X-Ms-Request-Charge"), 64)
+	}
+
+	metrics.ObserveRequest(operationName(method, path, resourceType, headers), resourceType, statusCode, time.Since(start), requestCharge)
+}
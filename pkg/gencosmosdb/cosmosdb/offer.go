@@ -0,0 +1,261 @@
+package cosmosdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Offer represents an offer
+type Offer struct {
+	MissingFields
+
+	ID              string        `json:"id,omitempty"`
+	ResourceID      string        `json:"_rid,omitempty"`
+	Timestamp       int           `json:"_ts,omitempty"`
+	Self            string        `json:"_self,omitempty"`
+	ETag            string        `json:"_etag,omitempty"`
+	OfferVersion    OfferVersion  `json:"offerVersion,omitempty"`
+	OfferType       OfferType     `json:"offerType,omitempty"`
+	Content         *OfferContent `json:"content,omitempty"`
+	Resource        string        `json:"resource,omitempty"`
+	OfferResourceID string        `json:"offerResourceId,omitempty"`
+}
+
+// OfferVersion represents an offer version
+type OfferVersion string
+
+// OfferVersion constants
+const (
+	OfferVersionV2 OfferVersion = "V2"
+)
+
+// OfferType represents an offer type
+type OfferType string
+
+// OfferType constants
+const (
+	OfferTypeInvalid OfferType = "Invalid"
+)
+
+// OfferContent represents the content of an offer
+type OfferContent struct {
+	MissingFields
+
+	OfferThroughput                     int                     `json:"offerThroughput,omitempty"`
+	OfferIsRUPerMinuteThroughputEnabled bool                    `json:"offerIsRUPerMinuteThroughputEnabled,omitempty"`
+	OfferAutopilotSettings              *OfferAutopilotSettings `json:"offerAutopilotSettings,omitempty"`
+}
+
+// OfferAutopilotSettings represents the autoscale settings of an offer
+type OfferAutopilotSettings struct {
+	MaxThroughput int `json:"maxThroughput,omitempty"`
+}
+
+// Throughput represents the throughput to provision for a new database or
+// collection.  At most one of its fields may be set.
+type Throughput struct {
+	// Throughput is the manual provisioned throughput in RU/s
+	Throughput int
+
+	// AutoscaleMaxThroughput is the autoscale maximum throughput in RU/s
+	AutoscaleMaxThroughput int
+}
+
+// setHeaders sets the offer headers corresponding to t
+func (t *Throughput) setHeaders(headers http.Header) error {
+	if t == nil {
+		return nil
+	}
+
+	switch {
+	case t.Throughput != 0 && t.AutoscaleMaxThroughput != 0:
+		return fmt.Errorf("only one of Throughput and AutoscaleMaxThroughput may be set")
+	case t.Throughput != 0:
+		headers.Set("X-Ms-Offer-Throughput", strconv.Itoa(t.Throughput))
+	case t.AutoscaleMaxThroughput != 0:
+		headers.Set("X-Ms-Cosmos-Offer-Autopilot-Settings", fmt.Sprintf(`{"maxThroughput":%d}`, t.AutoscaleMaxThroughput))
+	}
+
+	return nil
+}
+
+// Offers represents offers
+type Offers struct {
+	Count      int      `json:"_count,omitempty"`
+	ResourceID string   `json:"_rid,omitempty"`
+	Offers     []*Offer `json:"Offers,omitempty"`
+}
+
+type offerClient struct {
+	*databaseClient
+}
+
+// OfferClient is an offer client
+type OfferClient interface {
+	List() OfferIterator
+	ListAll(context.Context) (*Offers, error)
+	Get(context.Context, string) (*Offer, error)
+	GetForResource(context.Context, string) (*Offer, error)
+	Replace(context.Context, *Offer) (*Offer, error)
+	SetThroughput(context.Context, string, int) (*Offer, error)
+	SetAutoscaleMaxThroughput(context.Context, string, int) (*Offer, error)
+}
+
+type offerListIterator struct {
+	*offerClient
+	continuation string
+	done         bool
+}
+
+// OfferIterator is an offer iterator
+type OfferIterator interface {
+	Next(context.Context) (*Offers, error)
+	Items(context.Context) func(func(*Offer, error) bool)
+}
+
+// NewOfferClient returns a new offer client
+func NewOfferClient(c DatabaseClient) OfferClient {
+	return &offerClient{
+		databaseClient: c.(*databaseClient),
+	}
+}
+
+func (c *offerClient) all(ctx context.Context, i OfferIterator) (*Offers, error) {
+	alloffers := &Offers{}
+
+	for {
+		offers, err := i.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if offers == nil {
+			break
+		}
+
+		alloffers.Count += offers.Count
+		alloffers.ResourceID = offers.ResourceID
+		alloffers.Offers = append(alloffers.Offers, offers.Offers...)
+	}
+
+	return alloffers, nil
+}
+
+func (c *offerClient) List() OfferIterator {
+	return &offerListIterator{offerClient: c}
+}
+
+func (c *offerClient) ListAll(ctx context.Context) (*Offers, error) {
+	return c.all(ctx, c.List())
+}
+
+func (c *offerClient) Get(ctx context.Context, offerid string) (offer *Offer, err error) {
+	err = c.do(ctx, http.MethodGet, "offers/"+offerid, "offers", strings.ToLower(offerid), http.StatusOK, nil, &offer, nil, nil)
+	return
+}
+
+// GetForResource returns the offer of the database or collection with the
+// given resource ID (_rid)
+func (c *offerClient) GetForResource(ctx context.Context, resourceid string) (*Offer, error) {
+	query := &Query{
+		Query: "SELECT * FROM root WHERE root.offerResourceId = @offerResourceId",
+		Parameters: []Parameter{
+			{
+				Name:  "@offerResourceId",
+				Value: resourceid,
+			},
+		},
+	}
+
+	var offers *Offers
+	err := c.doQuery(ctx, "offers", "offers", "", query, &offers, http.Header{}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if offers == nil || len(offers.Offers) == 0 {
+		return nil, &Error{StatusCode: http.StatusNotFound, Message: "offer not found for resource " + resourceid}
+	}
+
+	return offers.Offers[0], nil
+}
+
+func (c *offerClient) Replace(ctx context.Context, newoffer *Offer) (*Offer, error) {
+	return c.replace(ctx, newoffer, nil)
+}
+
+// SetThroughput sets manual provisioned throughput on the offer of the
+// database or collection with the given resource ID (_rid), migrating it from
+// autoscale if necessary
+func (c *offerClient) SetThroughput(ctx context.Context, resourceid string, throughput int) (*Offer, error) {
+	offer, err := c.GetForResource(ctx, resourceid)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := http.Header{}
+	if offer.Content != nil && offer.Content.OfferAutopilotSettings != nil {
+		headers.Set("X-Ms-Cosmos-Migrate-Offer-To-Manual-Throughput", "true")
+	}
+
+	offer.Content = &OfferContent{
+		OfferThroughput: throughput,
+	}
+
+	return c.replace(ctx, offer, headers)
+}
+
+// SetAutoscaleMaxThroughput sets the autoscale maximum throughput on the offer
+// of the database or collection with the given resource ID (_rid), migrating it
+// from manual provisioned throughput if necessary
+func (c *offerClient) SetAutoscaleMaxThroughput(ctx context.Context, resourceid string, maxThroughput int) (*Offer, error) {
+	offer, err := c.GetForResource(ctx, resourceid)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := http.Header{}
+	if offer.Content == nil || offer.Content.OfferAutopilotSettings == nil {
+		headers.Set("X-Ms-Cosmos-Migrate-Offer-To-Autopilot", "true")
+	}
+
+	offer.Content = &OfferContent{
+		OfferAutopilotSettings: &OfferAutopilotSettings{
+			MaxThroughput: maxThroughput,
+		},
+	}
+
+	return c.replace(ctx, offer, headers)
+}
+
+func (c *offerClient) replace(ctx context.Context, newoffer *Offer, headers http.Header) (offer *Offer, err error) {
+	err = c.do(ctx, http.MethodPut, "offers/"+newoffer.ResourceID, "offers", strings.ToLower(newoffer.ResourceID), http.StatusOK, &newoffer, &offer, headers, nil)
+	return
+}
+
+func (i *offerListIterator) Next(ctx context.Context) (offers *Offers, err error) {
+	if i.done {
+		return
+	}
+
+	headers := http.Header{}
+	if i.continuation != "" {
+		headers.Set("X-Ms-Continuation", i.continuation)
+	}
+
+	err = i.do(ctx, http.MethodGet, "offers", "offers", "", http.StatusOK, nil, &offers, headers, nil)
+	if err != nil {
+		return
+	}
+
+	i.continuation = headers.Get("X-Ms-Continuation")
+	i.done = i.continuation == ""
+
+	return
+}
+
+func (i *offerListIterator) Items(ctx context.Context) func(func(*Offer, error) bool) {
+	return items(ctx, i.Next, func(offers *Offers) []*Offer { return offers.Offers })
+}
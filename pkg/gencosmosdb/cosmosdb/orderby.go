@@ -0,0 +1,116 @@
+package cosmosdb
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// orderByFilterPlaceholder is replaced in the rewritten query of an ORDER BY
+// query by a filter which the client uses to resume after a split
+const orderByFilterPlaceholder = "{documentdb-formattableorderbyquery-filter}"
+
+// undefined represents an undefined ORDER BY item, which sorts first
+type undefined struct{}
+
+// orderByResult is a result of the rewritten query of an ORDER BY query,
+// returned in the form {"orderByItems": [{"item": ...}], "payload": ...}
+type orderByResult struct {
+	items   []interface{}
+	payload json.RawMessage
+}
+
+func decodeOrderByResult(doc json.RawMessage) (*orderByResult, error) {
+	var result struct {
+		OrderByItems []map[string]json.RawMessage `json:"orderByItems"`
+		Payload      json.RawMessage              `json:"payload"`
+	}
+	err := json.Unmarshal(doc, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &orderByResult{
+		items:   make([]interface{}, len(result.OrderByItems)),
+		payload: json.RawMessage(result.Payload),
+	}
+
+	for i, item := range result.OrderByItems {
+		raw, found := item["item"]
+		if !found {
+			r.items[i] = undefined{}
+			continue
+		}
+
+		err = json.Unmarshal(raw, &r.items[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// orderByRange holds the results fetched from a partition key range which
+// have not yet been merged
+type orderByRange struct {
+	pkr          PartitionKeyRange
+	continuation string
+	done         bool
+	results      []*orderByResult
+}
+
+// orderByMerge merges the sorted results of each partition key range of an
+// ORDER BY query
+type orderByMerge struct {
+	descending []bool
+	ranges     []*orderByRange
+}
+
+func newOrderByMerge(orderBy []string, ranges []PartitionKeyRange) *orderByMerge {
+	m := &orderByMerge{
+		descending: make([]bool, len(orderBy)),
+		ranges:     make([]*orderByRange, len(ranges)),
+	}
+
+	for i, order := range orderBy {
+		m.descending[i] = strings.EqualFold(order, "Descending")
+	}
+
+	for i, r := range ranges {
+		m.ranges[i] = &orderByRange{pkr: r}
+	}
+
+	return m
+}
+
+// less returns true if a sorts before b
+func (m *orderByMerge) less(a, b *orderByResult) bool {
+	for i := range a.items {
+		if i >= len(b.items) {
+			break
+		}
+
+		c := compareValues(a.items[i], b.items[i])
+		if i < len(m.descending) && m.descending[i] {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+	}
+
+	return false
+}
+
+// next returns the range whose first result sorts first, or nil if all
+// fetched results have been merged.  Ties are broken in range order.
+func (m *orderByMerge) next() *orderByRange {
+	var next *orderByRange
+	for _, r := range m.ranges {
+		if len(r.results) > 0 && (next == nil || m.less(r.results[0], next.results[0])) {
+			next = r
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,78 @@
+package cosmosdb
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestOrderByMerge(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		orderBy []string
+		ranges  [][]string
+		want    []string
+	}{
+		{
+			name:    "ascending",
+			orderBy: []string{"Ascending"},
+			ranges: [][]string{
+				{`{"orderByItems":[{"item":1}],"payload":"a1"}`, `{"orderByItems":[{"item":4}],"payload":"a4"}`},
+				{`{"orderByItems":[{"item":2}],"payload":"b2"}`, `{"orderByItems":[{"item":3}],"payload":"b3"}`},
+				{},
+			},
+			want: []string{`"a1"`, `"b2"`, `"b3"`, `"a4"`},
+		},
+		{
+			name:    "descending",
+			orderBy: []string{"Descending"},
+			ranges: [][]string{
+				{`{"orderByItems":[{"item":"d"}],"payload":"a-d"}`, `{"orderByItems":[{"item":"a"}],"payload":"a-a"}`},
+				{`{"orderByItems":[{"item":"c"}],"payload":"b-c"}`, `{"orderByItems":[{"item":"b"}],"payload":"b-b"}`},
+			},
+			want: []string{`"a-d"`, `"b-c"`, `"b-b"`, `"a-a"`},
+		},
+		{
+			name:    "mixed types, undefined first",
+			orderBy: []string{"Ascending"},
+			ranges: [][]string{
+				{`{"orderByItems":[{}],"payload":"undefined"}`, `{"orderByItems":[{"item":true}],"payload":"true"}`, `{"orderByItems":[{"item":"s"}],"payload":"string"}`},
+				{`{"orderByItems":[{"item":null}],"payload":"null"}`, `{"orderByItems":[{"item":7}],"payload":"number"}`},
+			},
+			want: []string{`"undefined"`, `"null"`, `"true"`, `"number"`, `"string"`},
+		},
+		{
+			name:    "multiple items, ties broken in range order",
+			orderBy: []string{"Ascending", "Descending"},
+			ranges: [][]string{
+				{`{"orderByItems":[{"item":1},{"item":1}],"payload":"a11"}`, `{"orderByItems":[{"item":2},{"item":2}],"payload":"a22"}`},
+				{`{"orderByItems":[{"item":1},{"item":2}],"payload":"b12"}`, `{"orderByItems":[{"item":2},{"item":2}],"payload":"b22"}`},
+			},
+			want: []string{`"b12"`, `"a11"`, `"a22"`, `"b22"`},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newOrderByMerge(tt.orderBy, make([]PartitionKeyRange, len(tt.ranges)))
+
+			for i, docs := range tt.ranges {
+				for _, doc := range docs {
+					result, err := decodeOrderByResult(json.RawMessage(doc))
+					if err != nil {
+						t.Fatal(err)
+					}
+					m.ranges[i].results = append(m.ranges[i].results, result)
+				}
+			}
+
+			var got []string
+			for r := m.next(); r != nil; r = m.next() {
+				got = append(got, string(r.results[0].payload))
+				r.results = r.results[1:]
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
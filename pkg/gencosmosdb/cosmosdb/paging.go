@@ -0,0 +1,61 @@
+package cosmosdb
+
+import (
+	"fmt"
+)
+
+// ErrInvalidPageRequest is returned if a PageRequest has no PageSize
+var ErrInvalidPageRequest = fmt.Errorf("page size is required")
+
+// PageRequest requests a page of the results of a query, for APIs which
+// expose numbered pages.
+//
+// If Continuation is set, the page is fetched by resuming the query from it.
+// Otherwise, pages after the first are fetched by appending OFFSET ... LIMIT
+// to the query.  This is correct for any query, but its cost grows with the
+// page number and, across partitions, the offset is applied by the client
+// after fetching every preceding result.  The query should include an ORDER
+// BY clause so that numbered pages are stable.
+type PageRequest struct {
+	// Page is the 1-based page number
+	Page     int
+	PageSize int
+
+	// Continuation, if set, is the continuation returned with the previous
+	// page
+	Continuation string
+}
+
+// number returns the 1-based page number of p
+func (p *PageRequest) number() int {
+	if p.Page < 1 {
+		return 1
+	}
+
+	return p.Page
+}
+
+// offsetQuery returns query limited to the page p by OFFSET ... LIMIT.  The
+// query must not already have an OFFSET or LIMIT clause.
+func (p *PageRequest) offsetQuery(query *Query) *Query {
+	return &Query{
+		Query:      fmt.Sprintf("%s OFFSET %d LIMIT %d", query.Query, (p.number()-1)*p.PageSize, p.PageSize),
+		Parameters: query.Parameters,
+	}
+}
+
+// next returns the request for the page following p given the number of
+// results returned and the continuation of the query, or nil if there are no
+// further results.  If the query could not be continued, the next page is
+// requested by number.
+func (p *PageRequest) next(count int, continuation string) *PageRequest {
+	if count < p.PageSize {
+		return nil
+	}
+
+	return &PageRequest{
+		Page:         p.number() + 1,
+		PageSize:     p.PageSize,
+		Continuation: continuation,
+	}
+}
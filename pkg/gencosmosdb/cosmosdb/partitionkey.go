@@ -0,0 +1,60 @@
+package cosmosdb
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// hierarchicalPartitionKeyMarker prefixes the partition keys returned by
+// HierarchicalPartitionKey.  It is not valid UTF-8, so no partition key value,
+// which is a JSON string, can begin with it and be mistaken for one.
+const hierarchicalPartitionKeyMarker = "\xff"
+
+// HierarchicalPartitionKey returns the partition key of a document in a
+// collection with a MultiHash partition key, for passing wherever a partition
+// key is taken.  values are the values of the paths of the partition key, in
+// order.  Only a partition key returned by HierarchicalPartitionKey is treated
+// as hierarchical; any other string is a single value.
+//
+// A query by a prefix of a hierarchical partition key, for which fewer values
+// are passed, is run across the partition key ranges which may hold its
+// results, found from the effective partition key of the prefix, and returns
+// the documents whose partition keys extend the prefix.
+func HierarchicalPartitionKey(values ...string) string {
+	return hierarchicalPartitionKeyMarker + encodePartitionKeyValues(values)
+}
+
+// isHierarchicalPartitionKey returns true if partitionkey was returned by
+// HierarchicalPartitionKey
+func isHierarchicalPartitionKey(partitionkey string) bool {
+	return strings.HasPrefix(partitionkey, hierarchicalPartitionKeyMarker)
+}
+
+// encodePartitionKeyValues returns the JSON array of values
+func encodePartitionKeyValues(values []string) string {
+	b, _ := json.Marshal(values)
+	return string(b)
+}
+
+// encodePartitionKey returns the value of the x-ms-documentdb-partitionkey
+// header for partitionkey
+func encodePartitionKey(partitionkey string) string {
+	if isHierarchicalPartitionKey(partitionkey) {
+		return partitionkey[len(hierarchicalPartitionKeyMarker):]
+	}
+
+	return encodePartitionKeyValues([]string{partitionkey})
+}
+
+// partitionKeyPathExpression returns the query expression for the partition
+// key path, e.g. c["tenantId"] for /tenantId.  Names are quoted as JSON
+// strings, whose escapes the query language shares.
+func partitionKeyPathExpression(path string) string {
+	expression := "c"
+	for _, name := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		b, _ := json.Marshal(name)
+		expression += "[" + string(b) + "]"
+	}
+
+	return expression
+}
@@ -0,0 +1,254 @@
+package cosmosdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// partitionKeyRangeCache caches the partition key ranges of each collection.
+// The ranges of a collection are loaded when they are first needed, and
+// reloaded when a request finds that a cached range has been split or merged
+// away, as indicated by a 410 Gone response.
+type partitionKeyRangeCache struct {
+	mu      sync.Mutex
+	entries map[string]*partitionKeyRangeCacheEntry
+}
+
+// partitionKeyRangeCacheEntry holds the partition key ranges of a collection,
+// sorted by lower bound, and its partition key definition.  mu is held while
+// they are loaded so that concurrent callers wait for a single request.
+type partitionKeyRangeCacheEntry struct {
+	mu         sync.Mutex
+	ranges     []PartitionKeyRange
+	definition *PartitionKey
+}
+
+// entry returns the entry of the collection at path, creating it if needed
+func (c *partitionKeyRangeCache) entry(path string) *partitionKeyRangeCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]*partitionKeyRangeCacheEntry{}
+	}
+
+	e := c.entries[path]
+	if e == nil {
+		e = &partitionKeyRangeCacheEntry{}
+		c.entries[path] = e
+	}
+
+	return e
+}
+
+// invalidate discards the ranges of the collection at path, for example
+// because it has been deleted
+func (c *partitionKeyRangeCache) invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, path)
+}
+
+// loadPartitionKeyRanges fetches the partition key ranges of the collection
+// at path, sorted by lower bound
+func (c *databaseClient) loadPartitionKeyRanges(ctx context.Context, path string, options *Options) ([]PartitionKeyRange, error) {
+	var ranges []PartitionKeyRange
+	var continuation string
+
+	for {
+		headers := http.Header{}
+		if continuation != "" {
+			headers.Set("X-Ms-Continuation", continuation)
+		}
+
+		var pkrs *PartitionKeyRanges
+		err := c.do(ctx, http.MethodGet, path+"/pkranges", "pkranges", path, http.StatusOK, nil, &pkrs, headers, options)
+		if err != nil {
+			return nil, err
+		}
+
+		if pkrs != nil {
+			ranges = append(ranges, pkrs.PartitionKeyRanges...)
+		}
+
+		continuation = headers.Get("X-Ms-Continuation")
+		if continuation == "" {
+			break
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].MinInclusive < ranges[j].MinInclusive })
+
+	return ranges, nil
+}
+
+// partitionKeyRanges returns the partition key ranges of the collection at
+// path, sorted by lower bound, loading them if they are not cached
+func (c *databaseClient) partitionKeyRanges(ctx context.Context, path string, options *Options) ([]PartitionKeyRange, error) {
+	e := c.pkRangeCache.entry(path)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.ranges == nil {
+		ranges, err := c.loadPartitionKeyRanges(ctx, path, options)
+		if err != nil {
+			return nil, err
+		}
+		e.ranges = ranges
+	}
+
+	return append([]PartitionKeyRange(nil), e.ranges...), nil
+}
+
+// refreshPartitionKeyRanges reloads the partition key ranges of the
+// collection at path if the cached ranges still include gone, which a request
+// has found to no longer exist, and returns them
+func (c *databaseClient) refreshPartitionKeyRanges(ctx context.Context, path string, options *Options, gone PartitionKeyRange) ([]PartitionKeyRange, error) {
+	e := c.pkRangeCache.entry(path)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	stale := e.ranges == nil
+	for _, r := range e.ranges {
+		if r.ID == gone.ID {
+			stale = true
+			break
+		}
+	}
+
+	if stale {
+		ranges, err := c.loadPartitionKeyRanges(ctx, path, options)
+		if err != nil {
+			return nil, err
+		}
+		e.ranges = ranges
+	}
+
+	return append([]PartitionKeyRange(nil), e.ranges...), nil
+}
+
+// childPartitionKeyRanges refreshes the partition key ranges of the
+// collection at path and returns those which have replaced parent.  err, the
+// error returned when parent was found to be gone, is returned if there are
+// none.
+func (c *databaseClient) childPartitionKeyRanges(ctx context.Context, path string, options *Options, parent PartitionKeyRange, err error) ([]PartitionKeyRange, error) {
+	ranges, err2 := c.refreshPartitionKeyRanges(ctx, path, options, parent)
+	if err2 != nil {
+		return nil, err2
+	}
+
+	children := (&FeedRange{MinInclusive: parent.MinInclusive, MaxExclusive: parent.MaxExclusive}).scope(ranges)
+
+	if len(children) == 0 || (len(children) == 1 && children[0].ID == parent.ID) {
+		return nil, err
+	}
+
+	return children, nil
+}
+
+// partitionKeyDefinition returns the partition key definition of the
+// collection at path, fetching it if it is not cached
+func (c *databaseClient) partitionKeyDefinition(ctx context.Context, path string, options *Options) (*PartitionKey, error) {
+	e := c.pkRangeCache.entry(path)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.definition == nil {
+		var coll *Collection
+		err := c.do(ctx, http.MethodGet, path, "colls", path, http.StatusOK, nil, &coll, nil, options)
+		if err != nil {
+			return nil, err
+		}
+
+		if coll == nil || coll.PartitionKey == nil {
+			return nil, ErrInvalidPartitionKey
+		}
+		e.definition = coll.PartitionKey
+	}
+
+	return e.definition, nil
+}
+
+// feedRangeOf returns the feed range of the documents of the collection at
+// path with partition key partitionkey or, if it is a prefix of a
+// hierarchical partition key, of those whose partition keys extend it
+func (c *databaseClient) feedRangeOf(ctx context.Context, path string, options *Options, partitionkey string) (*FeedRange, error) {
+	def, err := c.partitionKeyDefinition(ctx, path, options)
+	if err != nil {
+		return nil, err
+	}
+
+	epk, err := EffectivePartitionKey(def, partitionkey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FeedRange{MinInclusive: epk, MaxExclusive: epk + maxEffectivePartitionKey}, nil
+}
+
+// partitionKeyRangeOf returns the current partition key range of the
+// collection at path which contains the effective partition key epk
+func (c *databaseClient) partitionKeyRangeOf(ctx context.Context, path string, options *Options, epk string) (PartitionKeyRange, error) {
+	ranges, err := c.partitionKeyRanges(ctx, path, options)
+	if err != nil {
+		return PartitionKeyRange{}, err
+	}
+
+	return searchPartitionKeyRanges(ranges, epk)
+}
+
+// searchPartitionKeyRanges returns the range of ranges, sorted by lower bound,
+// which contains the effective partition key epk
+func searchPartitionKeyRanges(ranges []PartitionKeyRange, epk string) (PartitionKeyRange, error) {
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].MaxExclusive > epk })
+	if i == len(ranges) || ranges[i].MinInclusive > epk {
+		return PartitionKeyRange{}, ErrInvalidFeedRange
+	}
+
+	return ranges[i], nil
+}
+
+// prefixQuery returns a cross-partition query of query restricted to the feed
+// range of partitionkey if it is a prefix of the hierarchical partition key of
+// the collection at path, as its documents may span partition key ranges, or
+// nil if it is not
+func (c *databaseClient) prefixQuery(ctx context.Context, path, partitionkey string, query *Query, options *Options, continuation string) (*crossPartitionQuery, error) {
+	if !isHierarchicalPartitionKey(partitionkey) || options != nil && (options.PartitionKeyRangeID != "" || options.FeedRange != nil) {
+		return nil, nil
+	}
+
+	def, err := c.partitionKeyDefinition(ctx, path, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	err = json.Unmarshal([]byte(encodePartitionKey(partitionkey)), &values)
+	if err != nil {
+		return nil, err
+	}
+
+	if def.Kind != PartitionKeyKindMultiHash || len(values) >= len(def.Paths) {
+		return nil, nil
+	}
+
+	feedRange, err := c.feedRangeOf(ctx, path, options, partitionkey)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := Options{}
+	if options != nil {
+		opts = *options
+	}
+	opts.FeedRange = feedRange
+
+	return c.newCrossPartitionQuery(path, query, &opts, continuation), nil
+}
@@ -0,0 +1,322 @@
+package cosmosdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// patchAPIVersion is the API version which introduced partial document update
+const patchAPIVersion = "2020-07-15"
+
+// PatchOperationType represents a patch operation type
+type PatchOperationType string
+
+// PatchOperationType constants
+const (
+	PatchOperationTypeAdd       PatchOperationType = "add"
+	PatchOperationTypeSet       PatchOperationType = "set"
+	PatchOperationTypeReplace   PatchOperationType = "replace"
+	PatchOperationTypeRemove    PatchOperationType = "remove"
+	PatchOperationTypeIncrement PatchOperationType = "incr"
+	PatchOperationTypeMove      PatchOperationType = "move"
+)
+
+// PatchOperation represents an operation of a partial document update.  Path
+// and From are JSON Pointers, e.g. "/address/city".
+type PatchOperation struct {
+	Op    PatchOperationType `json:"op"`
+	Path  string             `json:"path"`
+	From  string             `json:"from,omitempty"`
+	Value interface{}        `json:"value"`
+}
+
+// MarshalJSON encodes o.  Value is omitted only from the operations which take
+// none, so that a nil Value sets, adds or replaces a value with null.
+func (o PatchOperation) MarshalJSON() ([]byte, error) {
+	type operation struct {
+		Op   PatchOperationType `json:"op"`
+		Path string             `json:"path"`
+		From string             `json:"from,omitempty"`
+	}
+
+	if o.Op == PatchOperationTypeRemove || o.Op == PatchOperationTypeMove {
+		return json.Marshal(&operation{Op: o.Op, Path: o.Path, From: o.From})
+	}
+
+	return json.Marshal(&struct {
+		operation
+		Value interface{} `json:"value"`
+	}{
+		operation: operation{Op: o.Op, Path: o.Path, From: o.From},
+		Value:     o.Value,
+	})
+}
+
+// UnmarshalJSON decodes o.  Codecs such as ugorji's only call MarshalJSON on
+// types which also implement UnmarshalJSON.
+func (o *PatchOperation) UnmarshalJSON(b []byte) error {
+	type patchOperation PatchOperation
+	return json.Unmarshal(b, (*patchOperation)(o))
+}
+
+// patchRequest is the body of a PATCH request
+type patchRequest struct {
+	Condition  string           `json:"condition,omitempty"`
+	Operations []PatchOperation `json:"operations"`
+}
+
+// applyPatch applies operations to doc, a document decoded by encoding/json
+// with UseNumber, and returns the result
+func applyPatch(doc interface{}, operations []PatchOperation) (interface{}, error) {
+	for _, op := range operations {
+		value, err := normalizePatchValue(op.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		path, err := parsePatchPath(op.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.Op {
+		case PatchOperationTypeAdd, PatchOperationTypeSet:
+			insert := op.Op == PatchOperationTypeAdd
+			doc, err = patchContainer(doc, path, func(container interface{}, key string) (interface{}, error) {
+				return patchPut(container, key, value, insert)
+			})
+
+		case PatchOperationTypeReplace:
+			doc, err = patchContainer(doc, path, func(container interface{}, key string) (interface{}, error) {
+				_, err := patchGet(container, key)
+				if err != nil {
+					return nil, err
+				}
+				return patchPut(container, key, value, false)
+			})
+
+		case PatchOperationTypeRemove:
+			doc, err = patchContainer(doc, path, patchRemove)
+
+		case PatchOperationTypeIncrement:
+			doc, err = patchContainer(doc, path, func(container interface{}, key string) (interface{}, error) {
+				existing, err := patchGet(container, key)
+				if err == nil {
+					value, err = addPatchNumbers(existing, value)
+					if err != nil {
+						return nil, err
+					}
+				}
+				return patchPut(container, key, value, false)
+			})
+
+		case PatchOperationTypeMove:
+			var from []string
+			from, err = parsePatchPath(op.From)
+			if err != nil {
+				return nil, err
+			}
+
+			doc, err = patchContainer(doc, from, func(container interface{}, key string) (interface{}, error) {
+				value, err = patchGet(container, key)
+				if err != nil {
+					return nil, err
+				}
+				return patchRemove(container, key)
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			doc, err = patchContainer(doc, path, func(container interface{}, key string) (interface{}, error) {
+				return patchPut(container, key, value, false)
+			})
+
+		default:
+			err = patchError("unsupported operation %q", op.Op)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return doc, nil
+}
+
+func patchError(format string, a ...interface{}) error {
+	return &Error{
+		StatusCode: http.StatusBadRequest,
+		Code:       "BadRequest",
+		Message:    fmt.Sprintf(format, a...),
+	}
+}
+
+// parsePatchPath splits a JSON Pointer into its unescaped reference tokens
+func parsePatchPath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, patchError("invalid path %q", path)
+	}
+
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+// normalizePatchValue round trips value through encoding/json so that it can
+// be navigated by later operations
+func normalizePatchValue(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	err = d.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// patchContainer calls f with the container of the value at path and its
+// key within it, replacing the container with the result
+func patchContainer(v interface{}, path []string, f func(interface{}, string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return f(v, path[0])
+	}
+
+	child, err := patchGet(v, path[0])
+	if err != nil {
+		return nil, err
+	}
+
+	child, err = patchContainer(child, path[1:], f)
+	if err != nil {
+		return nil, err
+	}
+
+	return patchPut(v, path[0], child, false)
+}
+
+func patchIndex(key string, n int) (int, error) {
+	if key == "-" {
+		return n, nil
+	}
+
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || i > n {
+		return 0, patchError("invalid array index %q", key)
+	}
+
+	return i, nil
+}
+
+func patchGet(container interface{}, key string) (interface{}, error) {
+	switch container := container.(type) {
+	case map[string]interface{}:
+		if value, found := container[key]; found {
+			return value, nil
+		}
+	case []interface{}:
+		i, err := patchIndex(key, len(container))
+		if err != nil {
+			return nil, err
+		}
+		if i < len(container) {
+			return container[i], nil
+		}
+	}
+
+	return nil, patchError("path %q does not exist", key)
+}
+
+// patchPut sets the value at key of container.  If insert is true, a value
+// put into an array is inserted before the existing value at key.
+func patchPut(container interface{}, key string, value interface{}, insert bool) (interface{}, error) {
+	switch container := container.(type) {
+	case map[string]interface{}:
+		container[key] = value
+		return container, nil
+
+	case []interface{}:
+		i, err := patchIndex(key, len(container))
+		if err != nil {
+			return nil, err
+		}
+
+		if i == len(container) {
+			return append(container, value), nil
+		}
+		if !insert {
+			container[i] = value
+			return container, nil
+		}
+
+		container = append(container, nil)
+		copy(container[i+1:], container[i:])
+		container[i] = value
+		return container, nil
+	}
+
+	return nil, patchError("path %q is not within an object or array", key)
+}
+
+func patchRemove(container interface{}, key string) (interface{}, error) {
+	_, err := patchGet(container, key)
+	if err != nil {
+		return nil, err
+	}
+
+	switch container := container.(type) {
+	case map[string]interface{}:
+		delete(container, key)
+		return container, nil
+
+	case []interface{}:
+		i, _ := patchIndex(key, len(container))
+		return append(container[:i], container[i+1:]...), nil
+	}
+
+	return container, nil
+}
+
+// addPatchNumbers returns a + b, which must both be json.Numbers.  The result
+// is an integer if a and b are.
+func addPatchNumbers(a, b interface{}) (interface{}, error) {
+	x, ok1 := a.(json.Number)
+	y, ok2 := b.(json.Number)
+	if !ok1 || !ok2 {
+		return nil, patchError("cannot increment a non-numeric value")
+	}
+
+	i, err1 := x.Int64()
+	j, err2 := y.Int64()
+	if err1 == nil && err2 == nil {
+		return json.Number(strconv.FormatInt(i+j, 10)), nil
+	}
+
+	f, err := x.Float64()
+	if err != nil {
+		return nil, err
+	}
+	g, err := y.Float64()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Number(strconv.FormatFloat(f+g, 'g', -1, 64)), nil
+}
@@ -7,14 +7,15 @@ import (
 
 // Permission represents a permission
 type Permission struct {
-	ID             string         `json:"id,omitempty"`
-	ResourceID     string         `json:"_rid,omitempty"`
-	Timestamp      int            `json:"_ts,omitempty"`
-	Self           string         `json:"_self,omitempty"`
-	ETag           string         `json:"_etag,omitempty"`
-	Token          string         `json:"_token,omitempty"`
-	PermissionMode PermissionMode `json:"permissionMode,omitempty"`
-	Resource       string         `json:"resource,omitempty"`
+	ID                   string         `json:"id,omitempty"`
+	ResourceID           string         `json:"_rid,omitempty"`
+	Timestamp            int            `json:"_ts,omitempty"`
+	Self                 string         `json:"_self,omitempty"`
+	ETag                 string         `json:"_etag,omitempty"`
+	Token                string         `json:"_token,omitempty"`
+	PermissionMode       PermissionMode `json:"permissionMode,omitempty"`
+	Resource             string         `json:"resource,omitempty"`
+	ResourcePartitionKey []string       `json:"resourcePartitionKey,omitempty"`
 }
 
 // PermissionMode represents a permission mode
@@ -57,6 +58,7 @@ type permissionListIterator struct {
 // PermissionIterator is a permission iterator
 type PermissionIterator interface {
 	Next(context.Context) (*Permissions, error)
+	Items(context.Context) func(func(*Permission, error) bool)
 }
 
 // NewPermissionClient returns a new permission client
@@ -88,7 +90,7 @@ func (c *permissionClient) all(ctx context.Context, i PermissionIterator) (*Perm
 }
 
 func (c *permissionClient) Create(ctx context.Context, newpermission *Permission) (permission *Permission, err error) {
-	err = c.do(ctx, http.MethodPost, c.path+"/permissions", "permissions", c.path, http.StatusCreated, &newpermission, &permission, nil)
+	err = c.do(ctx, http.MethodPost, c.path+"/permissions", "permissions", c.path, http.StatusCreated, &newpermission, &permission, nil, nil)
 	return
 }
 
@@ -101,7 +103,7 @@ func (c *permissionClient) ListAll(ctx context.Context) (*Permissions, error) {
 }
 
 func (c *permissionClient) Get(ctx context.Context, permissionid string) (permission *Permission, err error) {
-	err = c.do(ctx, http.MethodGet, c.path+"/permissions/"+permissionid, "permissions", c.path+"/permissions/"+permissionid, http.StatusOK, nil, &permission, nil)
+	err = c.do(ctx, http.MethodGet, c.path+"/permissions/"+permissionid, "permissions", c.path+"/permissions/"+permissionid, http.StatusOK, nil, &permission, nil, nil)
 	return
 }
 
@@ -111,11 +113,11 @@ func (c *permissionClient) Delete(ctx context.Context, permission *Permission) e
 	}
 	headers := http.Header{}
 	headers.Set("If-Match", permission.ETag)
-	return c.do(ctx, http.MethodDelete, c.path+"/permissions/"+permission.ID, "permissions", c.path+"/permissions/"+permission.ID, http.StatusNoContent, nil, nil, headers)
+	return c.do(ctx, http.MethodDelete, c.path+"/permissions/"+permission.ID, "permissions", c.path+"/permissions/"+permission.ID, http.StatusNoContent, nil, nil, headers, nil)
 }
 
 func (c *permissionClient) Replace(ctx context.Context, newpermission *Permission) (permission *Permission, err error) {
-	err = c.do(ctx, http.MethodPost, c.path+"/permissions/"+newpermission.ID, "permissions", c.path+"/permissions/"+newpermission.ID, http.StatusCreated, &newpermission, &permission, nil)
+	err = c.do(ctx, http.MethodPut, c.path+"/permissions/"+newpermission.ID, "permissions", c.path+"/permissions/"+newpermission.ID, http.StatusOK, &newpermission, &permission, nil, nil)
 	return
 }
 
@@ -129,7 +131,7 @@ func (i *permissionListIterator) Next(ctx context.Context) (permissions *Permiss
 		headers.Set("X-Ms-Continuation", i.continuation)
 	}
 
-	err = i.do(ctx, http.MethodGet, i.path+"/permissions", "permissions", i.path, http.StatusOK, nil, &permissions, headers)
+	err = i.do(ctx, http.MethodGet, i.path+"/permissions", "permissions", i.path, http.StatusOK, nil, &permissions, headers, nil)
 	if err != nil {
 		return
 	}
@@ -139,3 +141,7 @@ func (i *permissionListIterator) Next(ctx context.Context) (permissions *Permiss
 
 	return
 }
+
+func (i *permissionListIterator) Items(ctx context.Context) func(func(*Permission, error) bool) {
+	return items(ctx, i.Next, func(permissions *Permissions) []*Permission { return permissions.Permissions })
+}
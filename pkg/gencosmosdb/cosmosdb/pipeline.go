@@ -0,0 +1,62 @@
+package cosmosdb
+
+import (
+	"net/http"
+)
+
+// Sender sends a request and returns its response, or an error if no response
+// was received
+type Sender func(*http.Request) (*http.Response, error)
+
+// Policy is a stage of the pipeline through which a client sends each attempt
+// of each request, once the request has been authorized and before its
+// response is decoded.  A Policy may modify the request before passing it to
+// next, inspect or replace the response which next returns, or return a
+// response or an error of its own without calling next, e.g. to serve a
+// request from a cache or to inject a fault.  As with http.Client.Do, a
+// response must have a Body, which the client closes.
+//
+// The headers of a request are reused by its later attempts, so a Policy
+// should Set rather than Add them.
+type Policy interface {
+	Do(req *http.Request, next Sender) (*http.Response, error)
+}
+
+// PolicyFunc is a function which implements Policy
+type PolicyFunc func(req *http.Request, next Sender) (*http.Response, error)
+
+func (f PolicyFunc) Do(req *http.Request, next Sender) (*http.Response, error) {
+	return f(req, next)
+}
+
+// SetPolicies sets the pipeline of policies through which the client and the
+// clients derived from it send requests.  The first policy receives each
+// request first and its response last.
+func (c *databaseClient) SetPolicies(policies ...Policy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.policies = policies
+}
+
+func (c *databaseClient) getPolicies() []Policy {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.policies
+}
+
+// pipeline returns a Sender which sends requests through the policies of the
+// client to send, which sends them to the server
+func (c *databaseClient) pipeline(send Sender) Sender {
+	policies := c.getPolicies()
+
+	for i := len(policies) - 1; i >= 0; i-- {
+		policy, next := policies[i], send
+		send = func(req *http.Request) (*http.Response, error) {
+			return policy.Do(req, next)
+		}
+	}
+
+	return send
+}
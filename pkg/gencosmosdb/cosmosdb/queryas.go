@@ -0,0 +1,42 @@
+package cosmosdb
+
+import (
+	"context"
+)
+
+// RawIterator is implemented by the iterators returned by the Query method of
+// generated clients
+type RawIterator interface {
+	NextRaw(context.Context, int, interface{}) error
+}
+
+// RawQuerier is implemented by generated clients
+type RawQuerier[I RawIterator] interface {
+	Query(string, *Query, *Options) I
+}
+
+// QueryAs runs query and decodes all of its results into values of type T,
+// which need not be the document type of the client, for example to receive a
+// projection.  Callers in modules targeting Go 1.20 must also name the raw
+// iterator type of the client, e.g. QueryAs[T, PersonRawIterator].
+func QueryAs[T any, I RawIterator](ctx context.Context, client RawQuerier[I], partitionkey string, query *Query, options *Options) ([]*T, error) {
+	i := client.Query(partitionkey, query, options)
+
+	var results []*T
+	for {
+		var page *struct {
+			Documents []*T
+		}
+		err := i.NextRaw(ctx, -1, &page)
+		if err != nil {
+			return nil, err
+		}
+		if page == nil {
+			break
+		}
+
+		results = append(results, page.Documents...)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,149 @@
+package cosmosdb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueryBuilder builds a parameterized query against the documents of a
+// collection, which are referred to by the alias "c".  Values are only ever
+// passed to the server as parameters, never interpolated into the query.
+type QueryBuilder struct {
+	fields     []string
+	where      []string
+	orderBy    []string
+	top        int
+	offset     int
+	limit      int
+	parameters []Parameter
+	params     int
+}
+
+// NewQueryBuilder returns a new QueryBuilder, initially for SELECT * FROM c
+func NewQueryBuilder() *QueryBuilder {
+	return &QueryBuilder{
+		top:   -1,
+		limit: -1,
+	}
+}
+
+// Select sets the expressions selected by the query, e.g. "c.id"
+func (b *QueryBuilder) Select(fields ...string) *QueryBuilder {
+	b.fields = fields
+	return b
+}
+
+// Where adds a condition to the query, ANDed with any existing conditions.
+// The condition should refer to values by named parameters, e.g.
+// Where("c.name = @name", Parameter{Name: "@name", Value: name}).
+func (b *QueryBuilder) Where(condition string, parameters ...Parameter) *QueryBuilder {
+	b.where = append(b.where, condition)
+	b.parameters = append(b.parameters, parameters...)
+	return b
+}
+
+// WhereEquals adds a condition that path equals value, binding value to a
+// generated parameter
+func (b *QueryBuilder) WhereEquals(path string, value interface{}) *QueryBuilder {
+	return b.Where(path + " = " + b.Param(value))
+}
+
+// WhereIn adds a condition that path equals one of values, binding each value
+// to a generated parameter
+func (b *QueryBuilder) WhereIn(path string, values ...interface{}) *QueryBuilder {
+	names := make([]string, len(values))
+	for i, value := range values {
+		names[i] = b.Param(value)
+	}
+
+	return b.Where(path + " IN (" + strings.Join(names, ", ") + ")")
+}
+
+// WherePartitionKeyPrefix adds conditions that the values of the leading
+// paths of a hierarchical partition key equal values, e.g.
+// WherePartitionKeyPrefix([]string{"/tenantId", "/userId"}, tenantID) for the
+// documents of a tenant
+func (b *QueryBuilder) WherePartitionKeyPrefix(paths []string, values ...string) *QueryBuilder {
+	for i, value := range values {
+		if i < len(paths) {
+			b.WhereEquals(partitionKeyPathExpression(paths[i]), value)
+		}
+	}
+
+	return b
+}
+
+// Param binds value to a generated parameter and returns its name, for use in
+// conditions passed to Where.  Generated parameters are named @__qbN, so as not
+// to collide with the caller's own.
+func (b *QueryBuilder) Param(value interface{}) string {
+	name := fmt.Sprintf("@__qb%d", b.params)
+	b.params++
+	b.parameters = append(b.parameters, Parameter{Name: name, Value: value})
+	return name
+}
+
+// OrderBy adds an ascending sort on path
+func (b *QueryBuilder) OrderBy(path string) *QueryBuilder {
+	b.orderBy = append(b.orderBy, path+" ASC")
+	return b
+}
+
+// OrderByDesc adds a descending sort on path
+func (b *QueryBuilder) OrderByDesc(path string) *QueryBuilder {
+	b.orderBy = append(b.orderBy, path+" DESC")
+	return b
+}
+
+// Limit limits the query to the first n results, replacing any limit set by
+// OffsetLimit
+func (b *QueryBuilder) Limit(n int) *QueryBuilder {
+	b.top = n
+	b.offset, b.limit = 0, -1
+	return b
+}
+
+// OffsetLimit limits the query to n results after skipping the first offset,
+// replacing any limit set by Limit
+func (b *QueryBuilder) OffsetLimit(offset, n int) *QueryBuilder {
+	b.top = -1
+	b.offset = offset
+	b.limit = n
+	return b
+}
+
+// Build returns the query
+func (b *QueryBuilder) Build() *Query {
+	var sb strings.Builder
+
+	sb.WriteString("SELECT ")
+	if b.top >= 0 {
+		fmt.Fprintf(&sb, "TOP %d ", b.top)
+	}
+	if len(b.fields) > 0 {
+		sb.WriteString(strings.Join(b.fields, ", "))
+	} else {
+		sb.WriteString("*")
+	}
+	sb.WriteString(" FROM c")
+
+	if len(b.where) > 0 {
+		sb.WriteString(" WHERE (")
+		sb.WriteString(strings.Join(b.where, ") AND ("))
+		sb.WriteString(")")
+	}
+
+	if len(b.orderBy) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(b.orderBy, ", "))
+	}
+
+	if b.limit >= 0 {
+		fmt.Fprintf(&sb, " OFFSET %d LIMIT %d", b.offset, b.limit)
+	}
+
+	return &Query{
+		Query:      sb.String(),
+		Parameters: append([]Parameter(nil), b.parameters...),
+	}
+}
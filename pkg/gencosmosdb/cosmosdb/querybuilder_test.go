@@ -0,0 +1,109 @@
+package cosmosdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		build func(*QueryBuilder) *QueryBuilder
+		want  *Query
+	}{
+		{
+			name:  "empty",
+			build: func(b *QueryBuilder) *QueryBuilder { return b },
+			want:  &Query{Query: "SELECT * FROM c"},
+		},
+		{
+			name: "select, where, order by",
+			build: func(b *QueryBuilder) *QueryBuilder {
+				return b.Select("c.id", "c.name").
+					Where("c.age > @age", Parameter{Name: "@age", Value: 18}).
+					WhereEquals("c.name", "bob").
+					OrderBy("c.name").
+					OrderByDesc("c.age")
+			},
+			want: &Query{
+				Query: "SELECT c.id, c.name FROM c WHERE (c.age > @age) AND (c.name = @__qb0) ORDER BY c.name ASC, c.age DESC",
+				Parameters: []Parameter{
+					{Name: "@age", Value: 18},
+					{Name: "@__qb0", Value: "bob"},
+				},
+			},
+		},
+		{
+			name: "where in",
+			build: func(b *QueryBuilder) *QueryBuilder {
+				return b.WhereEquals("c.type", "person").WhereIn("c.id", "a", "b")
+			},
+			want: &Query{
+				Query: "SELECT * FROM c WHERE (c.type = @__qb0) AND (c.id IN (@__qb1, @__qb2))",
+				Parameters: []Parameter{
+					{Name: "@__qb0", Value: "person"},
+					{Name: "@__qb1", Value: "a"},
+					{Name: "@__qb2", Value: "b"},
+				},
+			},
+		},
+		{
+			name: "param",
+			build: func(b *QueryBuilder) *QueryBuilder {
+				return b.Where("ARRAY_CONTAINS(c.tags, " + b.Param("x") + ")")
+			},
+			want: &Query{
+				Query:      "SELECT * FROM c WHERE (ARRAY_CONTAINS(c.tags, @__qb0))",
+				Parameters: []Parameter{{Name: "@__qb0", Value: "x"}},
+			},
+		},
+		{
+			name: "partition key prefix",
+			build: func(b *QueryBuilder) *QueryBuilder {
+				return b.WherePartitionKeyPrefix([]string{"/tenantId", "/user-id"}, "t", "u", "ignored")
+			},
+			want: &Query{
+				Query: `SELECT * FROM c WHERE (c["tenantId"] = @__qb0) AND (c["user-id"] = @__qb1)`,
+				Parameters: []Parameter{
+					{Name: "@__qb0", Value: "t"},
+					{Name: "@__qb1", Value: "u"},
+				},
+			},
+		},
+		{
+			name: "limit",
+			build: func(b *QueryBuilder) *QueryBuilder {
+				return b.Limit(10)
+			},
+			want: &Query{Query: "SELECT TOP 10 * FROM c"},
+		},
+		{
+			name: "offset limit",
+			build: func(b *QueryBuilder) *QueryBuilder {
+				return b.OrderBy("c.id").OffsetLimit(20, 10)
+			},
+			want: &Query{Query: "SELECT * FROM c ORDER BY c.id ASC OFFSET 20 LIMIT 10"},
+		},
+		{
+			name: "offset limit replaces limit",
+			build: func(b *QueryBuilder) *QueryBuilder {
+				return b.Limit(5).OffsetLimit(20, 10)
+			},
+			want: &Query{Query: "SELECT * FROM c OFFSET 20 LIMIT 10"},
+		},
+		{
+			name: "limit replaces offset limit",
+			build: func(b *QueryBuilder) *QueryBuilder {
+				return b.OffsetLimit(20, 10).Limit(5)
+			},
+			want: &Query{Query: "SELECT TOP 5 * FROM c"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.build(NewQueryBuilder()).Build()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package cosmosdb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryMetrics represents the metrics of a query, summed over the pages
+// fetched so far.  Only RequestCharge is populated unless
+// Options.PopulateQueryMetrics is set.
+type QueryMetrics struct {
+	RequestCharge float64
+
+	RetrievedDocumentCount int64
+	RetrievedDocumentSize  int64
+	OutputDocumentCount    int64
+	OutputDocumentSize     int64
+	IndexHitDocumentCount  float64
+
+	TotalExecutionTime         time.Duration
+	QueryCompileTime           time.Duration
+	QueryLogicalPlanBuildTime  time.Duration
+	QueryPhysicalPlanBuildTime time.Duration
+	QueryOptimizationTime      time.Duration
+	VMExecutionTime            time.Duration
+	IndexLookupTime            time.Duration
+	DocumentLoadTime           time.Duration
+	SystemFunctionExecuteTime  time.Duration
+	UserFunctionExecuteTime    time.Duration
+	DocumentWriteTime          time.Duration
+
+	// IndexUtilization is populated if Options.PopulateIndexMetrics is set
+	IndexUtilization *IndexUtilization
+}
+
+// IndexUtilization represents the indexes which were used by a query and
+// those which, if added to the indexing policy, might improve it
+type IndexUtilization struct {
+	UtilizedSingleIndexes     []*SingleIndexUtilization    `json:"UtilizedSingleIndexes,omitempty"`
+	PotentialSingleIndexes    []*SingleIndexUtilization    `json:"PotentialSingleIndexes,omitempty"`
+	UtilizedCompositeIndexes  []*CompositeIndexUtilization `json:"UtilizedCompositeIndexes,omitempty"`
+	PotentialCompositeIndexes []*CompositeIndexUtilization `json:"PotentialCompositeIndexes,omitempty"`
+}
+
+// SingleIndexUtilization represents the utilization of a single path index
+type SingleIndexUtilization struct {
+	FilterExpression string `json:"FilterExpression,omitempty"`
+	IndexSpec        string `json:"IndexSpec,omitempty"`
+	FilterPreciseSet bool   `json:"FilterPreciseSet,omitempty"`
+	IndexPreciseSet  bool   `json:"IndexPreciseSet,omitempty"`
+	IndexImpactScore string `json:"IndexImpactScore,omitempty"`
+}
+
+// CompositeIndexUtilization represents the utilization of a composite index
+type CompositeIndexUtilization struct {
+	IndexSpecs       []string `json:"IndexSpecs,omitempty"`
+	IndexPreciseSet  bool     `json:"IndexPreciseSet,omitempty"`
+	IndexImpactScore string   `json:"IndexImpactScore,omitempty"`
+}
+
+// parseIndexUtilization decodes the base64 encoded JSON index utilization
+// header
+func parseIndexUtilization(header string) (*IndexUtilization, error) {
+	b, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		return nil, err
+	}
+
+	indexUtilization := &IndexUtilization{}
+	err = json.Unmarshal(b, indexUtilization)
+	if err != nil {
+		return nil, err
+	}
+
+	return indexUtilization, nil
+}
+
+// IndexHitRatio returns the proportion of retrieved documents which were
+// served by the index
+func (m *QueryMetrics) IndexHitRatio() float64 {
+	if m.RetrievedDocumentCount == 0 {
+		return 1
+	}
+
+	return m.IndexHitDocumentCount / float64(m.RetrievedDocumentCount)
+}
+
+// add adds the metrics returned in the headers of a query response to m
+func (m *QueryMetrics) add(headers http.Header) {
+	charge, _ := strconv.ParseFloat(headers.Get("X-Ms-Request-Charge"), 64)
+	m.RequestCharge += charge
+
+	counts := map[string]*int64{
+		"retrievedDocumentCount": &m.RetrievedDocumentCount,
+		"retrievedDocumentSize":  &m.RetrievedDocumentSize,
+		"outputDocumentCount":    &m.OutputDocumentCount,
+		"outputDocumentSize":     &m.OutputDocumentSize,
+	}
+
+	durations := map[string]*time.Duration{
+		"totalExecutionTimeInMs":         &m.TotalExecutionTime,
+		"queryCompileTimeInMs":           &m.QueryCompileTime,
+		"queryLogicalPlanBuildTimeInMs":  &m.QueryLogicalPlanBuildTime,
+		"queryPhysicalPlanBuildTimeInMs": &m.QueryPhysicalPlanBuildTime,
+		"queryOptimizationTimeInMs":      &m.QueryOptimizationTime,
+		"VMExecutionTimeInMs":            &m.VMExecutionTime,
+		"indexLookupTimeInMs":            &m.IndexLookupTime,
+		"documentLoadTimeInMs":           &m.DocumentLoadTime,
+		"systemFunctionExecuteTimeInMs":  &m.SystemFunctionExecuteTime,
+		"userFunctionExecuteTimeInMs":    &m.UserFunctionExecuteTime,
+		"writeOutputTimeInMs":            &m.DocumentWriteTime,
+	}
+
+	// the header has the form name=value;name=value...
+	var retrieved int64
+	var indexUtilizationRatio float64
+	for _, metric := range strings.Split(headers.Get("X-Ms-Documentdb-Query-Metrics"), ";") {
+		name, value, ok := strings.Cut(metric, "=")
+		if !ok {
+			continue
+		}
+
+		if name == "indexUtilizationRatio" {
+			indexUtilizationRatio, _ = strconv.ParseFloat(value, 64)
+		}
+
+		if count, found := counts[name]; found {
+			n, _ := strconv.ParseInt(value, 10, 64)
+			*count += n
+			if name == "retrievedDocumentCount" {
+				retrieved = n
+			}
+		}
+
+		if duration, found := durations[name]; found {
+			ms, _ := strconv.ParseFloat(value, 64)
+			*duration += time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+
+	m.IndexHitDocumentCount += indexUtilizationRatio * float64(retrieved)
+
+	// index utilization describes the query as a whole, so the first
+	// returned is kept
+	if header := headers.Get("X-Ms-Cosmos-Index-Utilization"); header != "" && m.IndexUtilization == nil {
+		m.IndexUtilization, _ = parseIndexUtilization(header)
+	}
+}
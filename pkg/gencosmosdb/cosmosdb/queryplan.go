@@ -0,0 +1,57 @@
+package cosmosdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// supportedQueryFeatures are the query features which the client declares to
+// the gateway when requesting a query plan
+const supportedQueryFeatures = "Aggregate, Distinct, GroupBy, MultipleOrderBy, NonValueAggregate, OffsetAndLimit, OrderBy, Top"
+
+// queryPlan is the gateway's plan for executing a cross-partition query
+type queryPlan struct {
+	QueryInfo queryInfo `json:"queryInfo"`
+}
+
+// queryInfo describes the parts of a cross-partition query which must be
+// completed by the client
+type queryInfo struct {
+	Aggregates                  []string           `json:"aggregates"`
+	GroupByExpressions          []string           `json:"groupByExpressions"`
+	GroupByAliases              []string           `json:"groupByAliases"`
+	GroupByAliasToAggregateType map[string]*string `json:"groupByAliasToAggregateType"`
+	DistinctType                string             `json:"distinctType"`
+	Top                         *int               `json:"top"`
+	Offset                      *int               `json:"offset"`
+	Limit                       *int               `json:"limit"`
+	OrderBy                     []string           `json:"orderBy"`
+	RewrittenQuery              string             `json:"rewrittenQuery"`
+	HasSelectValue              bool               `json:"hasSelectValue"`
+}
+
+// getQueryPlan returns the query plan of query against the collection at path
+func (c *databaseClient) getQueryPlan(ctx context.Context, path string, query *Query, options *Options) (*queryPlan, error) {
+	headers := http.Header{}
+	headers.Set("X-Ms-Cosmos-Is-Query-Plan-Request", "True")
+	headers.Set("X-Ms-Cosmos-Supported-Query-Features", supportedQueryFeatures)
+	headers.Set("X-Ms-Cosmos-Query-Version", "1.4")
+	headers.Set("X-Ms-Documentdb-Query-Enablecrosspartition", "True")
+
+	// the plan is decoded independently of the client's Serializer, whose
+	// options may reject fields which queryPlan does not model
+	var raw json.RawMessage
+	err := c.doQuery(ctx, path+"/docs", "docs", path, query, &raw, headers, options)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &queryPlan{}
+	err = json.Unmarshal(raw, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
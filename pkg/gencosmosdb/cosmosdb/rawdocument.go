@@ -0,0 +1,329 @@
+package cosmosdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RawDocuments represents a page of documents of any schema
+type RawDocuments struct {
+	Count      int               `json:"_count,omitempty"`
+	ResourceID string            `json:"_rid,omitempty"`
+	Documents  []json.RawMessage `json:"Documents,omitempty"`
+}
+
+type rawDocumentClient struct {
+	*databaseClient
+	path string
+}
+
+// RawDocumentClient is a client of the documents of a collection which leaves
+// them undecoded, so that it can be used without generating a client for
+// their type, e.g. by tools and migrations
+type RawDocumentClient interface {
+	Create(context.Context, string, json.RawMessage, *Options) (json.RawMessage, error)
+	List(*Options) RawDocumentIterator
+	ListAll(context.Context, *Options) (*RawDocuments, error)
+	Get(context.Context, string, string, *Options) (json.RawMessage, error)
+	Replace(context.Context, string, json.RawMessage, *Options) (json.RawMessage, error)
+	Delete(context.Context, string, json.RawMessage, *Options) error
+	Query(string, *Query, *Options) RawDocumentIterator
+	QueryAll(context.Context, string, *Query, *Options) (*RawDocuments, error)
+}
+
+type rawDocumentListIterator struct {
+	*rawDocumentClient
+	continuation string
+	done         bool
+	options      *Options
+}
+
+type rawDocumentQueryIterator struct {
+	*rawDocumentClient
+	partitionkey   string
+	query          *Query
+	continuation   string
+	done           bool
+	options        *Options
+	crossPartition *crossPartitionQuery
+	routed         bool
+}
+
+// RawDocumentIterator is a raw document iterator
+type RawDocumentIterator interface {
+	Next(context.Context, int) (*RawDocuments, error)
+	Continuation() string
+}
+
+// rawDocumentMetadata is the system properties of a raw document which are
+// needed to address it
+type rawDocumentMetadata struct {
+	ID   string `json:"id,omitempty"`
+	ETag string `json:"_etag,omitempty"`
+}
+
+// NewRawDocumentClient returns a new raw document client
+func NewRawDocumentClient(collc CollectionClient, collid string) RawDocumentClient {
+	return &rawDocumentClient{
+		databaseClient: collc.(*collectionClient).databaseClient,
+		path:           collc.(*collectionClient).path + "/colls/" + collid,
+	}
+}
+
+func (c *rawDocumentClient) all(ctx context.Context, i RawDocumentIterator) (*RawDocuments, error) {
+	alldocs := &RawDocuments{}
+
+	for {
+		docs, err := i.Next(ctx, -1)
+		if err != nil {
+			return nil, err
+		}
+		if docs == nil {
+			break
+		}
+
+		alldocs.Count += docs.Count
+		alldocs.ResourceID = docs.ResourceID
+		alldocs.Documents = append(alldocs.Documents, docs.Documents...)
+	}
+
+	return alldocs, nil
+}
+
+func (c *rawDocumentClient) Create(ctx context.Context, partitionkey string, newdoc json.RawMessage, options *Options) (doc json.RawMessage, err error) {
+	headers := http.Header{}
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
+
+	err = c.setOptions(options, nil, headers)
+	if err != nil {
+		return
+	}
+
+	_, err = c.send(ctx, http.MethodPost, c.path+"/docs", "docs", c.path, http.StatusCreated, newdoc, &doc, headers, options)
+	return
+}
+
+func (c *rawDocumentClient) List(options *Options) RawDocumentIterator {
+	continuation := ""
+	if options != nil {
+		continuation = options.Continuation
+	}
+
+	return &rawDocumentListIterator{rawDocumentClient: c, options: options, continuation: continuation}
+}
+
+func (c *rawDocumentClient) ListAll(ctx context.Context, options *Options) (*RawDocuments, error) {
+	return c.all(ctx, c.List(options))
+}
+
+func (c *rawDocumentClient) Get(ctx context.Context, partitionkey, docid string, options *Options) (doc json.RawMessage, err error) {
+	headers := http.Header{}
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
+	if options != nil && options.IfNoneMatch != "" {
+		headers.Set("If-None-Match", options.IfNoneMatch)
+	}
+
+	err = c.setOptions(options, nil, headers)
+	if err != nil {
+		return
+	}
+
+	_, err = c.send(ctx, http.MethodGet, c.path+"/docs/"+docid, "docs", c.path+"/docs/"+docid, http.StatusOK, nil, &doc, headers, options)
+	return
+}
+
+// Replace replaces the document with the id of newdoc.  If options are given
+// and NoETag is not set, the replace is conditional on the _etag of newdoc.
+func (c *rawDocumentClient) Replace(ctx context.Context, partitionkey string, newdoc json.RawMessage, options *Options) (doc json.RawMessage, err error) {
+	var metadata rawDocumentMetadata
+	err = json.Unmarshal(newdoc, &metadata)
+	if err != nil {
+		return
+	}
+
+	headers := http.Header{}
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
+
+	err = c.setOptions(options, &metadata, headers)
+	if err != nil {
+		return
+	}
+
+	_, err = c.send(ctx, http.MethodPut, c.path+"/docs/"+metadata.ID, "docs", c.path+"/docs/"+metadata.ID, http.StatusOK, newdoc, &doc, headers, options)
+	return
+}
+
+// Delete deletes the document with the id of doc.  If options are given and
+// NoETag is not set, the delete is conditional on the _etag of doc.
+func (c *rawDocumentClient) Delete(ctx context.Context, partitionkey string, doc json.RawMessage, options *Options) (err error) {
+	var metadata rawDocumentMetadata
+	err = json.Unmarshal(doc, &metadata)
+	if err != nil {
+		return
+	}
+
+	headers := http.Header{}
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
+
+	err = c.setOptions(options, &metadata, headers)
+	if err != nil {
+		return
+	}
+
+	_, err = c.send(ctx, http.MethodDelete, c.path+"/docs/"+metadata.ID, "docs", c.path+"/docs/"+metadata.ID, http.StatusNoContent, nil, nil, headers, options)
+	return
+}
+
+func (c *rawDocumentClient) Query(partitionkey string, query *Query, options *Options) RawDocumentIterator {
+	continuation := ""
+	if options != nil {
+		continuation = options.Continuation
+	}
+
+	i := &rawDocumentQueryIterator{rawDocumentClient: c, partitionkey: partitionkey, query: query, options: options, continuation: continuation}
+
+	// without a partition key or range, fan the query out across all
+	// partition key ranges
+	if partitionkey == "" && (options == nil || options.PartitionKeyRangeID == "" && options.PartitionKey == "") {
+		i.crossPartition = c.newCrossPartitionQuery(c.path, query, options, continuation)
+	}
+
+	return i
+}
+
+func (c *rawDocumentClient) QueryAll(ctx context.Context, partitionkey string, query *Query, options *Options) (*RawDocuments, error) {
+	return c.all(ctx, c.Query(partitionkey, query, options))
+}
+
+func (c *rawDocumentClient) setOptions(options *Options, metadata *rawDocumentMetadata, headers http.Header) error {
+	if options == nil {
+		return nil
+	}
+
+	err := options.validateTriggers()
+	if err != nil {
+		return err
+	}
+
+	if metadata != nil && !options.NoETag {
+		if metadata.ETag == "" {
+			return ErrETagRequired
+		}
+		headers.Set("If-Match", metadata.ETag)
+	}
+	if len(options.PreTriggers) > 0 {
+		headers.Set("X-Ms-Documentdb-Pre-Trigger-Include", strings.Join(options.PreTriggers, ","))
+	}
+	if len(options.PostTriggers) > 0 {
+		headers.Set("X-Ms-Documentdb-Post-Trigger-Include", strings.Join(options.PostTriggers, ","))
+	}
+	if len(options.PartitionKeyRangeID) > 0 {
+		headers.Set("X-Ms-Documentdb-PartitionKeyRangeID", options.PartitionKeyRangeID)
+	}
+	if options.IndexingDirective != "" {
+		headers.Set("X-Ms-Indexing-Directive", string(options.IndexingDirective))
+	}
+	options.setFeedHeaders(headers)
+
+	return nil
+}
+
+func (i *rawDocumentListIterator) Next(ctx context.Context, maxItemCount int) (docs *RawDocuments, err error) {
+	if i.done {
+		return
+	}
+
+	headers := http.Header{}
+	headers.Set("X-Ms-Max-Item-Count", strconv.Itoa(maxItemCount))
+	if i.continuation != "" {
+		headers.Set("X-Ms-Continuation", i.continuation)
+	}
+
+	err = i.setOptions(i.options, nil, headers)
+	if err != nil {
+		return
+	}
+
+	err = i.do(ctx, http.MethodGet, i.path+"/docs", "docs", i.path, http.StatusOK, nil, &docs, headers, i.options)
+	if err != nil {
+		return
+	}
+
+	i.continuation = headers.Get("X-Ms-Continuation")
+	i.done = i.continuation == ""
+
+	return
+}
+
+func (i *rawDocumentListIterator) Continuation() string {
+	return i.continuation
+}
+
+func (i *rawDocumentQueryIterator) Next(ctx context.Context, maxItemCount int) (docs *RawDocuments, err error) {
+	err = i.route(ctx)
+	if err != nil {
+		return
+	}
+
+	if i.crossPartition != nil {
+		err = i.crossPartition.nextRaw(ctx, maxItemCount, &docs)
+		return
+	}
+
+	if i.done {
+		return
+	}
+
+	headers := http.Header{}
+	headers.Set("X-Ms-Max-Item-Count", strconv.Itoa(maxItemCount))
+	if i.partitionkey != "" {
+		headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(i.partitionkey))
+	} else {
+		headers.Set("X-Ms-Documentdb-Query-Enablecrosspartition", "True")
+	}
+	if i.continuation != "" {
+		headers.Set("X-Ms-Continuation", i.continuation)
+	}
+
+	err = i.setOptions(i.options, nil, headers)
+	if err != nil {
+		return
+	}
+
+	err = i.doQuery(ctx, i.path+"/docs", "docs", i.path, i.query, &docs, headers, i.options)
+	if err != nil {
+		return
+	}
+
+	i.continuation = headers.Get("X-Ms-Continuation")
+	i.done = i.continuation == ""
+
+	return
+}
+
+// route makes a query by a prefix of a hierarchical partition key a
+// cross-partition query of the partition key ranges which may hold its results
+func (i *rawDocumentQueryIterator) route(ctx context.Context) (err error) {
+	if i.routed || i.crossPartition != nil {
+		return
+	}
+
+	i.crossPartition, err = i.prefixQuery(ctx, i.path, i.partitionkey, i.query, i.options, i.continuation)
+	if err != nil {
+		return
+	}
+
+	i.routed = true
+	return
+}
+
+func (i *rawDocumentQueryIterator) Continuation() string {
+	if i.crossPartition != nil {
+		return i.crossPartition.Continuation()
+	}
+
+	return i.continuation
+}
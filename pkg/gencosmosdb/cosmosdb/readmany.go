@@ -0,0 +1,53 @@
+package cosmosdb
+
+// maxReadManyQueryIDs is the maximum number of IDs which ReadMany looks up in
+// a single query
+const maxReadManyQueryIDs = 100
+
+// ItemIdentity identifies a document by its ID and partition key
+type ItemIdentity struct {
+	ID           string
+	PartitionKey string
+}
+
+// readManyGroup is a set of IDs sharing a partition key which ReadMany reads
+// with a single request
+type readManyGroup struct {
+	partitionkey string
+	ids          []string
+}
+
+// groupReadMany groups the IDs of items by partition key, in groups of up to
+// maxReadManyQueryIDs, ignoring duplicates
+func groupReadMany(items []ItemIdentity) []*readManyGroup {
+	var groups []*readManyGroup
+	open := map[string]*readManyGroup{}
+	seen := map[ItemIdentity]bool{}
+
+	for _, item := range items {
+		if seen[item] {
+			continue
+		}
+		seen[item] = true
+
+		g := open[item.PartitionKey]
+		if g == nil || len(g.ids) == maxReadManyQueryIDs {
+			g = &readManyGroup{partitionkey: item.PartitionKey}
+			open[item.PartitionKey] = g
+			groups = append(groups, g)
+		}
+		g.ids = append(g.ids, item.ID)
+	}
+
+	return groups
+}
+
+// query returns a query for the documents of g
+func (g *readManyGroup) query() *Query {
+	ids := make([]interface{}, len(g.ids))
+	for i, id := range g.ids {
+		ids[i] = id
+	}
+
+	return NewQueryBuilder().WhereIn("c.id", ids...).Build()
+}
@@ -0,0 +1,133 @@
+package cosmosdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// RedisClient is the subset of a Redis client used by the Redis
+// CheckpointStore.  Eval runs a Lua script, as the EVAL command does, and
+// returns its result; with github.com/redis/go-redis it is implemented by
+// client.Eval(ctx, script, keys, args...).Result().
+type RedisClient interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// Lua scripts of the Redis CheckpointStore.  Those which update a lease return
+// the HTTP status code of the equivalent Cosmos DB request, so that the
+// conditional update is atomic.
+const (
+	redisListLeasesScript = `return redis.call('HVALS', KEYS[1])`
+
+	redisCreateLeaseScript = `return redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2])`
+
+	redisReplaceLeaseScript = `local existing = redis.call('HGET', KEYS[1], ARGV[1])
+if not existing then return 404 end
+if cjson.decode(existing)['_etag'] ~= ARGV[2] then return 412 end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+return 200`
+
+	redisDeleteLeaseScript = `local existing = redis.call('HGET', KEYS[1], ARGV[1])
+if not existing then return 404 end
+if cjson.decode(existing)['_etag'] ~= ARGV[2] then return 412 end
+redis.call('HDEL', KEYS[1], ARGV[1])
+return 204`
+)
+
+type redisCheckpointStore struct {
+	client RedisClient
+	key    string
+}
+
+// NewRedisCheckpointStore returns a CheckpointStore which stores leases in
+// the Redis hash key, keyed by lease ID
+func NewRedisCheckpointStore(client RedisClient, key string) CheckpointStore {
+	return &redisCheckpointStore{client: client, key: key}
+}
+
+// eval runs an updating script and returns an *Error if it did not succeed
+func (s *redisCheckpointStore) eval(ctx context.Context, script string, args ...interface{}) error {
+	result, err := s.client.Eval(ctx, script, []string{s.key}, args...)
+	if err != nil {
+		return err
+	}
+
+	statusCode, ok := result.(int64)
+	if !ok {
+		return fmt.Errorf("unexpected redis result %T", result)
+	}
+
+	if statusCode >= http.StatusBadRequest {
+		return &Error{StatusCode: int(statusCode)}
+	}
+
+	return nil
+}
+
+func (s *redisCheckpointStore) ListLeases(ctx context.Context, prefix string) ([]*Lease, error) {
+	result, err := s.client.Eval(ctx, redisListLeasesScript, []string{s.key})
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := result.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected redis result %T", result)
+	}
+
+	var leases []*Lease
+	for _, value := range values {
+		value, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected redis result %T", value)
+		}
+
+		var lease *Lease
+		err = json.Unmarshal([]byte(value), &lease)
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(lease.ID, prefix) {
+			leases = append(leases, lease)
+		}
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].ID < leases[j].ID })
+
+	return leases, nil
+}
+
+func (s *redisCheckpointStore) CreateLease(ctx context.Context, lease *Lease) error {
+	b, err := json.Marshal(updatedLease(lease))
+	if err != nil {
+		return err
+	}
+
+	// HSETNX returns 0 if the lease already exists, which is not an error
+	_, err = s.client.Eval(ctx, redisCreateLeaseScript, []string{s.key}, lease.ID, string(b))
+	return err
+}
+
+func (s *redisCheckpointStore) ReplaceLease(ctx context.Context, lease *Lease) (*Lease, error) {
+	replaced := updatedLease(lease)
+
+	b, err := json.Marshal(replaced)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.eval(ctx, redisReplaceLeaseScript, lease.ID, lease.ETag, string(b))
+	if err != nil {
+		return nil, err
+	}
+
+	return replaced, nil
+}
+
+func (s *redisCheckpointStore) DeleteLease(ctx context.Context, lease *Lease) error {
+	return s.eval(ctx, redisDeleteLeaseScript, lease.ID, lease.ETag)
+}
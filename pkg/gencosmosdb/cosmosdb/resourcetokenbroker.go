@@ -0,0 +1,117 @@
+package cosmosdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultResourceTokenLifetime is the validity of resource tokens issued by
+// the permissions API when no expiry is requested
+const DefaultResourceTokenLifetime = time.Hour
+
+type resourceTokenBroker struct {
+	mu          sync.Mutex
+	permc       PermissionClient
+	mode        PermissionMode
+	lifetime    time.Duration
+	collections map[string]*tokenAuthorizer
+}
+
+// NewResourceTokenBroker returns an Authorizer which signs requests with
+// resource tokens of the user behind permc.  On first use of each collection,
+// a permission with the given mode is read or, if it does not exist, created
+// for the collection, and its token is cached and refreshed before it
+// expires.  The permission's ID is derived from the link of the collection;
+// an existing permission with that ID but a different mode or resource is
+// replaced.  permc itself must use an Authorizer which is able to manage
+// permissions, e.g. a MasterKeyAuthorizer.
+func NewResourceTokenBroker(permc PermissionClient, mode PermissionMode) Authorizer {
+	return &resourceTokenBroker{
+		permc:       permc,
+		mode:        mode,
+		lifetime:    DefaultResourceTokenLifetime,
+		collections: map[string]*tokenAuthorizer{},
+	}
+}
+
+func (b *resourceTokenBroker) Authorize(ctx context.Context, req *http.Request, resourceType, resourceLink string) error {
+	collLink, ok := collectionLink(resourceLink)
+	if !ok {
+		return ErrResourceTokenNotFound
+	}
+
+	token, err := b.tokenAuthorizer(collLink).acquireToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", url.QueryEscape(token))
+	req.Header.Set("x-ms-date", time.Now().UTC().Format("Mon, 02 Jan 2006 15:04:05 GMT"))
+
+	return nil
+}
+
+func (b *resourceTokenBroker) tokenAuthorizer(collLink string) *tokenAuthorizer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	a := b.collections[collLink]
+	if a == nil {
+		a = &tokenAuthorizer{getToken: b.getToken(collLink), cond: sync.NewCond(&sync.Mutex{})}
+		b.collections[collLink] = a
+	}
+
+	return a
+}
+
+func (b *resourceTokenBroker) getToken(collLink string) func(context.Context) (string, time.Time, error) {
+	permissionid := resourceTokenBrokerPermissionID(collLink)
+
+	return func(ctx context.Context) (string, time.Time, error) {
+		issued := time.Now()
+
+		permission, err := b.permc.Get(ctx, permissionid)
+		if IsErrorStatusCode(err, http.StatusNotFound) {
+			permission, err = b.permc.Create(ctx, &Permission{
+				ID:             permissionid,
+				PermissionMode: b.mode,
+				Resource:       collLink,
+			})
+		} else if err == nil && (permission.PermissionMode != b.mode || strings.Trim(permission.Resource, "/") != collLink) {
+			permission, err = b.permc.Replace(ctx, &Permission{
+				ID:             permissionid,
+				PermissionMode: b.mode,
+				Resource:       collLink,
+			})
+		}
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		return permission.Token, issued.Add(b.lifetime), nil
+	}
+}
+
+// resourceTokenBrokerPermissionID returns the ID of the permission for the
+// collection at collLink, which differs between collections of the same name
+// in different databases
+func resourceTokenBrokerPermissionID(collLink string) string {
+	sum := sha256.Sum256([]byte(collLink))
+	return hex.EncodeToString(sum[:16])
+}
+
+// collectionLink returns the "dbs/{db}/colls/{coll}" prefix of resourceLink
+func collectionLink(resourceLink string) (string, bool) {
+	parts := strings.SplitN(strings.Trim(resourceLink, "/"), "/", 5)
+	if len(parts) < 4 || parts[0] != "dbs" || parts[2] != "colls" {
+		return "", false
+	}
+
+	return strings.Join(parts[:4], "/"), true
+}
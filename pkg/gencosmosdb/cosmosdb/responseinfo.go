@@ -0,0 +1,42 @@
+package cosmosdb
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// ResponseInfo represents the metadata of the response to an operation.  If
+// Options.ResponseInfo is set, it is populated after each successful request
+// made with the Options; operations such as queries, which may make several
+// requests, leave the metadata of the last.
+type ResponseInfo struct {
+	mu sync.Mutex
+
+	RequestCharge float64
+	SessionToken  string
+	ActivityID    string
+	ETag          string
+
+	// PartitionKeyRangeID is the ID of the partition key range which served
+	// the request
+	PartitionKeyRangeID string
+
+	// ScriptLog is the console.log output of a stored procedure executed
+	// with Options.EnableScriptLogging
+	ScriptLog string
+}
+
+// set populates r from the headers of a response
+func (r *ResponseInfo) set(headers http.Header) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.RequestCharge, _ = strconv.ParseFloat(headers.Get("X-Ms-Request-Charge"), 64)
+	r.SessionToken = headers.Get("X-Ms-Session-Token")
+	r.ActivityID = headers.Get("X-Ms-Activity-Id")
+	r.ETag = headers.Get("Etag")
+	r.PartitionKeyRangeID = headers.Get("X-Ms-Documentdb-Partitionkeyrangeid")
+	r.ScriptLog, _ = url.QueryUnescape(headers.Get("X-Ms-Documentdb-Script-Log-Results"))
+}
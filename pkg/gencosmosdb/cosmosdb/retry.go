@@ -0,0 +1,209 @@
+package cosmosdb
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// StatusRetryWith is returned by Cosmos DB when a write conflicts with a
+// concurrent write and should be retried
+const StatusRetryWith = 449
+
+// Defaults of DefaultRetryPolicy
+const (
+	defaultRetryBaseDelay = 100 * time.Millisecond
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// RetryAttempt describes an attempt of a request which failed
+type RetryAttempt struct {
+	// Attempt is the number of the attempt, counting from zero
+	Attempt int
+
+	Method string
+	Path   string
+
+	// Idempotent is true if repeating the request cannot change its outcome:
+	// it is a read, a replace, or a delete conditional on an ETag.  Other
+	// requests, such as creates and stored procedure executions, may have
+	// taken effect even if they appear to have failed.
+	Idempotent bool
+
+	// Response is nil if no response was received
+	Response *http.Response
+	Err      error
+}
+
+// RetryPolicy decides whether and when requests which fail are retried.  A
+// request is attempted at most MaxAttempts times, and is not retried if the
+// delays before its retries would add up to more than MaxRetryDuration, if
+// non-zero, or outlast the deadline of its context.
+type RetryPolicy interface {
+	MaxAttempts() int
+	MaxRetryDuration() time.Duration
+	ShouldRetry(*RetryAttempt) bool
+	Delay(*RetryAttempt) time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy used unless another is set with
+// SetRetryPolicy.  It retries requests which are throttled, fail with 449
+// Retry With or could not connect, which the server did not execute, and
+// idempotent requests which fail with 503 Service Unavailable, time out or
+// fail with another retriable network error, which are usually transient.
+//
+// Retries back off exponentially from BaseDelay up to MaxDelay, with random
+// jitter so that many clients do not retry in step, but never sooner than the
+// server requests.
+type DefaultRetryPolicy struct {
+	// Attempts is the maximum number of attempts of a request; if zero, 10
+	Attempts int
+
+	// BaseDelay is the delay before the first retry; if zero, 100ms
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay before each retry; if zero, 30s
+	MaxDelay time.Duration
+
+	// RetryDuration, if non-zero, caps the total delay before the retries of
+	// a request
+	RetryDuration time.Duration
+
+	// RetryNonIdempotent also retries requests which are not idempotent after
+	// failures which leave it unknown whether they took effect, at the risk
+	// of, for example, creating a document twice
+	RetryNonIdempotent bool
+}
+
+func (p *DefaultRetryPolicy) MaxAttempts() int {
+	if p.Attempts == 0 {
+		return 10
+	}
+
+	return p.Attempts
+}
+
+func (p *DefaultRetryPolicy) MaxRetryDuration() time.Duration {
+	return p.RetryDuration
+}
+
+func (p *DefaultRetryPolicy) ShouldRetry(a *RetryAttempt) bool {
+	switch {
+	case IsErrorStatusCode(a.Err, http.StatusTooManyRequests),
+		IsErrorStatusCode(a.Err, StatusRetryWith),
+		isDialError(a.Err):
+		return true
+
+	case IsErrorStatusCode(a.Err, http.StatusServiceUnavailable),
+		IsErrorStatusCode(a.Err, http.StatusRequestTimeout),
+		IsRetriableError(a.Err):
+		return a.Idempotent || p.RetryNonIdempotent
+	}
+
+	return false
+}
+
+func (p *DefaultRetryPolicy) Delay(a *RetryAttempt) time.Duration {
+	baseDelay, maxDelay := p.BaseDelay, p.MaxDelay
+	if baseDelay == 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+	if maxDelay == 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+
+	delay := maxDelay
+	if a.Attempt < 32 && baseDelay<<a.Attempt > 0 && baseDelay<<a.Attempt < maxDelay {
+		delay = baseDelay << a.Attempt
+	}
+
+	// wait between half and all of the backoff
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+	if serverDelay, ok := retryAfter(a.Response); ok && serverDelay > delay {
+		delay = serverDelay
+	}
+
+	return delay
+}
+
+// IsRetriableError returns true if err is a transient network error, such as
+// a connection reset or a DNS failure, after which a request may succeed if
+// it is retried
+func IsRetriableError(err error) bool {
+	return isDialError(err) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// isDialError returns true if err is the failure to connect to a server, in
+// which case no request was sent
+func isDialError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial" && !opErr.Timeout()
+}
+
+// isIdempotent returns true if repeating a request cannot change its outcome
+func isIdempotent(method string, headers http.Header) bool {
+	switch method {
+	case http.MethodPut:
+		return true
+	case http.MethodDelete:
+		return headers.Get("If-Match") != ""
+	}
+	return isReadRequest(method, headers)
+}
+
+// retriesNonIdempotent returns true if p retries requests which are not
+// idempotent after failures which leave it unknown whether they took effect
+func retriesNonIdempotent(p RetryPolicy) bool {
+	dp, ok := p.(*DefaultRetryPolicy)
+	return ok && dp.RetryNonIdempotent
+}
+
+// retryAfter returns the delay requested by the server in resp, if any, from
+// the x-ms-retry-after-ms header or, failing that, the Retry-After header
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	if ms, err := strconv.ParseInt(resp.Header.Get("x-ms-retry-after-ms"), 10, 0); err == nil {
+		return time.Duration(ms) * time.Millisecond, true
+	}
+
+	if s, err := strconv.ParseInt(resp.Header.Get("Retry-After"), 10, 0); err == nil {
+		return time.Duration(s) * time.Second, true
+	}
+
+	return 0, false
+}
+
+// SetRetryPolicy sets the RetryPolicy of the client and of the clients
+// derived from it
+func (c *databaseClient) SetRetryPolicy(retryPolicy RetryPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.retryPolicy = retryPolicy
+}
+
+func (c *databaseClient) getRetryPolicy() RetryPolicy {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.retryPolicy
+}
@@ -0,0 +1,271 @@
+package cosmosdb
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// SyncScripts reconciles the server-side scripts of the collection collid of
+// collc with the JavaScript files in fsys, which may be an embed.FS or, for a
+// directory, os.DirFS.  Stored procedures are read from sprocs/{id}.js,
+// user-defined functions from udfs/{id}.js and triggers from
+// triggers/{type}/{operation}/{id}.js, e.g. triggers/pre/all/updateTime.js.
+//
+// Scripts which are missing are created, those whose definitions differ are
+// replaced, and those which are not in fsys are deleted.  Kinds of script
+// whose directory is absent from fsys are left alone.
+func SyncScripts(ctx context.Context, collc CollectionClient, collid string, fsys fs.FS) error {
+	log := collc.(*collectionClient).getLogger()
+
+	triggers, found, err := readTriggers(fsys)
+	if err != nil {
+		return err
+	}
+	if found {
+		triggerc := NewTriggerClient(collc, collid)
+
+		existing, err := triggerc.ListAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = syncScripts(ctx, log, "trigger", triggers, existing.Triggers,
+			func(trigger *Trigger) string { return trigger.ID },
+			func(desired, existing *Trigger) bool {
+				return desired.Body == existing.Body &&
+					desired.TriggerType == existing.TriggerType &&
+					desired.TriggerOperation == existing.TriggerOperation
+			},
+			triggerc.Create,
+			func(ctx context.Context, desired, existing *Trigger) (*Trigger, error) {
+				desired.ETag = existing.ETag
+				return triggerc.Replace(ctx, desired)
+			},
+			triggerc.Delete)
+		if err != nil {
+			return err
+		}
+	}
+
+	bodies, found, err := readScripts(fsys, "sprocs")
+	if err != nil {
+		return err
+	}
+	if found {
+		sprocc := NewStoredProcedureClient(collc, collid)
+
+		existing, err := sprocc.ListAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		sprocs := map[string]*StoredProcedure{}
+		for id, body := range bodies {
+			sprocs[id] = &StoredProcedure{ID: id, Body: body}
+		}
+
+		err = syncScripts(ctx, log, "stored procedure", sprocs, existing.StoredProcedures,
+			func(sproc *StoredProcedure) string { return sproc.ID },
+			func(desired, existing *StoredProcedure) bool { return desired.Body == existing.Body },
+			sprocc.Create,
+			func(ctx context.Context, desired, existing *StoredProcedure) (*StoredProcedure, error) {
+				desired.ETag = existing.ETag
+				return sprocc.Replace(ctx, desired)
+			},
+			sprocc.Delete)
+		if err != nil {
+			return err
+		}
+	}
+
+	bodies, found, err = readScripts(fsys, "udfs")
+	if err != nil {
+		return err
+	}
+	if found {
+		udfc := NewUDFClient(collc, collid)
+
+		existing, err := udfc.ListAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		udfs := map[string]*UDF{}
+		for id, body := range bodies {
+			udfs[id] = &UDF{ID: id, Body: body}
+		}
+
+		err = syncScripts(ctx, log, "user-defined function", udfs, existing.UDFs,
+			func(udf *UDF) string { return udf.ID },
+			func(desired, existing *UDF) bool { return desired.Body == existing.Body },
+			udfc.Create,
+			func(ctx context.Context, desired, existing *UDF) (*UDF, error) {
+				desired.ETag = existing.ETag
+				return udfc.Replace(ctx, desired)
+			},
+			udfc.Delete)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncScripts reconciles the existing scripts of one kind with the desired
+// scripts, keyed by ID
+func syncScripts[T any](ctx context.Context, log Logger, kind string, desired map[string]*T, existing []*T,
+	id func(*T) string,
+	equal func(desired, existing *T) bool,
+	create func(context.Context, *T) (*T, error),
+	replace func(ctx context.Context, desired, existing *T) (*T, error),
+	remove func(context.Context, *T) error) error {
+	found := map[string]bool{}
+
+	for _, e := range existing {
+		found[id(e)] = true
+
+		d := desired[id(e)]
+		switch {
+		case d == nil:
+			log.Info("deleting "+kind, "id", id(e))
+			err := remove(ctx, e)
+			if err != nil {
+				return err
+			}
+
+		case !equal(d, e):
+			log.Info("replacing "+kind, "id", id(e))
+			_, err := replace(ctx, d, e)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(desired))
+	for id := range desired {
+		if !found[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		log.Info("creating "+kind, "id", id)
+		_, err := create(ctx, desired[id])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readScripts returns the bodies of the scripts in dir, keyed by ID, and
+// whether dir exists
+func readScripts(fsys fs.FS, dir string) (map[string]string, bool, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	bodies := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".js") {
+			continue
+		}
+
+		b, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, false, err
+		}
+
+		bodies[strings.TrimSuffix(entry.Name(), ".js")] = string(b)
+	}
+
+	return bodies, true, nil
+}
+
+// readTriggers returns the triggers in the triggers directory, keyed by ID,
+// and whether it exists
+func readTriggers(fsys fs.FS) (map[string]*Trigger, bool, error) {
+	types, err := fs.ReadDir(fsys, "triggers")
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	triggers := map[string]*Trigger{}
+	for _, t := range types {
+		if !t.IsDir() {
+			continue
+		}
+
+		triggerType, ok := parseScriptDir(t.Name(), TriggerTypePre, TriggerTypePost)
+		if !ok {
+			return nil, false, fmt.Errorf("invalid trigger type directory %q", path.Join("triggers", t.Name()))
+		}
+
+		operations, err := fs.ReadDir(fsys, path.Join("triggers", t.Name()))
+		if err != nil {
+			return nil, false, err
+		}
+
+		for _, o := range operations {
+			if !o.IsDir() {
+				continue
+			}
+
+			dir := path.Join("triggers", t.Name(), o.Name())
+
+			triggerOperation, ok := parseScriptDir(o.Name(), TriggerOperationAll, TriggerOperationCreate, TriggerOperationReplace, TriggerOperationDelete)
+			if !ok {
+				return nil, false, fmt.Errorf("invalid trigger operation directory %q", dir)
+			}
+
+			bodies, _, err := readScripts(fsys, dir)
+			if err != nil {
+				return nil, false, err
+			}
+
+			for id, body := range bodies {
+				if triggers[id] != nil {
+					return nil, false, fmt.Errorf("duplicate trigger %q", id)
+				}
+
+				triggers[id] = &Trigger{
+					ID:               id,
+					Body:             body,
+					TriggerType:      triggerType,
+					TriggerOperation: triggerOperation,
+				}
+			}
+		}
+	}
+
+	return triggers, true, nil
+}
+
+// parseScriptDir returns the value which matches the directory name,
+// case-insensitively
+func parseScriptDir[T ~string](name string, values ...T) (T, bool) {
+	for _, value := range values {
+		if strings.EqualFold(name, string(value)) {
+			return value, true
+		}
+	}
+
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,105 @@
+package cosmosdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Serializer encodes the bodies of requests and decodes the bodies of
+// responses, including documents.  It may be set per client with
+// SetSerializer; by default the JSON handle passed to NewDatabaseClient is
+// used.  A Serializer wrapping another JSON library, e.g. jsoniter, need only
+// adapt its encoder and decoder.
+type Serializer interface {
+	Encode(io.Writer, interface{}) error
+	Decode(io.Reader, interface{}) error
+}
+
+// JSONSerializer is a Serializer using encoding/json, which honours the
+// json.Marshaler and json.Unmarshaler implementations of documents
+type JSONSerializer struct{}
+
+func (*JSONSerializer) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (*JSONSerializer) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// codecSerializer is a Serializer using a github.com/ugorji/go/codec JSON
+// handle.  Its encoders and decoders are pooled, as each allocates buffers.
+type codecSerializer struct {
+	jsonHandle *codec.JsonHandle
+	encoders   sync.Pool
+	decoders   sync.Pool
+}
+
+// NewCodecSerializer returns a Serializer using jsonHandle
+func NewCodecSerializer(jsonHandle *codec.JsonHandle) Serializer {
+	return &codecSerializer{jsonHandle: jsonHandle}
+}
+
+func (s *codecSerializer) Encode(w io.Writer, v interface{}) error {
+	enc, ok := s.encoders.Get().(*codec.Encoder)
+	if ok {
+		enc.Reset(w)
+	} else {
+		enc = codec.NewEncoder(w, s.jsonHandle)
+	}
+
+	err := enc.Encode(v)
+	if err != nil {
+		// an encoder which failed is not reused
+		return err
+	}
+
+	enc.Reset(nil)
+	s.encoders.Put(enc)
+
+	return nil
+}
+
+func (s *codecSerializer) Decode(r io.Reader, v interface{}) error {
+	dec, ok := s.decoders.Get().(*codec.Decoder)
+	if ok {
+		dec.Reset(r)
+	} else {
+		dec = codec.NewDecoder(r, s.jsonHandle)
+	}
+
+	err := dec.Decode(v)
+	if err != nil {
+		return err
+	}
+
+	dec.Reset(nil)
+	s.decoders.Put(dec)
+
+	return nil
+}
+
+// SetSerializer sets the Serializer of the client and of the clients derived
+// from it
+func (c *databaseClient) SetSerializer(serializer Serializer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.serializer = serializer
+}
+
+func (c *databaseClient) getSerializer() Serializer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.serializer
+}
+
+// decode decodes the JSON b into out with the client's Serializer
+func (c *databaseClient) decode(b []byte, out interface{}) error {
+	return c.getSerializer().Decode(bytes.NewReader(b), out)
+}
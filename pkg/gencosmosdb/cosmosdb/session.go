@@ -0,0 +1,197 @@
+package cosmosdb
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Session tracks the session tokens returned by Cosmos DB so that subsequent
+// reads observe earlier writes under session consistency.  Session tokens are
+// held per collection and partition key range.  A Session can be shared
+// between clients, and exported and imported across processes using
+// MarshalText and UnmarshalText.
+type Session struct {
+	mu     sync.RWMutex
+	tokens map[string]map[string]string
+}
+
+// NewSession returns a new, empty Session
+func NewSession() *Session {
+	return &Session{tokens: map[string]map[string]string{}}
+}
+
+// Token returns the session token of the collection with the given link, or
+// the empty string if none is known
+func (s *Session) Token(collLink string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.token(collLink)
+}
+
+func (s *Session) token(collLink string) string {
+	ranges := s.tokens[collLink]
+	if len(ranges) == 0 {
+		return ""
+	}
+
+	tokens := make([]string, 0, len(ranges))
+	for rangeid, token := range ranges {
+		tokens = append(tokens, rangeid+":"+token)
+	}
+	sort.Strings(tokens)
+
+	return strings.Join(tokens, ",")
+}
+
+// SetToken merges the session token sessionToken into the tokens known for
+// the collection with the given link, keeping the most recent token per
+// partition key range
+func (s *Session) SetToken(collLink, sessionToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setToken(collLink, sessionToken)
+}
+
+func (s *Session) setToken(collLink, sessionToken string) {
+	if s.tokens == nil {
+		s.tokens = map[string]map[string]string{}
+	}
+
+	for _, rangeToken := range strings.Split(sessionToken, ",") {
+		rangeid, token, ok := strings.Cut(strings.TrimSpace(rangeToken), ":")
+		if !ok {
+			continue
+		}
+
+		if s.tokens[collLink] == nil {
+			s.tokens[collLink] = map[string]string{}
+		}
+
+		if existing, found := s.tokens[collLink][rangeid]; !found || sessionTokenLess(existing, token) {
+			s.tokens[collLink][rangeid] = token
+		}
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s *Session) MarshalText() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := map[string]string{}
+	for collLink := range s.tokens {
+		tokens[collLink] = s.token(collLink)
+	}
+
+	return json.Marshal(tokens)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.  The imported tokens are
+// merged into those already held by the Session.
+func (s *Session) UnmarshalText(b []byte) error {
+	var tokens map[string]string
+	err := json.Unmarshal(b, &tokens)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for collLink, token := range tokens {
+		s.setToken(collLink, token)
+	}
+
+	return nil
+}
+
+// sessionTokenLess returns true if the partition key range session token a is
+// older than b.  Tokens have the form "{lsn}" or
+// "{version}#{globalLSN}[#{region}={lsn}...]".
+func sessionTokenLess(a, b string) bool {
+	av, alsn := parseSessionToken(a)
+	bv, blsn := parseSessionToken(b)
+
+	if av != bv {
+		return av < bv
+	}
+	return alsn < blsn
+}
+
+func parseSessionToken(token string) (version, lsn int64) {
+	parts := strings.Split(token, "#")
+	if len(parts) == 1 {
+		lsn, _ = strconv.ParseInt(parts[0], 10, 64)
+		return
+	}
+
+	version, _ = strconv.ParseInt(parts[0], 10, 64)
+	lsn, _ = strconv.ParseInt(parts[1], 10, 64)
+	return
+}
+
+// SetSession sets or unsets the Session used to track session tokens for
+// requests which do not set Options.Session
+func (c *databaseClient) SetSession(session *Session) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.session = session
+}
+
+func (c *databaseClient) getSession(options *Options) *Session {
+	if options != nil && options.Session != nil {
+		return options.Session
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.session
+}
+
+// setSessionToken sets the session token header on read requests to
+// collection resources, unless the caller has set it explicitly
+func (c *databaseClient) setSessionToken(req *http.Request, method, resourceLink string, headers http.Header, options *Options) {
+	session := c.getSession(options)
+	if session == nil || !isReadRequest(method, headers) || headers.Get("X-Ms-Session-Token") != "" {
+		return
+	}
+
+	collLink, ok := collectionLink(resourceLink)
+	if !ok {
+		return
+	}
+
+	if token := session.Token(collLink); token != "" {
+		req.Header.Set("X-Ms-Session-Token", token)
+	} else {
+		req.Header.Del("X-Ms-Session-Token")
+	}
+}
+
+// updateSessionToken records the session token returned with a response
+func (c *databaseClient) updateSessionToken(resp *http.Response, resourceLink string, options *Options) {
+	session := c.getSession(options)
+	if session == nil {
+		return
+	}
+
+	token := resp.Header.Get("X-Ms-Session-Token")
+	if token == "" {
+		return
+	}
+
+	collLink, ok := collectionLink(resourceLink)
+	if !ok {
+		return
+	}
+
+	session.SetToken(collLink, token)
+}
@@ -0,0 +1,228 @@
+package cosmosdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// StoredProcedure represents a stored procedure
+type StoredProcedure struct {
+	ID         string `json:"id,omitempty"`
+	ResourceID string `json:"_rid,omitempty"`
+	Timestamp  int    `json:"_ts,omitempty"`
+	Self       string `json:"_self,omitempty"`
+	ETag       string `json:"_etag,omitempty"`
+	Body       string `json:"body,omitempty"`
+}
+
+// StoredProcedures represents stored procedures
+type StoredProcedures struct {
+	Count            int                `json:"_count,omitempty"`
+	ResourceID       string             `json:"_rid,omitempty"`
+	StoredProcedures []*StoredProcedure `json:"StoredProcedures,omitempty"`
+}
+
+// StoredProcedurePage is the value which a stored procedure executed with
+// ExecuteAll returns: its results and, if it stopped early because a request
+// was not accepted within its execution bounds, the state from which it is to
+// continue
+type StoredProcedurePage struct {
+	Results      []json.RawMessage `json:"results,omitempty"`
+	Continuation interface{}       `json:"continuation,omitempty"`
+}
+
+type storedProcedureClient struct {
+	*databaseClient
+	path string
+}
+
+// StoredProcedureClient is a stored procedure client
+type StoredProcedureClient interface {
+	Create(context.Context, *StoredProcedure) (*StoredProcedure, error)
+	List() StoredProcedureIterator
+	ListAll(context.Context) (*StoredProcedures, error)
+	Query(*Query) StoredProcedureIterator
+	QueryAll(context.Context, *Query) (*StoredProcedures, error)
+	Get(context.Context, string) (*StoredProcedure, error)
+	Delete(context.Context, *StoredProcedure) error
+	Replace(context.Context, *StoredProcedure) (*StoredProcedure, error)
+	Execute(context.Context, string, string, []interface{}, interface{}, *Options) error
+	ExecuteAll(context.Context, string, string, []interface{}, interface{}, *Options) error
+}
+
+type storedProcedureListIterator struct {
+	*storedProcedureClient
+	query        *Query
+	continuation string
+	done         bool
+}
+
+// StoredProcedureIterator is a stored procedure iterator
+type StoredProcedureIterator interface {
+	Next(context.Context) (*StoredProcedures, error)
+	Items(context.Context) func(func(*StoredProcedure, error) bool)
+}
+
+// NewStoredProcedureClient returns a new stored procedure client
+func NewStoredProcedureClient(collc CollectionClient, collid string) StoredProcedureClient {
+	return &storedProcedureClient{
+		databaseClient: collc.(*collectionClient).databaseClient,
+		path:           collc.(*collectionClient).path + "/colls/" + collid,
+	}
+}
+
+func (c *storedProcedureClient) all(ctx context.Context, i StoredProcedureIterator) (*StoredProcedures, error) {
+	allsprocs := &StoredProcedures{}
+
+	for {
+		sprocs, err := i.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if sprocs == nil {
+			break
+		}
+
+		allsprocs.Count += sprocs.Count
+		allsprocs.ResourceID = sprocs.ResourceID
+		allsprocs.StoredProcedures = append(allsprocs.StoredProcedures, sprocs.StoredProcedures...)
+	}
+
+	return allsprocs, nil
+}
+
+func (c *storedProcedureClient) Create(ctx context.Context, newsproc *StoredProcedure) (sproc *StoredProcedure, err error) {
+	err = c.do(ctx, http.MethodPost, c.path+"/sprocs", "sprocs", c.path, http.StatusCreated, &newsproc, &sproc, nil, nil)
+	return
+}
+
+func (c *storedProcedureClient) List() StoredProcedureIterator {
+	return &storedProcedureListIterator{storedProcedureClient: c}
+}
+
+func (c *storedProcedureClient) ListAll(ctx context.Context) (*StoredProcedures, error) {
+	return c.all(ctx, c.List())
+}
+
+func (c *storedProcedureClient) Query(query *Query) StoredProcedureIterator {
+	return &storedProcedureListIterator{storedProcedureClient: c, query: query}
+}
+
+func (c *storedProcedureClient) QueryAll(ctx context.Context, query *Query) (*StoredProcedures, error) {
+	return c.all(ctx, c.Query(query))
+}
+
+func (c *storedProcedureClient) Get(ctx context.Context, sprocid string) (sproc *StoredProcedure, err error) {
+	err = c.do(ctx, http.MethodGet, c.path+"/sprocs/"+sprocid, "sprocs", c.path+"/sprocs/"+sprocid, http.StatusOK, nil, &sproc, nil, nil)
+	return
+}
+
+func (c *storedProcedureClient) Delete(ctx context.Context, sproc *StoredProcedure) error {
+	if sproc.ETag == "" {
+		return ErrETagRequired
+	}
+	headers := http.Header{}
+	headers.Set("If-Match", sproc.ETag)
+	return c.do(ctx, http.MethodDelete, c.path+"/sprocs/"+sproc.ID, "sprocs", c.path+"/sprocs/"+sproc.ID, http.StatusNoContent, nil, nil, headers, nil)
+}
+
+func (c *storedProcedureClient) Replace(ctx context.Context, newsproc *StoredProcedure) (sproc *StoredProcedure, err error) {
+	headers := http.Header{}
+	if newsproc.ETag != "" {
+		headers.Set("If-Match", newsproc.ETag)
+	}
+
+	err = c.do(ctx, http.MethodPut, c.path+"/sprocs/"+newsproc.ID, "sprocs", c.path+"/sprocs/"+newsproc.ID, http.StatusOK, &newsproc, &sproc, headers, nil)
+	return
+}
+
+// Execute executes the stored procedure sprocid in the partition partitionkey
+// with the parameters params, decoding the value it returns into out.  If
+// options.EnableScriptLogging is set, the script's console.log output is
+// made available in options.ResponseInfo.
+func (c *storedProcedureClient) Execute(ctx context.Context, sprocid, partitionkey string, params []interface{}, out interface{}, options *Options) error {
+	headers := http.Header{}
+	if partitionkey != "" {
+		headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
+	}
+	if options != nil && options.EnableScriptLogging {
+		headers.Set("X-Ms-Documentdb-Script-Enable-Logging", "true")
+	}
+
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	return c.do(ctx, http.MethodPost, c.path+"/sprocs/"+sprocid, "sprocs", c.path+"/sprocs/"+sprocid, http.StatusOK, &params, out, headers, options)
+}
+
+// ExecuteAll executes the stored procedure sprocid, which returns a
+// StoredProcedurePage, until it completes, decoding the concatenation of its
+// results into out, which must point to a slice.  The stored procedure is
+// passed params followed by the continuation it last returned, or null the
+// first time.
+func (c *storedProcedureClient) ExecuteAll(ctx context.Context, sprocid, partitionkey string, params []interface{}, out interface{}, options *Options) error {
+	var results []json.RawMessage
+	var continuation interface{}
+
+	for {
+		var page *StoredProcedurePage
+		err := c.Execute(ctx, sprocid, partitionkey, append(params[:len(params):len(params)], continuation), &page, options)
+		if err != nil {
+			return err
+		}
+		if page == nil {
+			break
+		}
+
+		results = append(results, page.Results...)
+
+		continuation = page.Continuation
+		if continuation == nil {
+			break
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteByte('[')
+	for i, result := range results {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(result)
+	}
+	buf.WriteByte(']')
+
+	return c.decode(buf.Bytes(), out)
+}
+
+func (i *storedProcedureListIterator) Next(ctx context.Context) (sprocs *StoredProcedures, err error) {
+	if i.done {
+		return
+	}
+
+	headers := http.Header{}
+	if i.continuation != "" {
+		headers.Set("X-Ms-Continuation", i.continuation)
+	}
+
+	if i.query != nil {
+		err = i.doQuery(ctx, i.path+"/sprocs", "sprocs", i.path, i.query, &sprocs, headers, nil)
+	} else {
+		err = i.do(ctx, http.MethodGet, i.path+"/sprocs", "sprocs", i.path, http.StatusOK, nil, &sprocs, headers, nil)
+	}
+	if err != nil {
+		return
+	}
+
+	i.continuation = headers.Get("X-Ms-Continuation")
+	i.done = i.continuation == ""
+
+	return
+}
+
+func (i *storedProcedureListIterator) Items(ctx context.Context) func(func(*StoredProcedure, error) bool) {
+	return items(ctx, i.Next, func(sprocs *StoredProcedures) []*StoredProcedure { return sprocs.StoredProcedures })
+}
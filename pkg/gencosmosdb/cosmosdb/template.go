@@ -2,6 +2,7 @@ package cosmosdb
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -19,18 +20,34 @@ type TemplateClient interface {
 	Create(context.Context, string, *pkg.Template, *Options) (*pkg.Template, error)
 	List(*Options) TemplateIterator
 	ListAll(context.Context, *Options) (*pkg.Templates, error)
+	ForEach(context.Context, *Options, func(*pkg.Template) error) error
 	Get(context.Context, string, string, *Options) (*pkg.Template, error)
 	Replace(context.Context, string, *pkg.Template, *Options) (*pkg.Template, error)
+	Patch(context.Context, string, string, []PatchOperation, *Options) (*pkg.Template, error)
+	SetTTL(context.Context, string, string, int, *Options) (*pkg.Template, error)
+	ExecuteBatch(context.Context, string, *TemplateBatch, *Options) (*TemplateBatchResponse, error)
+	ExecuteBulk(context.Context, *TemplateBulk, *Options) ([]*TemplateBatchResult, error)
 	Delete(context.Context, string, *pkg.Template, *Options) error
 	Query(string, *Query, *Options) TemplateRawIterator
 	QueryAll(context.Context, string, *Query, *Options) (*pkg.Templates, error)
+	QueryPage(context.Context, string, *Query, *PageRequest, *Options) (*pkg.Templates, *PageRequest, error)
+	ReadMany(context.Context, []ItemIdentity, *Options) (*pkg.Templates, []ItemIdentity, error)
 	ChangeFeed(*Options) TemplateIterator
+	AllVersionsChangeFeed(*Options) TemplateChangeFeedItemIterator
+	ChangeFeedProcessor(CollectionClient, string, func(context.Context, *pkg.Templates) error, *ChangeFeedProcessorOptions) ChangeFeedProcessor
+	ChangeFeedEstimator(CollectionClient, string, *ChangeFeedProcessorOptions) ChangeFeedEstimator
 }
 
 type templateChangeFeedIterator struct {
 	*templateClient
 	continuation string
 	options      *Options
+	feed         *changeFeed
+	reader       *documentReader
+}
+
+type templateChangeFeedItemIterator struct {
+	feed *changeFeed
 }
 
 type templateListIterator struct {
@@ -38,27 +55,66 @@ type templateListIterator struct {
 	continuation string
 	done         bool
 	options      *Options
+	reader       *documentReader
 }
 
 type templateQueryIterator struct {
 	*templateClient
-	partitionkey string
-	query        *Query
-	continuation string
-	done         bool
-	options      *Options
+	partitionkey   string
+	query          *Query
+	continuation   string
+	done           bool
+	options        *Options
+	crossPartition *crossPartitionQuery
+	routed         bool
+	metrics        QueryMetrics
+	reader         *documentReader
 }
 
-// TemplateIterator is a template iterator
+// TemplateIterator is a template iterator.  NextDocument returns documents
+// one at a time, decoding each as it is read from the response; it returns
+// nil when the iterator is exhausted.  Continuation reflects whole pages, so
+// it should not be used after partially reading a page with NextDocument.
 type TemplateIterator interface {
 	Next(context.Context, int) (*pkg.Templates, error)
+	NextDocument(context.Context) (*pkg.Template, error)
 	Continuation() string
+	Items(context.Context) func(func(*pkg.Template, error) bool)
+	ForEach(context.Context, func(*pkg.Template) error) error
+	Stream(context.Context, int) (<-chan *pkg.Templates, func() error)
+}
+
+// TemplateChangeFeedItemIterator is an iterator of the all versions and
+// deletes change feed of a template collection.  Next returns nil if there
+// are no further changes, but later calls return any changes made since.
+type TemplateChangeFeedItemIterator interface {
+	Next(context.Context, int) (*TemplateChangeFeedItems, error)
+	Continuation() string
+}
+
+// TemplateChangeFeedItem represents a change in the all versions and deletes
+// change feed of a template collection.  Current is empty for a delete;
+// Previous is only set for replaces and deletes if the collection retains
+// previous images.
+type TemplateChangeFeedItem struct {
+	Current  *pkg.Template      `json:"current,omitempty"`
+	Previous *pkg.Template      `json:"previous,omitempty"`
+	Metadata ChangeFeedMetadata `json:"metadata,omitempty"`
+}
+
+// TemplateChangeFeedItems represents a page of changes in the all versions
+// and deletes change feed of a template collection
+type TemplateChangeFeedItems struct {
+	Count      int                       `json:"_count,omitempty"`
+	ResourceID string                    `json:"_rid,omitempty"`
+	Items      []*TemplateChangeFeedItem `json:"Documents,omitempty"`
 }
 
 // TemplateRawIterator is a template raw iterator
 type TemplateRawIterator interface {
 	TemplateIterator
 	NextRaw(context.Context, int, interface{}) error
+	QueryMetrics() *QueryMetrics
 }
 
 // NewTemplateClient returns a new template client
@@ -91,7 +147,7 @@ func (c *templateClient) all(ctx context.Context, i TemplateIterator) (*pkg.Temp
 
 func (c *templateClient) Create(ctx context.Context, partitionkey string, newtemplate *pkg.Template, options *Options) (template *pkg.Template, err error) {
 	headers := http.Header{}
-	headers.Set("X-Ms-Documentdb-Partitionkey", `["`+partitionkey+`"]`)
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
 
 	if options == nil {
 		options = &Options{}
@@ -103,7 +159,7 @@ func (c *templateClient) Create(ctx context.Context, partitionkey string, newtem
 		return
 	}
 
-	err = c.do(ctx, http.MethodPost, c.path+"/docs", "docs", c.path, http.StatusCreated, &newtemplate, &template, headers)
+	_, err = c.send(ctx, http.MethodPost, c.path+"/docs", "docs", c.path, http.StatusCreated, &newtemplate, &template, headers, options)
 	return
 }
 
@@ -120,42 +176,150 @@ func (c *templateClient) ListAll(ctx context.Context, options *Options) (*pkg.Te
 	return c.all(ctx, c.List(options))
 }
 
+// ForEach calls fn with each Template in the collection, fetching a page at a
+// time, until fn returns an error.  Unlike ListAll, it does not accumulate the
+// Templates in memory.
+func (c *templateClient) ForEach(ctx context.Context, options *Options, fn func(*pkg.Template) error) error {
+	return c.List(options).ForEach(ctx, fn)
+}
+
 func (c *templateClient) Get(ctx context.Context, partitionkey, templateid string, options *Options) (template *pkg.Template, err error) {
 	headers := http.Header{}
-	headers.Set("X-Ms-Documentdb-Partitionkey", `["`+partitionkey+`"]`)
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
+	if options != nil && options.IfNoneMatch != "" {
+		headers.Set("If-None-Match", options.IfNoneMatch)
+	}
 
 	err = c.setOptions(options, nil, headers)
 	if err != nil {
 		return
 	}
 
-	err = c.do(ctx, http.MethodGet, c.path+"/docs/"+templateid, "docs", c.path+"/docs/"+templateid, http.StatusOK, nil, &template, headers)
+	_, err = c.send(ctx, http.MethodGet, c.path+"/docs/"+templateid, "docs", c.path+"/docs/"+templateid, http.StatusOK, nil, &template, headers, options)
 	return
 }
 
 func (c *templateClient) Replace(ctx context.Context, partitionkey string, newtemplate *pkg.Template, options *Options) (template *pkg.Template, err error) {
 	headers := http.Header{}
-	headers.Set("X-Ms-Documentdb-Partitionkey", `["`+partitionkey+`"]`)
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
 
 	err = c.setOptions(options, newtemplate, headers)
 	if err != nil {
 		return
 	}
 
-	err = c.do(ctx, http.MethodPut, c.path+"/docs/"+newtemplate.ID, "docs", c.path+"/docs/"+newtemplate.ID, http.StatusOK, &newtemplate, &template, headers)
+	_, err = c.send(ctx, http.MethodPut, c.path+"/docs/"+newtemplate.ID, "docs", c.path+"/docs/"+newtemplate.ID, http.StatusOK, &newtemplate, &template, headers, options)
 	return
 }
 
+// Patch applies operations to the Template with the given ID without
+// replacing it, so that no ETag is required
+func (c *templateClient) Patch(ctx context.Context, partitionkey, templateid string, operations []PatchOperation, options *Options) (template *pkg.Template, err error) {
+	headers := http.Header{}
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
+	headers.Set("X-Ms-Version", patchAPIVersion)
+	headers.Set("Content-Type", "application/json_patch+json")
+
+	err = c.setOptions(options, nil, headers)
+	if err != nil {
+		return
+	}
+
+	patch := &patchRequest{Operations: operations}
+	if options != nil {
+		patch.Condition = options.PatchCondition
+	}
+
+	_, err = c.send(ctx, http.MethodPatch, c.path+"/docs/"+templateid, "docs", c.path+"/docs/"+templateid, http.StatusOK, patch, &template, headers, options)
+	return
+}
+
+// SetTTL sets the TTL in seconds of the Template with the given ID, without
+// replacing it.  See TTLNoExpiry and TTLOff.
+func (c *templateClient) SetTTL(ctx context.Context, partitionkey, templateid string, ttl int, options *Options) (*pkg.Template, error) {
+	return c.Patch(ctx, partitionkey, templateid, []PatchOperation{ttlPatch(ttl)}, options)
+}
+
+// ExecuteBatch executes the operations of batch atomically.  An error is
+// returned only if the batch could not be executed; if an operation fails,
+// the batch is rolled back and the failure is reported in the response.
+func (c *templateClient) ExecuteBatch(ctx context.Context, partitionkey string, batch *TemplateBatch, options *Options) (*TemplateBatchResponse, error) {
+	operations, err := prepareBatch(batch.operations, options)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := http.Header{}
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
+	setBatchHeaders(headers, true)
+
+	err = c.setOptions(options, nil, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &batchResponse{}
+	err = c.do(ctx, http.MethodPost, c.path+"/docs", "docs", c.path, http.StatusOK, operations, resp, headers, options)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := c.batchResults(resp.results)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TemplateBatchResponse{
+		StatusCode: resp.statusCode,
+		Results:    results,
+	}, nil
+}
+
+// ExecuteBulk executes the operations of bulk, grouped into batches by the
+// partition key range to which their partition keys belong.  Unlike a
+// TemplateBatch, the operations succeed or fail independently; the result of
+// each is returned in order.
+func (c *templateClient) ExecuteBulk(ctx context.Context, bulk *TemplateBulk, options *Options) ([]*TemplateBatchResult, error) {
+	results, err := c.executeBulk(ctx, c.path, bulk.partitionkeys, bulk.operations, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.batchResults(results)
+}
+
+func (c *templateClient) batchResults(results []batchResult) ([]*TemplateBatchResult, error) {
+	templateResults := make([]*TemplateBatchResult, len(results))
+
+	for i, result := range results {
+		templateResults[i] = &TemplateBatchResult{
+			StatusCode:    result.StatusCode,
+			SubStatusCode: result.SubStatusCode,
+			RequestCharge: result.RequestCharge,
+			ETag:          result.ETag,
+		}
+
+		if len(result.ResourceBody) > 0 {
+			err := c.decode(result.ResourceBody, &templateResults[i].Template)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return templateResults, nil
+}
+
 func (c *templateClient) Delete(ctx context.Context, partitionkey string, template *pkg.Template, options *Options) (err error) {
 	headers := http.Header{}
-	headers.Set("X-Ms-Documentdb-Partitionkey", `["`+partitionkey+`"]`)
+	headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(partitionkey))
 
 	err = c.setOptions(options, template, headers)
 	if err != nil {
 		return
 	}
 
-	err = c.do(ctx, http.MethodDelete, c.path+"/docs/"+template.ID, "docs", c.path+"/docs/"+template.ID, http.StatusNoContent, nil, nil, headers)
+	_, err = c.send(ctx, http.MethodDelete, c.path+"/docs/"+template.ID, "docs", c.path+"/docs/"+template.ID, http.StatusNoContent, nil, nil, headers, options)
 	return
 }
 
@@ -165,20 +329,169 @@ func (c *templateClient) Query(partitionkey string, query *Query, options *Optio
 		continuation = options.Continuation
 	}
 
-	return &templateQueryIterator{templateClient: c, partitionkey: partitionkey, query: query, options: options, continuation: continuation}
+	i := &templateQueryIterator{templateClient: c, partitionkey: partitionkey, query: query, options: options, continuation: continuation}
+
+	// without a partition key or range, fan the query out across all
+	// partition key ranges
+	if partitionkey == "" && (options == nil || options.PartitionKeyRangeID == "" && options.PartitionKey == "") {
+		i.crossPartition = c.newCrossPartitionQuery(c.path, query, options, continuation)
+	}
+
+	return i
 }
 
 func (c *templateClient) QueryAll(ctx context.Context, partitionkey string, query *Query, options *Options) (*pkg.Templates, error) {
 	return c.all(ctx, c.Query(partitionkey, query, options))
 }
 
+// QueryPage returns the requested page of the results of query and the
+// request for the following page, or nil if there are no further results
+func (c *templateClient) QueryPage(ctx context.Context, partitionkey string, query *Query, page *PageRequest, options *Options) (*pkg.Templates, *PageRequest, error) {
+	if page.PageSize <= 0 {
+		return nil, nil, ErrInvalidPageRequest
+	}
+
+	if page.Continuation == "" && page.number() > 1 {
+		templates, err := c.QueryAll(ctx, partitionkey, page.offsetQuery(query), options)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return templates, page.next(len(templates.Templates), ""), nil
+	}
+
+	opts := Options{}
+	if options != nil {
+		opts = *options
+	}
+	opts.Continuation = page.Continuation
+	opts.MaxItemCount = 0
+
+	i := c.Query(partitionkey, query, &opts)
+
+	alltemplates := &pkg.Templates{}
+	for len(alltemplates.Templates) < page.PageSize {
+		templates, err := i.Next(ctx, page.PageSize-len(alltemplates.Templates))
+		if err != nil {
+			return nil, nil, err
+		}
+		if templates == nil {
+			return alltemplates, nil, nil
+		}
+
+		alltemplates.Count += templates.Count
+		alltemplates.ResourceID = templates.ResourceID
+		alltemplates.Templates = append(alltemplates.Templates, templates.Templates...)
+	}
+
+	return alltemplates, page.next(len(alltemplates.Templates), i.Continuation()), nil
+}
+
+// ReadMany reads the Templates identified by items, returning those found in
+// the order requested and the identities of those which were not.  The IDs of
+// each partition key are read with a point read or a single query, and
+// partition keys are read concurrently up to Options.MaxDegreeOfParallelism.
+func (c *templateClient) ReadMany(ctx context.Context, items []ItemIdentity, options *Options) (*pkg.Templates, []ItemIdentity, error) {
+	groups := groupReadMany(items)
+	found := make([][]*pkg.Template, len(groups))
+	errs := make([]error, len(groups))
+
+	parallel(options.maxDegreeOfParallelism(), len(groups), func(i int) {
+		g := groups[i]
+
+		if len(g.ids) == 1 {
+			template, err := c.Get(ctx, g.partitionkey, g.ids[0], options)
+			if IsErrorStatusCode(err, http.StatusNotFound) {
+				return
+			}
+			found[i], errs[i] = []*pkg.Template{template}, err
+			return
+		}
+
+		templates, err := c.QueryAll(ctx, g.partitionkey, g.query(), options)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		found[i] = templates.Templates
+	})
+
+	byIdentity := map[ItemIdentity]*pkg.Template{}
+	for i, g := range groups {
+		if errs[i] != nil {
+			return nil, nil, errs[i]
+		}
+		for _, template := range found[i] {
+			byIdentity[ItemIdentity{ID: template.ID, PartitionKey: g.partitionkey}] = template
+		}
+	}
+
+	templates := &pkg.Templates{}
+	var missing []ItemIdentity
+	for _, item := range items {
+		if template, ok := byIdentity[item]; ok {
+			templates.Templates = append(templates.Templates, template)
+		} else {
+			missing = append(missing, item)
+		}
+	}
+	templates.Count = len(templates.Templates)
+
+	return templates, missing, nil
+}
+
 func (c *templateClient) ChangeFeed(options *Options) TemplateIterator {
 	continuation := ""
 	if options != nil {
 		continuation = options.Continuation
 	}
 
-	return &templateChangeFeedIterator{templateClient: c, options: options, continuation: continuation}
+	i := &templateChangeFeedIterator{templateClient: c, options: options, continuation: continuation}
+
+	// without a partition key range, read the change feed of every range
+	if options == nil || options.PartitionKeyRangeID == "" {
+		i.feed = c.newChangeFeed(c.path, options, continuation)
+	}
+
+	return i
+}
+
+// AllVersionsChangeFeed returns an iterator of the all versions and deletes
+// change feed of the collection, which returns every create, replace and
+// delete made since it was first read, or since options.Continuation
+func (c *templateClient) AllVersionsChangeFeed(options *Options) TemplateChangeFeedItemIterator {
+	continuation := ""
+	if options != nil {
+		continuation = options.Continuation
+	}
+
+	feed := c.newChangeFeed(c.path, options, continuation)
+	feed.allVersions = true
+
+	return &templateChangeFeedItemIterator{feed: feed}
+}
+
+// ChangeFeedProcessor returns a ChangeFeedProcessor of the collection which
+// stores its leases in the collection leaseCollID of leasec, or in
+// options.CheckpointStore if it is set, and calls handler with each batch of
+// changes
+func (c *templateClient) ChangeFeedProcessor(leasec CollectionClient, leaseCollID string, handler func(context.Context, *pkg.Templates) error, options *ChangeFeedProcessorOptions) ChangeFeedProcessor {
+	return c.newChangeFeedProcessor(c.path, leasec, leaseCollID, func(ctx context.Context, page *queryPage) error {
+		var templates *pkg.Templates
+		err := c.decodePage(page, &templates)
+		if err != nil {
+			return err
+		}
+
+		return handler(ctx, templates)
+	}, options)
+}
+
+// ChangeFeedEstimator returns a ChangeFeedEstimator of the change feed
+// processors of the collection configured with the same lease collection and
+// options
+func (c *templateClient) ChangeFeedEstimator(leasec CollectionClient, leaseCollID string, options *ChangeFeedProcessorOptions) ChangeFeedEstimator {
+	return c.newChangeFeedProcessor(c.path, leasec, leaseCollID, nil, options)
 }
 
 func (c *templateClient) setOptions(options *Options, template *pkg.Template, headers http.Header) error {
@@ -186,6 +499,11 @@ func (c *templateClient) setOptions(options *Options, template *pkg.Template, he
 		return nil
 	}
 
+	err := options.validateTriggers()
+	if err != nil {
+		return err
+	}
+
 	if template != nil && !options.NoETag {
 		if template.ETag == "" {
 			return ErrETagRequired
@@ -201,17 +519,46 @@ func (c *templateClient) setOptions(options *Options, template *pkg.Template, he
 	if len(options.PartitionKeyRangeID) > 0 {
 		headers.Set("X-Ms-Documentdb-PartitionKeyRangeID", options.PartitionKeyRangeID)
 	}
+	if options.IndexingDirective != "" {
+		headers.Set("X-Ms-Indexing-Directive", string(options.IndexingDirective))
+	}
+	options.setFeedHeaders(headers)
 
 	return nil
 }
 
 func (i *templateChangeFeedIterator) Next(ctx context.Context, maxItemCount int) (templates *pkg.Templates, err error) {
+	if i.feed != nil {
+		err = i.feed.nextRaw(ctx, maxItemCount, &templates)
+		return
+	}
+
+	err = i.next(ctx, maxItemCount, &templates)
+	return
+}
+
+func (i *templateChangeFeedIterator) NextDocument(ctx context.Context) (template *pkg.Template, err error) {
+	if i.feed != nil {
+		var ok bool
+		ok, err = i.feed.nextDocument(ctx, &template)
+		if !ok {
+			template = nil
+		}
+		return
+	}
+
+	return i.nextDocument(ctx, &i.reader, func(body *io.ReadCloser) error { return i.next(ctx, -1, body) })
+}
+
+func (i *templateChangeFeedIterator) next(ctx context.Context, maxItemCount int, out interface{}) (err error) {
 	headers := http.Header{}
 	headers.Set("A-IM", "Incremental feed")
 
 	headers.Set("X-Ms-Max-Item-Count", strconv.Itoa(maxItemCount))
 	if i.continuation != "" {
 		headers.Set("If-None-Match", i.continuation)
+	} else if i.options != nil {
+		i.options.ChangeFeedStartFrom.setHeaders(headers)
 	}
 
 	err = i.setOptions(i.options, nil, headers)
@@ -219,7 +566,7 @@ func (i *templateChangeFeedIterator) Next(ctx context.Context, maxItemCount int)
 		return
 	}
 
-	err = i.do(ctx, http.MethodGet, i.path+"/docs", "docs", i.path, http.StatusOK, nil, &templates, headers)
+	err = i.do(ctx, http.MethodGet, i.path+"/docs", "docs", i.path, http.StatusOK, nil, out, headers, i.options)
 	if IsErrorStatusCode(err, http.StatusNotModified) {
 		err = nil
 	}
@@ -233,10 +580,44 @@ func (i *templateChangeFeedIterator) Next(ctx context.Context, maxItemCount int)
 }
 
 func (i *templateChangeFeedIterator) Continuation() string {
+	if i.feed != nil {
+		return i.feed.Continuation()
+	}
+
 	return i.continuation
 }
 
+func (i *templateChangeFeedIterator) Items(ctx context.Context) func(func(*pkg.Template, error) bool) {
+	return templateItems(ctx, i)
+}
+
+func (i *templateChangeFeedIterator) ForEach(ctx context.Context, fn func(*pkg.Template) error) error {
+	return templateForEach(ctx, i, fn)
+}
+
+func (i *templateChangeFeedIterator) Stream(ctx context.Context, buffer int) (<-chan *pkg.Templates, func() error) {
+	return templateStream(ctx, i, buffer)
+}
+
+func (i *templateChangeFeedItemIterator) Next(ctx context.Context, maxItemCount int) (items *TemplateChangeFeedItems, err error) {
+	err = i.feed.nextRaw(ctx, maxItemCount, &items)
+	return
+}
+
+func (i *templateChangeFeedItemIterator) Continuation() string {
+	return i.feed.Continuation()
+}
+
 func (i *templateListIterator) Next(ctx context.Context, maxItemCount int) (templates *pkg.Templates, err error) {
+	err = i.next(ctx, maxItemCount, &templates)
+	return
+}
+
+func (i *templateListIterator) NextDocument(ctx context.Context) (*pkg.Template, error) {
+	return i.nextDocument(ctx, &i.reader, func(body *io.ReadCloser) error { return i.next(ctx, -1, body) })
+}
+
+func (i *templateListIterator) next(ctx context.Context, maxItemCount int, out interface{}) (err error) {
 	if i.done {
 		return
 	}
@@ -252,7 +633,7 @@ func (i *templateListIterator) Next(ctx context.Context, maxItemCount int) (temp
 		return
 	}
 
-	err = i.do(ctx, http.MethodGet, i.path+"/docs", "docs", i.path, http.StatusOK, nil, &templates, headers)
+	err = i.do(ctx, http.MethodGet, i.path+"/docs", "docs", i.path, http.StatusOK, nil, out, headers, i.options)
 	if err != nil {
 		return
 	}
@@ -267,22 +648,59 @@ func (i *templateListIterator) Continuation() string {
 	return i.continuation
 }
 
+func (i *templateListIterator) Items(ctx context.Context) func(func(*pkg.Template, error) bool) {
+	return templateItems(ctx, i)
+}
+
+func (i *templateListIterator) ForEach(ctx context.Context, fn func(*pkg.Template) error) error {
+	return templateForEach(ctx, i, fn)
+}
+
+func (i *templateListIterator) Stream(ctx context.Context, buffer int) (<-chan *pkg.Templates, func() error) {
+	return templateStream(ctx, i, buffer)
+}
+
 func (i *templateQueryIterator) Next(ctx context.Context, maxItemCount int) (templates *pkg.Templates, err error) {
 	err = i.NextRaw(ctx, maxItemCount, &templates)
 	return
 }
 
+func (i *templateQueryIterator) NextDocument(ctx context.Context) (template *pkg.Template, err error) {
+	err = i.route(ctx)
+	if err != nil {
+		return
+	}
+
+	if i.crossPartition != nil {
+		var ok bool
+		ok, err = i.crossPartition.nextDocument(ctx, &template)
+		if !ok {
+			template = nil
+		}
+		return
+	}
+
+	return i.nextDocument(ctx, &i.reader, func(body *io.ReadCloser) error { return i.NextRaw(ctx, -1, body) })
+}
+
 func (i *templateQueryIterator) NextRaw(ctx context.Context, maxItemCount int, raw interface{}) (err error) {
+	err = i.route(ctx)
+	if err != nil {
+		return
+	}
+
+	if i.crossPartition != nil {
+		return i.crossPartition.nextRaw(ctx, maxItemCount, raw)
+	}
+
 	if i.done {
 		return
 	}
 
 	headers := http.Header{}
 	headers.Set("X-Ms-Max-Item-Count", strconv.Itoa(maxItemCount))
-	headers.Set("X-Ms-Documentdb-Isquery", "True")
-	headers.Set("Content-Type", "application/query+json")
 	if i.partitionkey != "" {
-		headers.Set("X-Ms-Documentdb-Partitionkey", `["`+i.partitionkey+`"]`)
+		headers.Set("X-Ms-Documentdb-Partitionkey", encodePartitionKey(i.partitionkey))
 	} else {
 		headers.Set("X-Ms-Documentdb-Query-Enablecrosspartition", "True")
 	}
@@ -295,17 +713,240 @@ func (i *templateQueryIterator) NextRaw(ctx context.Context, maxItemCount int, r
 		return
 	}
 
-	err = i.do(ctx, http.MethodPost, i.path+"/docs", "docs", i.path, http.StatusOK, &i.query, &raw, headers)
+	err = i.doQuery(ctx, i.path+"/docs", "docs", i.path, i.query, raw, headers, i.options)
 	if err != nil {
 		return
 	}
 
+	i.metrics.add(headers)
+
 	i.continuation = headers.Get("X-Ms-Continuation")
 	i.done = i.continuation == ""
 
 	return
 }
 
+// route makes a query by a prefix of a hierarchical partition key a
+// cross-partition query of the partition key ranges which may hold its results
+func (i *templateQueryIterator) route(ctx context.Context) (err error) {
+	if i.routed || i.crossPartition != nil {
+		return
+	}
+
+	i.crossPartition, err = i.prefixQuery(ctx, i.path, i.partitionkey, i.query, i.options, i.continuation)
+	if err != nil {
+		return
+	}
+
+	i.routed = true
+	return
+}
+
 func (i *templateQueryIterator) Continuation() string {
+	if i.crossPartition != nil {
+		return i.crossPartition.Continuation()
+	}
+
 	return i.continuation
 }
+
+func (i *templateQueryIterator) QueryMetrics() *QueryMetrics {
+	if i.crossPartition != nil {
+		metrics := i.crossPartition.metrics
+		return &metrics
+	}
+
+	metrics := i.metrics
+	return &metrics
+}
+
+func (i *templateQueryIterator) Items(ctx context.Context) func(func(*pkg.Template, error) bool) {
+	return templateItems(ctx, i)
+}
+
+func (i *templateQueryIterator) ForEach(ctx context.Context, fn func(*pkg.Template) error) error {
+	return templateForEach(ctx, i, fn)
+}
+
+func (i *templateQueryIterator) Stream(ctx context.Context, buffer int) (<-chan *pkg.Templates, func() error) {
+	return templateStream(ctx, i, buffer)
+}
+
+// templateItems yields each template returned by i, fetching pages of the
+// server's default size
+func templateItems(ctx context.Context, i TemplateIterator) func(func(*pkg.Template, error) bool) {
+	next := func(ctx context.Context) (*pkg.Templates, error) { return i.Next(ctx, -1) }
+	return items(ctx, next, func(templates *pkg.Templates) []*pkg.Template { return templates.Templates })
+}
+
+// templateForEach calls fn with each template returned by i, fetching pages of
+// the server's default size, until fn returns an error
+func templateForEach(ctx context.Context, i TemplateIterator, fn func(*pkg.Template) error) error {
+	return forEach(i.Items(ctx), fn)
+}
+
+// templateStream fetches pages of the server's default size from i in a
+// goroutine, so that they can be processed while the next is fetched
+func templateStream(ctx context.Context, i TemplateIterator, buffer int) (<-chan *pkg.Templates, func() error) {
+	return stream(ctx, func(ctx context.Context) (*pkg.Templates, error) { return i.Next(ctx, -1) }, buffer)
+}
+
+// nextDocument decodes the next document from the page being read by
+// *reader, calling next to fetch a new page when it is exhausted
+func (c *templateClient) nextDocument(ctx context.Context, reader **documentReader, next func(*io.ReadCloser) error) (*pkg.Template, error) {
+	for {
+		if *reader == nil {
+			var body io.ReadCloser
+			err := next(&body)
+			if err != nil || body == nil {
+				return nil, err
+			}
+
+			*reader, err = newDocumentReader(body, c.getSerializer())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var template *pkg.Template
+		ok, err := (*reader).next(&template)
+		if err != nil || !ok {
+			*reader = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return template, nil
+		}
+	}
+}
+
+// TemplateBatch is a transactional batch of operations on Templates which
+// share a partition key
+type TemplateBatch struct {
+	operations []batchOperation
+}
+
+// NewTemplateBatch returns a new, empty TemplateBatch
+func NewTemplateBatch() *TemplateBatch {
+	return &TemplateBatch{}
+}
+
+// Create adds an operation to create template
+func (b *TemplateBatch) Create(template *pkg.Template) *TemplateBatch {
+	b.operations = append(b.operations, batchOperation{OperationType: BatchOperationTypeCreate, ResourceBody: template})
+	return b
+}
+
+// Upsert adds an operation to create or replace template
+func (b *TemplateBatch) Upsert(template *pkg.Template) *TemplateBatch {
+	b.operations = append(b.operations, batchOperation{OperationType: BatchOperationTypeUpsert, ResourceBody: template})
+	return b
+}
+
+// Read adds an operation to read the Template with the given ID
+func (b *TemplateBatch) Read(templateid string) *TemplateBatch {
+	b.operations = append(b.operations, batchOperation{OperationType: BatchOperationTypeRead, ID: templateid})
+	return b
+}
+
+// Replace adds an operation to replace template
+func (b *TemplateBatch) Replace(template *pkg.Template) *TemplateBatch {
+	b.operations = append(b.operations, batchOperation{OperationType: BatchOperationTypeReplace, ID: template.ID, ResourceBody: template, IfMatch: template.ETag})
+	return b
+}
+
+// Delete adds an operation to delete template
+func (b *TemplateBatch) Delete(template *pkg.Template) *TemplateBatch {
+	b.operations = append(b.operations, batchOperation{OperationType: BatchOperationTypeDelete, ID: template.ID, IfMatch: template.ETag})
+	return b
+}
+
+// Patch adds an operation to patch the Template with the given ID
+func (b *TemplateBatch) Patch(templateid string, operations []PatchOperation) *TemplateBatch {
+	b.operations = append(b.operations, batchOperation{OperationType: BatchOperationTypePatch, ID: templateid, ResourceBody: &patchRequest{Operations: operations}})
+	return b
+}
+
+// TemplateBulk is a set of operations on Templates with any partition keys,
+// to be executed in bulk
+type TemplateBulk struct {
+	partitionkeys []string
+	operations    []batchOperation
+}
+
+// NewTemplateBulk returns a new, empty TemplateBulk
+func NewTemplateBulk() *TemplateBulk {
+	return &TemplateBulk{}
+}
+
+func (b *TemplateBulk) add(partitionkey string, op batchOperation) *TemplateBulk {
+	b.partitionkeys = append(b.partitionkeys, partitionkey)
+	b.operations = append(b.operations, op)
+	return b
+}
+
+// Create adds an operation to create template
+func (b *TemplateBulk) Create(partitionkey string, template *pkg.Template) *TemplateBulk {
+	return b.add(partitionkey, batchOperation{OperationType: BatchOperationTypeCreate, ResourceBody: template})
+}
+
+// Upsert adds an operation to create or replace template
+func (b *TemplateBulk) Upsert(partitionkey string, template *pkg.Template) *TemplateBulk {
+	return b.add(partitionkey, batchOperation{OperationType: BatchOperationTypeUpsert, ResourceBody: template})
+}
+
+// Read adds an operation to read the Template with the given ID
+func (b *TemplateBulk) Read(partitionkey, templateid string) *TemplateBulk {
+	return b.add(partitionkey, batchOperation{OperationType: BatchOperationTypeRead, ID: templateid})
+}
+
+// Replace adds an operation to replace template
+func (b *TemplateBulk) Replace(partitionkey string, template *pkg.Template) *TemplateBulk {
+	return b.add(partitionkey, batchOperation{OperationType: BatchOperationTypeReplace, ID: template.ID, ResourceBody: template, IfMatch: template.ETag})
+}
+
+// Delete adds an operation to delete template
+func (b *TemplateBulk) Delete(partitionkey string, template *pkg.Template) *TemplateBulk {
+	return b.add(partitionkey, batchOperation{OperationType: BatchOperationTypeDelete, ID: template.ID, IfMatch: template.ETag})
+}
+
+// Patch adds an operation to patch the Template with the given ID
+func (b *TemplateBulk) Patch(partitionkey, templateid string, operations []PatchOperation) *TemplateBulk {
+	return b.add(partitionkey, batchOperation{OperationType: BatchOperationTypePatch, ID: templateid, ResourceBody: &patchRequest{Operations: operations}})
+}
+
+// TemplateBatchResponse is the response of a transactional batch.  If the
+// batch failed, StatusCode is that of the operation which caused it to fail
+// and the other operations have status 424 Failed Dependency.
+type TemplateBatchResponse struct {
+	StatusCode int
+	Results    []*TemplateBatchResult
+}
+
+// TemplateBatchResult is the result of an operation of a transactional batch
+type TemplateBatchResult struct {
+	StatusCode    int
+	SubStatusCode int
+	RequestCharge float64
+	ETag          string
+	Template      *pkg.Template
+}
+
+// Success returns true if every operation of the batch succeeded
+func (r *TemplateBatchResponse) Success() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// ErrorIndex returns the index of the operation which caused the batch to
+// fail, or -1 if it succeeded
+func (r *TemplateBatchResponse) ErrorIndex() int {
+	for i, result := range r.Results {
+		if result.StatusCode >= 300 && result.StatusCode != http.StatusFailedDependency {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -1,10 +1,13 @@
 package cosmosdb
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/ugorji/go/codec"
 
@@ -118,7 +121,12 @@ func (c *FakeTemplateClient) apply(ctx context.Context, partitionkey string, tem
 	}
 
 	if options != nil {
-		err := c.processPreTriggers(ctx, template, options)
+		err := options.validateTriggers()
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.processPreTriggers(ctx, template, options)
 		if err != nil {
 			return nil, err
 		}
@@ -146,7 +154,7 @@ func (c *FakeTemplateClient) apply(ctx context.Context, partitionkey string, tem
 			if c.conflictChecker(templateToCheck, template) {
 				return nil, &Error{
 					StatusCode: http.StatusConflict,
-					Message:    "Entity with the specified id already exists in the system",
+					Message:    uniqueKeyViolationMessage,
 				}
 			}
 		}
@@ -174,6 +182,169 @@ func (c *FakeTemplateClient) Replace(ctx context.Context, partitionkey string, t
 	return c.apply(ctx, partitionkey, template, options, false)
 }
 
+// Patch applies patch operations to a Template in the database.  Conditional
+// patches are not implemented.
+func (c *FakeTemplateClient) Patch(ctx context.Context, partitionkey string, id string, operations []PatchOperation, options *Options) (*pkg.Template, error) {
+	if options != nil && options.PatchCondition != "" {
+		return nil, ErrNotImplemented
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	existingTemplate, exists := c.templates[id]
+	if !exists {
+		return nil, &Error{StatusCode: http.StatusNotFound}
+	}
+
+	var b []byte
+	err := codec.NewEncoderBytes(&b, c.jsonHandle).Encode(existingTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var doc interface{}
+	err = d.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err = applyPatch(doc, operations)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err = json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var template *pkg.Template
+	err = codec.NewDecoderBytes(b, c.jsonHandle).Decode(&template)
+	if err != nil {
+		return nil, err
+	}
+
+	template.ETag = fmt.Sprint(c.etag)
+	c.etag++
+
+	c.templates[template.ID] = template
+
+	if err = c.updateChangeFeeds(template); err != nil {
+		return nil, err
+	}
+
+	return c.deepCopy(template)
+}
+
+// SetTTL sets the TTL of a Template in the database.  Templates do not
+// expire.
+func (c *FakeTemplateClient) SetTTL(ctx context.Context, partitionkey string, id string, ttl int, options *Options) (*pkg.Template, error) {
+	return c.Patch(ctx, partitionkey, id, []PatchOperation{ttlPatch(ttl)}, options)
+}
+
+// ExecuteBatch executes the operations of a TemplateBatch, rolling back the
+// database if one fails.  The batch is not isolated from concurrent operations
+// and rolled back changes are not removed from change feeds.
+func (c *FakeTemplateClient) ExecuteBatch(ctx context.Context, partitionkey string, batch *TemplateBatch, options *Options) (*TemplateBatchResponse, error) {
+	operations, err := prepareBatch(batch.operations, options)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	snapshot := make(map[string]*pkg.Template, len(c.templates))
+	for id, template := range c.templates {
+		snapshot[id] = template
+	}
+	c.lock.Unlock()
+
+	response := &TemplateBatchResponse{
+		StatusCode: http.StatusOK,
+		Results:    make([]*TemplateBatchResult, len(operations)),
+	}
+
+	for i, op := range operations {
+		var template *pkg.Template
+		statusCode := http.StatusOK
+
+		opOptions := &Options{NoETag: op.IfMatch == ""}
+
+		switch op.OperationType {
+		case BatchOperationTypeCreate:
+			template, err = c.Create(ctx, partitionkey, op.ResourceBody.(*pkg.Template), nil)
+			statusCode = http.StatusCreated
+		case BatchOperationTypeUpsert:
+			template, err = c.Replace(ctx, partitionkey, op.ResourceBody.(*pkg.Template), &Options{NoETag: true})
+			if IsErrorStatusCode(err, http.StatusNotFound) {
+				template, err = c.Create(ctx, partitionkey, op.ResourceBody.(*pkg.Template), nil)
+				statusCode = http.StatusCreated
+			}
+		case BatchOperationTypeRead:
+			template, err = c.Get(ctx, partitionkey, op.ID, nil)
+		case BatchOperationTypeReplace:
+			template, err = c.Replace(ctx, partitionkey, op.ResourceBody.(*pkg.Template), opOptions)
+		case BatchOperationTypeDelete:
+			err = c.Delete(ctx, partitionkey, &pkg.Template{ID: op.ID, ETag: op.IfMatch}, opOptions)
+			statusCode = http.StatusNoContent
+		case BatchOperationTypePatch:
+			template, err = c.Patch(ctx, partitionkey, op.ID, op.ResourceBody.(*patchRequest).Operations, nil)
+		default:
+			err = ErrNotImplemented
+		}
+
+		if err, ok := err.(*Error); ok {
+			statusCode = err.StatusCode
+		} else if err != nil {
+			return nil, err
+		}
+
+		response.Results[i] = &TemplateBatchResult{StatusCode: statusCode, Template: template}
+		if template != nil {
+			response.Results[i].ETag = template.ETag
+		}
+
+		if statusCode >= 300 {
+			c.lock.Lock()
+			c.templates = snapshot
+			c.lock.Unlock()
+
+			response.StatusCode = statusCode
+			for j := range response.Results {
+				if j != i {
+					response.Results[j] = &TemplateBatchResult{StatusCode: http.StatusFailedDependency}
+				}
+			}
+			break
+		}
+	}
+
+	return response, nil
+}
+
+// ExecuteBulk executes the operations of a TemplateBulk one at a time
+func (c *FakeTemplateClient) ExecuteBulk(ctx context.Context, bulk *TemplateBulk, options *Options) ([]*TemplateBatchResult, error) {
+	results := make([]*TemplateBatchResult, len(bulk.operations))
+
+	for i, op := range bulk.operations {
+		response, err := c.ExecuteBatch(ctx, bulk.partitionkeys[i], &TemplateBatch{operations: []batchOperation{op}}, options)
+		if err != nil {
+			return nil, err
+		}
+
+		results[i] = response.Results[0]
+	}
+
+	return results, nil
+}
+
 // List returns a TemplateIterator to list all Templates in the database
 func (c *FakeTemplateClient) List(*Options) TemplateIterator {
 	c.lock.RLock()
@@ -205,6 +376,11 @@ func (c *FakeTemplateClient) ListAll(ctx context.Context, options *Options) (*pk
 	return iter.Next(ctx, -1)
 }
 
+// ForEach calls fn with each Template in the database
+func (c *FakeTemplateClient) ForEach(ctx context.Context, options *Options, fn func(*pkg.Template) error) error {
+	return c.List(options).ForEach(ctx, fn)
+}
+
 // Get gets a Template from the database
 func (c *FakeTemplateClient) Get(ctx context.Context, partitionkey string, id string, options *Options) (*pkg.Template, error) {
 	c.lock.RLock()
@@ -219,9 +395,35 @@ func (c *FakeTemplateClient) Get(ctx context.Context, partitionkey string, id st
 		return nil, &Error{StatusCode: http.StatusNotFound}
 	}
 
+	if options != nil && options.IfNoneMatch != "" && options.IfNoneMatch == template.ETag {
+		return nil, &Error{StatusCode: http.StatusNotModified}
+	}
+
 	return c.deepCopy(template)
 }
 
+// ReadMany reads Templates from the database
+func (c *FakeTemplateClient) ReadMany(ctx context.Context, items []ItemIdentity, options *Options) (*pkg.Templates, []ItemIdentity, error) {
+	templates := &pkg.Templates{}
+	var missing []ItemIdentity
+
+	for _, item := range items {
+		template, err := c.Get(ctx, item.PartitionKey, item.ID, options)
+		if IsErrorStatusCode(err, http.StatusNotFound) {
+			missing = append(missing, item)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		templates.Templates = append(templates.Templates, template)
+	}
+	templates.Count = len(templates.Templates)
+
+	return templates, missing, nil
+}
+
 // Delete deletes a Template from the database
 func (c *FakeTemplateClient) Delete(ctx context.Context, partitionKey string, template *pkg.Template, options *Options) error {
 	c.lock.Lock()
@@ -260,6 +462,86 @@ func (c *FakeTemplateClient) ChangeFeed(*Options) TemplateIterator {
 	return newIter
 }
 
+// AllVersionsChangeFeed is not implemented by the fake
+func (c *FakeTemplateClient) AllVersionsChangeFeed(*Options) TemplateChangeFeedItemIterator {
+	return &fakeTemplateErroringChangeFeedItemIterator{err: ErrNotImplemented}
+}
+
+type fakeTemplateErroringChangeFeedItemIterator struct {
+	err error
+}
+
+func (i *fakeTemplateErroringChangeFeedItemIterator) Next(context.Context, int) (*TemplateChangeFeedItems, error) {
+	return nil, i.err
+}
+
+func (i *fakeTemplateErroringChangeFeedItemIterator) Continuation() string {
+	return ""
+}
+
+// ChangeFeedProcessor returns a ChangeFeedProcessor which polls a ChangeFeed
+// of the fake, calling handler with each batch of changes.  Leases are not
+// used, so a single processor receives every change.
+func (c *FakeTemplateClient) ChangeFeedProcessor(leasec CollectionClient, leaseCollID string, handler func(context.Context, *pkg.Templates) error, options *ChangeFeedProcessorOptions) ChangeFeedProcessor {
+	pollDelay := DefaultFeedPollDelay
+	if options != nil && options.FeedPollDelay != 0 {
+		pollDelay = options.FeedPollDelay
+	}
+
+	return &fakeTemplateChangeFeedProcessor{
+		c:         c,
+		iterator:  c.ChangeFeed(nil),
+		handler:   handler,
+		pollDelay: pollDelay,
+	}
+}
+
+type fakeTemplateChangeFeedProcessor struct {
+	c         *FakeTemplateClient
+	iterator  TemplateIterator
+	handler   func(context.Context, *pkg.Templates) error
+	pollDelay time.Duration
+}
+
+func (p *fakeTemplateChangeFeedProcessor) Run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		p.c.lock.RLock()
+		templates, err := p.iterator.Next(ctx, -1)
+		p.c.lock.RUnlock()
+		if err != nil {
+			return err
+		}
+
+		if templates != nil && len(templates.Templates) > 0 {
+			err = p.handler(ctx, templates)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(p.pollDelay):
+		}
+	}
+
+	return nil
+}
+
+// ChangeFeedEstimator is not implemented by the fake
+func (c *FakeTemplateClient) ChangeFeedEstimator(leasec CollectionClient, leaseCollID string, options *ChangeFeedProcessorOptions) ChangeFeedEstimator {
+	return &fakeTemplateErroringChangeFeedEstimator{err: ErrNotImplemented}
+}
+
+type fakeTemplateErroringChangeFeedEstimator struct {
+	err error
+}
+
+func (e *fakeTemplateErroringChangeFeedEstimator) Estimate(context.Context) ([]ChangeFeedEstimate, error) {
+	return nil, e.err
+}
+
 func (c *FakeTemplateClient) updateChangeFeeds(template *pkg.Template) error {
 	for _, currentIterator := range c.changeFeedIterators {
 		newTpl, err := c.deepCopy(template)
@@ -315,6 +597,36 @@ func (c *FakeTemplateClient) QueryAll(ctx context.Context, partitionkey string,
 	return iter.Next(ctx, -1)
 }
 
+// QueryPage calls a query handler to implement database querying, returning
+// the requested page of its results by number
+func (c *FakeTemplateClient) QueryPage(ctx context.Context, partitionkey string, query *Query, page *PageRequest, options *Options) (*pkg.Templates, *PageRequest, error) {
+	if page.PageSize <= 0 {
+		return nil, nil, ErrInvalidPageRequest
+	}
+
+	templates, err := c.QueryAll(ctx, partitionkey, query, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	if templates == nil {
+		templates = &pkg.Templates{}
+	}
+
+	start := (page.number() - 1) * page.PageSize
+	if start > len(templates.Templates) {
+		start = len(templates.Templates)
+	}
+	end := start + page.PageSize
+	if end > len(templates.Templates) {
+		end = len(templates.Templates)
+	}
+
+	templates.Templates = templates.Templates[start:end]
+	templates.Count = len(templates.Templates)
+
+	return templates, page.next(templates.Count, ""), nil
+}
+
 func NewFakeTemplateIterator(templates []*pkg.Template, continuation int) TemplateRawIterator {
 	return &fakeTemplateIterator{templates: templates, continuation: continuation}
 }
@@ -355,6 +667,15 @@ func (i *fakeTemplateIterator) Next(ctx context.Context, maxItemCount int) (*pkg
 	}, nil
 }
 
+func (i *fakeTemplateIterator) NextDocument(ctx context.Context) (*pkg.Template, error) {
+	templates, err := i.Next(ctx, 1)
+	if err != nil || templates == nil || len(templates.Templates) == 0 {
+		return nil, err
+	}
+
+	return templates.Templates[0], nil
+}
+
 func (i *fakeTemplateIterator) Continuation() string {
 	if i.continuation >= len(i.templates) {
 		return ""
@@ -362,6 +683,22 @@ func (i *fakeTemplateIterator) Continuation() string {
 	return fmt.Sprintf("%d", i.continuation)
 }
 
+func (i *fakeTemplateIterator) QueryMetrics() *QueryMetrics {
+	return &QueryMetrics{}
+}
+
+func (i *fakeTemplateIterator) Items(ctx context.Context) func(func(*pkg.Template, error) bool) {
+	return templateItems(ctx, i)
+}
+
+func (i *fakeTemplateIterator) ForEach(ctx context.Context, fn func(*pkg.Template) error) error {
+	return templateForEach(ctx, i, fn)
+}
+
+func (i *fakeTemplateIterator) Stream(ctx context.Context, buffer int) (<-chan *pkg.Templates, func() error) {
+	return templateStream(ctx, i, buffer)
+}
+
 // NewFakeTemplateErroringRawIterator returns a TemplateRawIterator which
 // whose methods return the given error
 func NewFakeTemplateErroringRawIterator(err error) TemplateRawIterator {
@@ -376,6 +713,10 @@ func (i *fakeTemplateErroringRawIterator) Next(ctx context.Context, maxItemCount
 	return nil, i.err
 }
 
+func (i *fakeTemplateErroringRawIterator) NextDocument(context.Context) (*pkg.Template, error) {
+	return nil, i.err
+}
+
 func (i *fakeTemplateErroringRawIterator) NextRaw(context.Context, int, interface{}) error {
 	return i.err
 }
@@ -383,3 +724,19 @@ func (i *fakeTemplateErroringRawIterator) NextRaw(context.Context, int, interfac
 func (i *fakeTemplateErroringRawIterator) Continuation() string {
 	return ""
 }
+
+func (i *fakeTemplateErroringRawIterator) QueryMetrics() *QueryMetrics {
+	return &QueryMetrics{}
+}
+
+func (i *fakeTemplateErroringRawIterator) Items(ctx context.Context) func(func(*pkg.Template, error) bool) {
+	return templateItems(ctx, i)
+}
+
+func (i *fakeTemplateErroringRawIterator) ForEach(ctx context.Context, fn func(*pkg.Template) error) error {
+	return templateForEach(ctx, i, fn)
+}
+
+func (i *fakeTemplateErroringRawIterator) Stream(ctx context.Context, buffer int) (<-chan *pkg.Templates, func() error) {
+	return templateStream(ctx, i, buffer)
+}
@@ -0,0 +1,135 @@
+package cosmosdb
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of the spans of requests
+const tracerName = "github.com/bennerv/go-cosmosdb"
+
+// SetTracerProvider sets the OpenTelemetry TracerProvider with which the
+// client and the clients derived from it trace requests.  If it is not set,
+// the global TracerProvider is used.
+//
+// Each request is traced by a client span covering all of its attempts, with
+// its operation, resource link, status code, request charge and number of
+// retries.  The trace context of the span is propagated to the server with
+// the global TextMapPropagator.
+func (c *databaseClient) SetTracerProvider(tracerProvider trace.TracerProvider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tracerProvider = tracerProvider
+}
+
+func (c *databaseClient) getTracer() trace.Tracer {
+	c.mu.RLock()
+	tracerProvider := c.tracerProvider
+	c.mu.RUnlock()
+
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+
+	return tracerProvider.Tracer(tracerName)
+}
+
+// startSpan starts the span of a request and injects its trace context into
+// reqHeader, the headers of the request
+func (c *databaseClient) startSpan(ctx context.Context, method, path, resourceType, resourceLink string, headers, reqHeader http.Header) (context.Context, trace.Span) {
+	operation := operationName(method, path, resourceType, headers)
+
+	ctx, span := c.getTracer().Start(ctx, "cosmosdb."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "cosmosdb"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.cosmosdb.resource_link", resourceLink),
+			attribute.String("http.request.method", method),
+		),
+	)
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(reqHeader))
+
+	return ctx, span
+}
+
+// endSpan records the outcome of a request, which was retried retries times,
+// on its span and ends it
+func endSpan(span trace.Span, resp *http.Response, retries int, err error) {
+	if span.IsRecording() {
+		span.SetAttributes(attribute.Int("db.cosmosdb.retry_count", retries))
+
+		if resp != nil {
+			span.SetAttributes(attribute.Int("db.cosmosdb.status_code", resp.StatusCode))
+
+			if subStatusCode, err := strconv.Atoi(resp.Header.Get("x-ms-substatus")); err == nil {
+				span.SetAttributes(attribute.Int("db.cosmosdb.sub_status_code", subStatusCode))
+			}
+
+			if charge, err := strconv.ParseFloat(resp.Header.Get("X-Ms-Request-Charge"), 64); err == nil {
+				span.SetAttributes(attribute.Float64("db.cosmosdb.request_charge", charge))
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+
+	span.End()
+}
+
+// addRetryEvent records on span that attempt failed with err and is retried
+func addRetryEvent(span trace.Span, attempt int, err error) {
+	span.AddEvent("retry", trace.WithAttributes(
+		attribute.Int("db.cosmosdb.attempt", attempt),
+		attribute.String("error", err.Error()),
+	))
+}
+
+// operationName returns the name of the operation performed by a request
+func operationName(method, path, resourceType string, headers http.Header) string {
+	switch method {
+	case http.MethodGet:
+		switch {
+		case headers.Get("A-IM") != "":
+			return "ChangeFeed"
+		case path == resourceType || strings.HasSuffix(path, "/"+resourceType):
+			return "ReadFeed"
+		}
+		return "Read"
+
+	case http.MethodPost:
+		switch {
+		case headers.Get("X-Ms-Documentdb-Isquery") != "":
+			return "Query"
+		case headers.Get("X-Ms-Cosmos-Is-Batch-Request") != "":
+			return "Batch"
+		case resourceType == "sprocs" && !strings.HasSuffix(path, "/sprocs"):
+			return "Execute"
+		}
+		return "Create"
+
+	case http.MethodPut:
+		return "Replace"
+
+	case http.MethodPatch:
+		return "Patch"
+
+	case http.MethodDelete:
+		return "Delete"
+	}
+
+	return method
+}
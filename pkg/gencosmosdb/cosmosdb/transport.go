@@ -0,0 +1,131 @@
+package cosmosdb
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// Defaults of TransportOptions
+const (
+	defaultMaxIdleConnsPerHost  = 100
+	defaultIdleConnTimeout      = 90 * time.Second
+	defaultHTTP2ReadIdleTimeout = 30 * time.Second
+	defaultHTTP2PingTimeout     = 15 * time.Second
+)
+
+// TransportOptions represents the options of a transport returned by
+// NewTransport
+type TransportOptions struct {
+	// MaxIdleConnsPerHost is the number of idle connections kept open to each
+	// endpoint; if zero, 100.  The net/http default of 2 causes connections
+	// to be closed and reopened under concurrent load.
+	MaxIdleConnsPerHost int
+
+	// MaxConnsPerHost, if non-zero, limits the number of connections to each
+	// endpoint
+	MaxConnsPerHost int
+
+	// IdleConnTimeout is the time after which an idle connection is closed;
+	// if zero, 90s
+	IdleConnTimeout time.Duration
+
+	// TLSClientConfig, if set, is the TLS configuration of connections
+	TLSClientConfig *tls.Config
+
+	// DisableHTTP2 restricts the transport to HTTP/1.1.  Otherwise HTTP/2 is
+	// used with endpoints which negotiate it.
+	DisableHTTP2 bool
+
+	// HTTP2PriorKnowledge uses HTTP/2 without negotiating it, for proxies
+	// which support HTTP/2 but do not advertise it.  The connection options
+	// above, other than TLSClientConfig, do not apply, as each endpoint is
+	// reached over a single multiplexed connection.
+	HTTP2PriorKnowledge bool
+
+	// HTTP2ReadIdleTimeout is the time after which an HTTP/2 connection on
+	// which nothing has been received is health checked with a ping; if zero,
+	// 30s
+	HTTP2ReadIdleTimeout time.Duration
+
+	// HTTP2PingTimeout is the time after which an HTTP/2 connection whose
+	// health check ping is unanswered is closed, failing its requests rather
+	// than leaving them hanging; if zero, 15s
+	HTTP2PingTimeout time.Duration
+}
+
+// NewTransport returns an HTTP transport suited to the many concurrent
+// requests which a database client makes to a few endpoints.  It may be
+// wrapped by another http.RoundTripper, e.g. for tracing, and passed to
+// NewDatabaseClient in an http.Client.
+func NewTransport(options *TransportOptions) http.RoundTripper {
+	if options == nil {
+		options = &TransportOptions{}
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	readIdleTimeout := options.HTTP2ReadIdleTimeout
+	if readIdleTimeout == 0 {
+		readIdleTimeout = defaultHTTP2ReadIdleTimeout
+	}
+
+	pingTimeout := options.HTTP2PingTimeout
+	if pingTimeout == 0 {
+		pingTimeout = defaultHTTP2PingTimeout
+	}
+
+	if options.HTTP2PriorKnowledge {
+		return &http2.Transport{
+			TLSClientConfig: options.TLSClientConfig,
+			ReadIdleTimeout: readIdleTimeout,
+			PingTimeout:     pingTimeout,
+			// dialing TLS ourselves skips the check that HTTP/2 was negotiated
+			DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return (&tls.Dialer{NetDialer: dialer, Config: cfg}).DialContext(ctx, network, addr)
+			},
+		}
+	}
+
+	// as http.DefaultTransport, which is not cloned because it may already
+	// have been configured for HTTP/2
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       options.TLSClientConfig,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConnsPerHost:   options.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       options.MaxConnsPerHost,
+		IdleConnTimeout:       options.IdleConnTimeout,
+	}
+
+	if transport.MaxIdleConnsPerHost == 0 {
+		transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+
+	if transport.IdleConnTimeout == 0 {
+		transport.IdleConnTimeout = defaultIdleConnTimeout
+	}
+
+	if options.DisableHTTP2 {
+		// a non-nil empty map disables HTTP/2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		return transport
+	}
+
+	// this cannot fail, as the transport is not yet configured for HTTP/2
+	if t2, err := http2.ConfigureTransports(transport); err == nil {
+		t2.ReadIdleTimeout = readIdleTimeout
+		t2.PingTimeout = pingTimeout
+	}
+
+	return transport
+}
@@ -0,0 +1,30 @@
+package cosmosdb
+
+// TTL constants.  As the DefaultTTL of a collection, TTLOff disables expiry
+// and TTLNoExpiry enables per-document TTLs without expiring documents by
+// default.  As the TTL of a document, TTLNoExpiry prevents the document
+// expiring and TTLOff leaves it to inherit the DefaultTTL of its collection.
+// Any positive TTL is a number of seconds since the document was last
+// modified.
+//
+// As the AnalyticalStorageTTL of a collection, TTLNoExpiry enables the
+// analytical store (Synapse Link) and retains documents in it indefinitely,
+// and a positive TTL retains them for that number of seconds.  The analytical
+// store cannot be disabled once enabled.
+//
+// To support per-document TTLs, a document type should have the field
+//
+//	TTL int `json:"ttl,omitempty"`
+const (
+	TTLOff      = 0
+	TTLNoExpiry = -1
+)
+
+// ttlPatch returns the patch operation which sets the TTL of a document
+func ttlPatch(ttl int) PatchOperation {
+	if ttl == TTLOff {
+		return PatchOperation{Op: PatchOperationTypeRemove, Path: "/ttl"}
+	}
+
+	return PatchOperation{Op: PatchOperationTypeSet, Path: "/ttl", Value: ttl}
+}
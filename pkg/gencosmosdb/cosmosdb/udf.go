@@ -0,0 +1,155 @@
+package cosmosdb
+
+import (
+	"context"
+	"net/http"
+)
+
+// UDF represents a user-defined function
+type UDF struct {
+	ID         string `json:"id,omitempty"`
+	ResourceID string `json:"_rid,omitempty"`
+	Timestamp  int    `json:"_ts,omitempty"`
+	Self       string `json:"_self,omitempty"`
+	ETag       string `json:"_etag,omitempty"`
+	Body       string `json:"body,omitempty"`
+}
+
+// UDFs represents user-defined functions
+type UDFs struct {
+	Count      int    `json:"_count,omitempty"`
+	ResourceID string `json:"_rid,omitempty"`
+	UDFs       []*UDF `json:"UserDefinedFunctions,omitempty"`
+}
+
+type udfClient struct {
+	*databaseClient
+	path string
+}
+
+// UDFClient is a user-defined function client
+type UDFClient interface {
+	Create(context.Context, *UDF) (*UDF, error)
+	List() UDFIterator
+	ListAll(context.Context) (*UDFs, error)
+	Query(*Query) UDFIterator
+	QueryAll(context.Context, *Query) (*UDFs, error)
+	Get(context.Context, string) (*UDF, error)
+	Delete(context.Context, *UDF) error
+	Replace(context.Context, *UDF) (*UDF, error)
+}
+
+type udfListIterator struct {
+	*udfClient
+	query        *Query
+	continuation string
+	done         bool
+}
+
+// UDFIterator is a user-defined function iterator
+type UDFIterator interface {
+	Next(context.Context) (*UDFs, error)
+	Items(context.Context) func(func(*UDF, error) bool)
+}
+
+// NewUDFClient returns a new user-defined function client
+func NewUDFClient(collc CollectionClient, collid string) UDFClient {
+	return &udfClient{
+		databaseClient: collc.(*collectionClient).databaseClient,
+		path:           collc.(*collectionClient).path + "/colls/" + collid,
+	}
+}
+
+func (c *udfClient) all(ctx context.Context, i UDFIterator) (*UDFs, error) {
+	alludfs := &UDFs{}
+
+	for {
+		udfs, err := i.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if udfs == nil {
+			break
+		}
+
+		alludfs.Count += udfs.Count
+		alludfs.ResourceID = udfs.ResourceID
+		alludfs.UDFs = append(alludfs.UDFs, udfs.UDFs...)
+	}
+
+	return alludfs, nil
+}
+
+func (c *udfClient) Create(ctx context.Context, newudf *UDF) (udf *UDF, err error) {
+	err = c.do(ctx, http.MethodPost, c.path+"/udfs", "udfs", c.path, http.StatusCreated, &newudf, &udf, nil, nil)
+	return
+}
+
+func (c *udfClient) List() UDFIterator {
+	return &udfListIterator{udfClient: c}
+}
+
+func (c *udfClient) ListAll(ctx context.Context) (*UDFs, error) {
+	return c.all(ctx, c.List())
+}
+
+func (c *udfClient) Query(query *Query) UDFIterator {
+	return &udfListIterator{udfClient: c, query: query}
+}
+
+func (c *udfClient) QueryAll(ctx context.Context, query *Query) (*UDFs, error) {
+	return c.all(ctx, c.Query(query))
+}
+
+func (c *udfClient) Get(ctx context.Context, udfid string) (udf *UDF, err error) {
+	err = c.do(ctx, http.MethodGet, c.path+"/udfs/"+udfid, "udfs", c.path+"/udfs/"+udfid, http.StatusOK, nil, &udf, nil, nil)
+	return
+}
+
+func (c *udfClient) Delete(ctx context.Context, udf *UDF) error {
+	if udf.ETag == "" {
+		return ErrETagRequired
+	}
+	headers := http.Header{}
+	headers.Set("If-Match", udf.ETag)
+	return c.do(ctx, http.MethodDelete, c.path+"/udfs/"+udf.ID, "udfs", c.path+"/udfs/"+udf.ID, http.StatusNoContent, nil, nil, headers, nil)
+}
+
+func (c *udfClient) Replace(ctx context.Context, newudf *UDF) (udf *UDF, err error) {
+	headers := http.Header{}
+	if newudf.ETag != "" {
+		headers.Set("If-Match", newudf.ETag)
+	}
+
+	err = c.do(ctx, http.MethodPut, c.path+"/udfs/"+newudf.ID, "udfs", c.path+"/udfs/"+newudf.ID, http.StatusOK, &newudf, &udf, headers, nil)
+	return
+}
+
+func (i *udfListIterator) Next(ctx context.Context) (udfs *UDFs, err error) {
+	if i.done {
+		return
+	}
+
+	headers := http.Header{}
+	if i.continuation != "" {
+		headers.Set("X-Ms-Continuation", i.continuation)
+	}
+
+	if i.query != nil {
+		err = i.doQuery(ctx, i.path+"/udfs", "udfs", i.path, i.query, &udfs, headers, nil)
+	} else {
+		err = i.do(ctx, http.MethodGet, i.path+"/udfs", "udfs", i.path, http.StatusOK, nil, &udfs, headers, nil)
+	}
+	if err != nil {
+		return
+	}
+
+	i.continuation = headers.Get("X-Ms-Continuation")
+	i.done = i.continuation == ""
+
+	return
+}
+
+func (i *udfListIterator) Items(ctx context.Context) func(func(*UDF, error) bool) {
+	return items(ctx, i.Next, func(udfs *UDFs) []*UDF { return udfs.UDFs })
+}
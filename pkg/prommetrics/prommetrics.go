@@ -0,0 +1,73 @@
+// Package prommetrics records the metrics of Cosmos DB clients with
+// Prometheus.  It is kept apart from the generated packages so that they do
+// not depend on the Prometheus client library:
+//
+//	metrics, err := prommetrics.NewMetrics(prometheus.DefaultRegisterer)
+//	...
+//	dbc.SetMetrics(metrics)
+package prommetrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics implements the Metrics interface of the generated packages with
+// Prometheus collectors, labelled by operation and resource type
+type Metrics struct {
+	requests      *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	requestCharge *prometheus.CounterVec
+	throttles     *prometheus.CounterVec
+}
+
+// NewMetrics returns new Metrics whose collectors are registered with
+// registerer
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	labels := []string{"operation", "resource_type"}
+
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cosmosdb_requests_total",
+			Help: "Number of Cosmos DB request attempts by status code; a status code of 0 means that no response was received.",
+		}, append(labels, "status_code")),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cosmosdb_request_duration_seconds",
+			Help:    "Duration of Cosmos DB request attempts.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+		}, labels),
+		requestCharge: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cosmosdb_request_units_total",
+			Help: "Request units consumed by Cosmos DB requests.",
+		}, labels),
+		throttles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cosmosdb_throttled_requests_total",
+			Help: "Number of Cosmos DB request attempts which were throttled.",
+		}, labels),
+	}
+
+	for _, c := range []prometheus.Collector{m.requests, m.duration, m.requestCharge, m.throttles} {
+		err := registerer.Register(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Metrics) ObserveRequest(operation, resourceType string, statusCode int, duration time.Duration, requestCharge float64) {
+	m.requests.WithLabelValues(operation, resourceType, strconv.Itoa(statusCode)).Inc()
+	m.duration.WithLabelValues(operation, resourceType).Observe(duration.Seconds())
+
+	if requestCharge > 0 {
+		m.requestCharge.WithLabelValues(operation, resourceType).Add(requestCharge)
+	}
+
+	if statusCode == http.StatusTooManyRequests {
+		m.throttles.WithLabelValues(operation, resourceType).Inc()
+	}
+}
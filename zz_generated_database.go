@@ -9,6 +9,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/ugorji/go/codec"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Database represents a database
@@ -31,18 +32,41 @@ type Databases struct {
 
 type databaseClient struct {
 	mu               sync.RWMutex
-	log              *logrus.Entry
+	log              Logger
 	hc               *http.Client
-	jsonHandle       *codec.JsonHandle
+	serializer       Serializer
 	databaseHostname string
 	authorizer       Authorizer
-	maxRetries       int
+	retryPolicy      RetryPolicy
+	endpointManager  *endpointManager
+	circuitBreaker   *circuitBreaker
+	compressionSize  int
+	maxResponseSize  int64
+	tracerProvider   trace.TracerProvider
+	metrics          Metrics
+	policies         []Policy
+	session          *Session
+	pkRangeCache     partitionKeyRangeCache
 }
 
 // DatabaseClient is a database client
 type DatabaseClient interface {
 	SetAuthorizer(Authorizer)
+	SetMasterKey(string) error
+	GetDatabaseAccount(context.Context) (*DatabaseAccount, error)
+	EnableEndpointDiscovery(context.Context, []string) error
+	EnableCircuitBreaker(*CircuitBreakerOptions)
+	EnableRequestCompression(int)
+	SetSession(*Session)
+	SetRetryPolicy(RetryPolicy)
+	SetMaxResponseSize(int64)
+	SetSerializer(Serializer)
+	SetTracerProvider(trace.TracerProvider)
+	SetMetrics(Metrics)
+	SetLogger(Logger)
+	SetPolicies(...Policy)
 	Create(context.Context, *Database) (*Database, error)
+	CreateWithThroughput(context.Context, *Database, *Throughput) (*Database, error)
 	List() DatabaseIterator
 	ListAll(context.Context) (*Databases, error)
 	Get(context.Context, string) (*Database, error)
@@ -58,17 +82,24 @@ type databaseListIterator struct {
 // DatabaseIterator is a database iterator
 type DatabaseIterator interface {
 	Next(context.Context) (*Databases, error)
+	Items(context.Context) func(func(*Database, error) bool)
 }
 
-// NewDatabaseClient returns a new database client
+// NewDatabaseClient returns a new database client which logs to log.  If log
+// is nil, nothing is logged unless a Logger is set with SetLogger.
 func NewDatabaseClient(log *logrus.Entry, hc *http.Client, jsonHandle *codec.JsonHandle, databaseHostname string, authorizer Authorizer) DatabaseClient {
+	var logger Logger = discardLogger{}
+	if log != nil {
+		logger = NewLogrusLogger(log)
+	}
+
 	return &databaseClient{
-		log:              log,
+		log:              logger,
 		hc:               hc,
-		jsonHandle:       jsonHandle,
+		serializer:       NewCodecSerializer(jsonHandle),
 		databaseHostname: databaseHostname,
 		authorizer:       authorizer,
-		maxRetries:       10,
+		retryPolicy:      &DefaultRetryPolicy{},
 	}
 }
 
@@ -99,8 +130,43 @@ func (c *databaseClient) SetAuthorizer(authorizer Authorizer) {
 	c.authorizer = authorizer
 }
 
-func (c *databaseClient) Create(ctx context.Context, newdb *Database) (db *Database, err error) {
-	err = c.do(ctx, http.MethodPost, "dbs", "dbs", "", http.StatusCreated, &newdb, &db, nil)
+// SetMasterKey rotates the master key used to sign requests.  If the client's
+// Authorizer is a MasterKeyAuthorizer, its key is updated in place; otherwise
+// it is replaced by a new MasterKeyAuthorizer.  Requests which are already in
+// flight complete using the previous key.
+func (c *databaseClient) SetMasterKey(masterKey string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if authorizer, ok := c.authorizer.(MasterKeyAuthorizer); ok {
+		return authorizer.SetMasterKey(masterKey)
+	}
+
+	authorizer, err := NewMasterKeyAuthorizer(masterKey)
+	if err != nil {
+		return err
+	}
+
+	c.authorizer = authorizer
+
+	return nil
+}
+
+func (c *databaseClient) Create(ctx context.Context, newdb *Database) (*Database, error) {
+	return c.CreateWithThroughput(ctx, newdb, nil)
+}
+
+// CreateWithThroughput creates a database with shared throughput provisioned
+// according to throughput
+func (c *databaseClient) CreateWithThroughput(ctx context.Context, newdb *Database, throughput *Throughput) (db *Database, err error) {
+	headers := http.Header{}
+
+	err = throughput.setHeaders(headers)
+	if err != nil {
+		return
+	}
+
+	err = c.do(ctx, http.MethodPost, "dbs", "dbs", "", http.StatusCreated, &newdb, &db, headers, nil)
 	return
 }
 
@@ -113,7 +179,7 @@ func (c *databaseClient) ListAll(ctx context.Context) (*Databases, error) {
 }
 
 func (c *databaseClient) Get(ctx context.Context, dbid string) (db *Database, err error) {
-	err = c.do(ctx, http.MethodGet, "dbs/"+dbid, "dbs", "dbs/"+dbid, http.StatusOK, nil, &db, nil)
+	err = c.do(ctx, http.MethodGet, "dbs/"+dbid, "dbs", "dbs/"+dbid, http.StatusOK, nil, &db, nil, nil)
 	return
 }
 
@@ -123,7 +189,7 @@ func (c *databaseClient) Delete(ctx context.Context, db *Database) error {
 	}
 	headers := http.Header{}
 	headers.Set("If-Match", db.ETag)
-	return c.do(ctx, http.MethodDelete, "dbs/"+db.ID, "dbs", "dbs/"+db.ID, http.StatusNoContent, nil, nil, headers)
+	return c.do(ctx, http.MethodDelete, "dbs/"+db.ID, "dbs", "dbs/"+db.ID, http.StatusNoContent, nil, nil, headers, nil)
 }
 
 func (i *databaseListIterator) Next(ctx context.Context) (dbs *Databases, err error) {
@@ -136,7 +202,7 @@ func (i *databaseListIterator) Next(ctx context.Context) (dbs *Databases, err er
 		headers.Set("X-Ms-Continuation", i.continuation)
 	}
 
-	err = i.do(ctx, http.MethodGet, "dbs", "dbs", "", http.StatusOK, nil, &dbs, headers)
+	err = i.do(ctx, http.MethodGet, "dbs", "dbs", "", http.StatusOK, nil, &dbs, headers, nil)
 	if err != nil {
 		return
 	}
@@ -146,3 +212,7 @@ func (i *databaseListIterator) Next(ctx context.Context) (dbs *Databases, err er
 
 	return
 }
+
+func (i *databaseListIterator) Items(ctx context.Context) func(func(*Database, error) bool) {
+	return items(ctx, i.Next, func(dbs *Databases) []*Database { return dbs.Databases })
+}
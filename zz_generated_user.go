@@ -48,6 +48,7 @@ type userListIterator struct {
 // UserIterator is a user iterator
 type UserIterator interface {
 	Next(context.Context) (*Users, error)
+	Items(context.Context) func(func(*User, error) bool)
 }
 
 // NewUserClient returns a new user client
@@ -79,7 +80,7 @@ func (c *userClient) all(ctx context.Context, i UserIterator) (*Users, error) {
 }
 
 func (c *userClient) Create(ctx context.Context, newuser *User) (user *User, err error) {
-	err = c.do(ctx, http.MethodPost, c.path+"/users", "users", c.path, http.StatusCreated, &newuser, &user, nil)
+	err = c.do(ctx, http.MethodPost, c.path+"/users", "users", c.path, http.StatusCreated, &newuser, &user, nil, nil)
 	return
 }
 
@@ -92,7 +93,7 @@ func (c *userClient) ListAll(ctx context.Context) (*Users, error) {
 }
 
 func (c *userClient) Get(ctx context.Context, userid string) (user *User, err error) {
-	err = c.do(ctx, http.MethodGet, c.path+"/users/"+userid, "users", c.path+"/users/"+userid, http.StatusOK, nil, &user, nil)
+	err = c.do(ctx, http.MethodGet, c.path+"/users/"+userid, "users", c.path+"/users/"+userid, http.StatusOK, nil, &user, nil, nil)
 	return
 }
 
@@ -102,11 +103,11 @@ func (c *userClient) Delete(ctx context.Context, user *User) error {
 	}
 	headers := http.Header{}
 	headers.Set("If-Match", user.ETag)
-	return c.do(ctx, http.MethodDelete, c.path+"/users/"+user.ID, "users", c.path+"/users/"+user.ID, http.StatusNoContent, nil, nil, headers)
+	return c.do(ctx, http.MethodDelete, c.path+"/users/"+user.ID, "users", c.path+"/users/"+user.ID, http.StatusNoContent, nil, nil, headers, nil)
 }
 
 func (c *userClient) Replace(ctx context.Context, newuser *User) (user *User, err error) {
-	err = c.do(ctx, http.MethodPost, c.path+"/users/"+newuser.ID, "users", c.path+"/users/"+newuser.ID, http.StatusCreated, &newuser, &user, nil)
+	err = c.do(ctx, http.MethodPut, c.path+"/users/"+newuser.ID, "users", c.path+"/users/"+newuser.ID, http.StatusOK, &newuser, &user, nil, nil)
 	return
 }
 
@@ -120,7 +121,7 @@ func (i *userListIterator) Next(ctx context.Context) (users *Users, err error) {
 		headers.Set("X-Ms-Continuation", i.continuation)
 	}
 
-	err = i.do(ctx, http.MethodGet, i.path+"/users", "users", i.path, http.StatusOK, nil, &users, headers)
+	err = i.do(ctx, http.MethodGet, i.path+"/users", "users", i.path, http.StatusOK, nil, &users, headers, nil)
 	if err != nil {
 		return
 	}
@@ -130,3 +131,7 @@ func (i *userListIterator) Next(ctx context.Context) (users *Users, err error) {
 
 	return
 }
+
+func (i *userListIterator) Items(ctx context.Context) func(func(*User, error) bool) {
+	return items(ctx, i.Next, func(users *Users) []*User { return users.Users })
+}